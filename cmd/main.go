@@ -1,11 +1,27 @@
 package main
 
 import (
+	"os"
+
 	"github.com/Viquad/crud-app/internal/app"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			app.Import(os.Args[2:])
+			return
+		case "payments":
+			app.Payments(os.Args[2:])
+			return
+		case "migrate":
+			app.Migrate(os.Args[2:])
+			return
+		}
+	}
+
 	app.Run()
 }
@@ -2,20 +2,39 @@ package app
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/Viquad/crud-app/internal/domain"
 	"github.com/Viquad/crud-app/internal/repository/psql"
 	"github.com/Viquad/crud-app/internal/service"
+	graphqltransport "github.com/Viquad/crud-app/internal/transport/graphql"
+	grpctransport "github.com/Viquad/crud-app/internal/transport/grpc"
 	"github.com/Viquad/crud-app/internal/transport/rest"
 	"github.com/Viquad/crud-app/pkg/config"
 	"github.com/Viquad/crud-app/pkg/database"
 	"github.com/Viquad/crud-app/pkg/hash"
+	"github.com/Viquad/crud-app/pkg/health"
+	"github.com/Viquad/crud-app/pkg/logger"
+	"github.com/Viquad/crud-app/pkg/mailer"
+	"github.com/Viquad/crud-app/pkg/metrics"
+	"github.com/Viquad/crud-app/pkg/migrate"
+	"github.com/Viquad/crud-app/pkg/publisher"
+	"github.com/Viquad/crud-app/pkg/scheduler"
+	"github.com/Viquad/crud-app/pkg/tracing"
 	cache "github.com/Viquad/simple-cache"
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
 )
 
 // @title       CRUD app API
@@ -34,6 +53,11 @@ func init() {
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 	logrus.SetOutput(os.Stdout)
 	logrus.SetLevel(logrus.DebugLevel)
+	logrus.AddHook(logger.NewContextHook(map[string]interface{}{
+		"request_id": domain.RequestIdKey,
+		"user_id":    domain.UserIdKey,
+		"route":      domain.RouteKey,
+	}))
 }
 
 func Run() {
@@ -47,45 +71,142 @@ func Run() {
 		cancel()
 	}()
 
-	cfg, err := config.New("configs", "config")
+	cfg := loadConfig()
+
+	shutdownTracing := initTracing(ctx, cfg)
+	defer shutdownTracing(context.Background())
+
+	db := connectDB(cfg)
+	defer db.Close()
+
+	migrator := newMigrator(db)
+	migrateSchema(ctx, cfg, migrator)
+
+	repo := psql.NewRepositories(db)
+	memCache := cache.NewMemoryCache()
+	m := newMetrics(cfg, db)
+	checker := newHealth(cfg, db, memCache, migrator)
+	services := newServices(cfg, repo, memCache, m)
+	graphqlHandler, err := graphqltransport.NewHandler(services, graphqltransport.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"context": "app.Run()",
-			"problem": "can't initialize config",
+			"problem": "can't build GraphQL schema",
 		}).Fatal(err.Error())
 	}
 
-	db, err := database.NewPostgresConnection(cfg.DB)
-	if err != nil {
+	handler := rest.NewHandler(services, graphqlHandler, m)
+
+	checkFeeAccounts(ctx, cfg, repo)
+
+	if n, err := services.GetAccountService().AssignIbans(ctx); err != nil {
 		logrus.WithFields(logrus.Fields{
 			"context": "app.Run()",
-			"problem": "can't connect to DB",
-		}).Fatal(err.Error())
+			"problem": "can't assign IBANs",
+		}).Error(err.Error())
+	} else if n > 0 {
+		logrus.Infof("IBANs assigned to %d accounts", n)
 	}
 
-	defer db.Close()
-
-	hasher := hash.NewSHA1Hasher("TODO:MoveItToConfig")
-	cache := cache.NewMemoryCache()
-	repo := psql.NewRepositories(db)
-	services := service.NewServices(repo, cache, hasher, []byte("TODO:MoveItToConfig"), cfg.Cache.TTL, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
-	handler := rest.NewHandler(services)
-
 	router := handler.InitRouter()
 
 	httpServer := http.Server{
 		Addr:    ":8080",
 		Handler: router,
 	}
+	// event streams never finish by themselves, so they are closed for Shutdown to complete
+	httpServer.RegisterOnShutdown(services.GetStreamService().Close)
+
+	// metrics are served only on the admin address, which isn't exposed to clients
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		metricsServer = &http.Server{
+			Addr:    cfg.Metrics.Addr,
+			Handler: m.Handler(),
+		}
+	}
+
+	router.GET("/healthz", gin.WrapH(checker.LiveHandler()))
+	router.GET("/readyz", gin.WrapH(checker.ReadyHandler()))
+
+	grpcServer := grpctransport.NewHandler(services).InitServer()
 
 	g, gCtx := errgroup.WithContext(ctx)
 	g.Go(func() error {
 		return httpServer.ListenAndServe()
 	})
 
+	if metricsServer != nil {
+		g.Go(func() error {
+			return metricsServer.ListenAndServe()
+		})
+	}
+
+	g.Go(func() error {
+		lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			return err
+		}
+
+		return grpcServer.Serve(lis)
+	})
+
+	g.Go(func() error {
+		return scheduler.Daily(gCtx, "interest", cfg.Interest.RunAt, services.GetInterestService().Run)
+	})
+
+	g.Go(func() error {
+		return scheduler.Daily(gCtx, "statements", cfg.Statements.RunAt, services.GetStatementService().Run)
+	})
+
+	g.Go(func() error {
+		return scheduler.Daily(gCtx, "payments", cfg.Payments.RunAt, services.GetPaymentService().Export)
+	})
+
+	g.Go(func() error {
+		return scheduler.Every(gCtx, "outbox", cfg.Outbox.PollInterval, services.GetOutboxService().Relay)
+	})
+
+	g.Go(func() error {
+		return scheduler.Every(gCtx, "webhooks", cfg.Webhooks.PollInterval, services.GetWebhookService().Deliver)
+	})
+
+	g.Go(func() error {
+		return scheduler.Every(gCtx, "balance metrics", cfg.Metrics.BalancesInterval, balanceMetrics(repo, m))
+	})
+
+	g.Go(func() error {
+		return services.GetUserService().SendPasswordResets(gCtx)
+	})
+
+	g.Go(func() error {
+		return psql.NewOutboxListener(cfg.DB.DSN()).Listen(gCtx, services.GetStreamService().Dispatch)
+	})
+
 	g.Go(func() error {
 		<-gCtx.Done()
-		return httpServer.Shutdown(context.Background())
+
+		// fail readiness first and give load balancers time to notice it, so new requests
+		// go to other instances while in-flight ones are finished
+		checker.Drain()
+		time.Sleep(cfg.Health.DrainDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Health.ShutdownTimeout)
+		defer cancel()
+
+		stopGRPC(ctx, grpcServer)
+		if metricsServer != nil {
+			if err := metricsServer.Shutdown(ctx); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"context": "app.Run()",
+					"problem": "can't shutdown metrics server",
+				}).Error(err.Error())
+			}
+		}
+		return httpServer.Shutdown(ctx)
 	})
 
 	logrus.Info("Server started")
@@ -97,3 +218,270 @@ func Run() {
 		}).Error(err.Error())
 	}
 }
+
+// stopGRPC waits for in-flight calls till ctx is done and then closes the rest.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+func loadConfig() *config.Config {
+	cfg, err := config.New("configs", "config")
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.loadConfig()",
+			"problem": "can't initialize config",
+		}).Fatal(err.Error())
+	}
+
+	return cfg
+}
+
+func initTracing(ctx context.Context, cfg *config.Config) func(ctx context.Context) error {
+	shutdown, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.initTracing()",
+			"problem": "can't initialize tracing",
+		}).Fatal(err.Error())
+	}
+
+	return shutdown
+}
+
+func connectDB(cfg *config.Config) *sql.DB {
+	db, err := database.NewPostgresConnection(cfg.DB)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.connectDB()",
+			"problem": "can't connect to DB",
+		}).Fatal(err.Error())
+	}
+
+	return db
+}
+
+// checkFeeAccounts stops the app if a fee income account doesn't exist or is in another
+// currency, so fees are never credited to a wrong account, or if a currency of fee rules or
+// of existing accounts charged by generic rules has no income account, so operations with
+// fees don't fail.
+func checkFeeAccounts(ctx context.Context, cfg *config.Config, repo *psql.Repositories) {
+	fatal := func(problem string, err error) {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.checkFeeAccounts()",
+			"problem": problem,
+		}).Fatal(err.Error())
+	}
+
+	balances, err := repo.GetAccountRepository().SumBalances(ctx)
+	if err != nil {
+		fatal("can't get account currencies", err)
+	}
+
+	currencies := make([]string, len(balances))
+	for i, b := range balances {
+		currencies[i] = b.Currency
+	}
+
+	if unfunded := cfg.Fees.Unfunded(currencies); len(unfunded) > 0 {
+		fatal("missing fee income account", fmt.Errorf("fees are charged in %s without income account", strings.Join(unfunded, ", ")))
+	}
+
+	for _, income := range cfg.Fees.IncomeAccounts {
+		account, err := repo.GetAccountRepository().Lookup(ctx, income.AccountId)
+		if err != nil {
+			err = fmt.Errorf("account %d: %w", income.AccountId, err)
+		} else if account.Currency != income.Currency {
+			err = fmt.Errorf("account %d is in %s, not %s", income.AccountId, account.Currency, income.Currency)
+		}
+		if err != nil {
+			fatal("invalid fee income account", err)
+		}
+	}
+}
+
+// newMetrics returns metrics with stats of the DB pool.
+func newMetrics(cfg *config.Config, db *sql.DB) *metrics.Metrics {
+	m := metrics.New()
+
+	if err := m.RegisterDB(db, cfg.DB.DBName); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.newMetrics()",
+			"problem": "can't register DB stats",
+		}).Fatal(err.Error())
+	}
+
+	return m
+}
+
+// balanceMetrics returns job updating balances of accounts in metrics.
+func balanceMetrics(repo *psql.Repositories, m *metrics.Metrics) scheduler.Job {
+	return func(ctx context.Context, now time.Time) error {
+		balances, err := repo.GetAccountRepository().SumBalances(ctx)
+		if err != nil {
+			return err
+		}
+
+		result := make(map[string]metrics.Balance, len(balances))
+		for _, b := range balances {
+			result[b.Currency] = metrics.Balance{Total: float64(b.Balance), Accounts: float64(b.Accounts)}
+		}
+		m.SetBalances(result)
+
+		return nil
+	}
+}
+
+// newHealth returns checker of the DB, the cache and the schema version.
+func newHealth(cfg *config.Config, db *sql.DB, c cache.Cache, migrator *migrate.Migrator) *health.Checker {
+	checker := health.New(cfg.Health.Timeout)
+
+	checker.Add("postgres", func(ctx context.Context) (string, error) {
+		return "", db.PingContext(ctx)
+	})
+
+	checker.Add("cache", func(ctx context.Context) (string, error) {
+		const key = "health:probe"
+		if err := c.Set(key, true, time.Second); err != nil {
+			return "", err
+		}
+
+		_, err := c.Get(key)
+		return "", err
+	})
+
+	checker.Add("migrations", func(ctx context.Context) (string, error) {
+		s, err := migrator.Check(ctx)
+		detail := fmt.Sprintf("version %d of %d", s.Version, migrator.Latest())
+		if err != nil {
+			return detail, err
+		}
+
+		if s.Version < migrator.Latest() {
+			return detail, errors.New("pending migrations")
+		}
+
+		return detail, nil
+	})
+
+	return checker
+}
+
+func newServices(cfg *config.Config, repo *psql.Repositories, cache cache.Cache, m *metrics.Metrics) *service.Services {
+	hasher := hash.NewSHA1Hasher(cfg.Auth.PasswordSalt)
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.newServices()",
+			"problem": "can't initialize mailer",
+		}).Fatal(err.Error())
+	}
+
+	events, err := publisher.New(cfg.Outbox.Publisher)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.newServices()",
+			"problem": "can't initialize event publisher",
+		}).Fatal(err.Error())
+	}
+
+	return service.NewServices(service.Deps{
+		Repos:         repo,
+		Cache:         cache,
+		Hasher:        hasher,
+		Mailer:        mail,
+		Publisher:     events,
+		WebhookSender: publisher.NewSender(cfg.Webhooks.Timeout, cfg.Webhooks.AllowInsecure),
+		Fees:          &cfg.Fees,
+		InterestRates: cfg.Interest.Rates,
+		PaymentExport: service.PaymentExportSettings{
+			Dir:        cfg.Payments.OutputDir,
+			Initiator:  cfg.Payments.Initiator,
+			DebtorName: cfg.Payments.Debtor.Name,
+			DebtorIban: cfg.Payments.Debtor.IBAN,
+			DebtorBic:  cfg.Payments.Debtor.BIC,
+		},
+		Iban: service.IbanSettings{
+			CountryCode: cfg.Iban.CountryCode,
+			BankCode:    cfg.Iban.BankCode,
+		},
+		Limits:     limitDefaults(cfg.Limits),
+		UserLimits: limitDefaults(cfg.UserLimits),
+		RiskEngine: service.NewRuleRiskEngine(repo, cfg.Risk),
+		Mfa: service.MfaSettings{
+			Issuer:        cfg.Auth.Mfa.Issuer,
+			ChallengeTTL:  cfg.Auth.Mfa.ChallengeTTL,
+			MaxAge:        cfg.Auth.Mfa.MaxAge,
+			LargeTransfer: cfg.Auth.Mfa.LargeTransfer,
+			MaxAttempts:   cfg.Auth.Mfa.MaxAttempts,
+			Lockout:       cfg.Auth.Mfa.Lockout,
+		},
+		Email: service.EmailSettings{
+			BaseURL:         cfg.BaseURL,
+			VerificationTTL: cfg.Auth.VerificationTTL,
+			ResetTTL:        cfg.Auth.ResetTTL,
+			ResetQueue:      cfg.Auth.Reset.Queue,
+			ResetWindow:     cfg.Auth.Reset.Window,
+			ResetEmailLimit: cfg.Auth.Reset.EmailLimit,
+			ResetIpLimit:    cfg.Auth.Reset.IpLimit,
+		},
+		Outbox: service.OutboxSettings{
+			BatchSize:  cfg.Outbox.BatchSize,
+			MinBackoff: cfg.Outbox.MinBackoff,
+			MaxBackoff: cfg.Outbox.MaxBackoff,
+			Lease:      cfg.Outbox.Lease,
+		},
+		Webhooks: service.WebhookSettings{
+			BatchSize:        cfg.Webhooks.BatchSize,
+			Concurrency:      cfg.Webhooks.Concurrency,
+			Lease:            cfg.Webhooks.Lease,
+			MaxAttempts:      cfg.Webhooks.MaxAttempts,
+			DisableAfter:     cfg.Webhooks.DisableAfter,
+			MinBackoff:       cfg.Webhooks.MinBackoff,
+			MaxBackoff:       cfg.Webhooks.MaxBackoff,
+			LargeTransaction: cfg.Webhooks.LargeTransaction,
+			AllowInsecure:    cfg.Webhooks.AllowInsecure,
+		},
+		Stream: service.StreamSettings{
+			Buffer:       cfg.Stream.Buffer,
+			ReplayBatch:  cfg.Stream.ReplayBatch,
+			ReplayWindow: cfg.Stream.ReplayWindow,
+		},
+		Metrics:         m,
+		HmacSecret:      []byte(cfg.Auth.Secret),
+		CacheTTL:        cfg.Cache.TTL,
+		AccessTokenTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL: cfg.Auth.RefreshTokenTTL,
+	})
+}
+
+// limitDefaults converts configured limits, where zero means unlimited.
+func limitDefaults(limits []config.Limit) service.LimitDefaults {
+	optional := func(v int64) *int64 {
+		if v == 0 {
+			return nil
+		}
+		return &v
+	}
+
+	defaults := make(service.LimitDefaults, len(limits))
+	for _, l := range limits {
+		defaults[l.Currency] = domain.Limits{
+			PerTransaction: optional(l.PerTransaction),
+			Daily:          optional(l.Daily),
+			Monthly:        optional(l.Monthly),
+		}
+	}
+
+	return defaults
+}
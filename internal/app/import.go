@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/internal/repository/psql"
+	"github.com/Viquad/crud-app/pkg/bankimport"
+	"github.com/Viquad/crud-app/pkg/metrics"
+	cache "github.com/Viquad/simple-cache"
+	"github.com/sirupsen/logrus"
+)
+
+// Import loads bank file into an account and prints the result as JSON:
+//
+//	main import -account 1 -format ofx -file statement.ofx [-dry-run]
+func Import(args []string) {
+	var (
+		mapping bankimport.CSVMapping
+		flags   = flag.NewFlagSet("import", flag.ExitOnError)
+	)
+
+	accountId := flags.Int64("account", 0, "account id")
+	format := flags.String("format", "", "file format: csv, ofx or camt053")
+	path := flags.String("file", "", "path to bank file")
+	dryRun := flags.Bool("dry-run", false, "preview import without posting transactions")
+	flags.StringVar(&mapping.Date, "date-column", "", "CSV date column name or index (default: date)")
+	flags.StringVar(&mapping.Amount, "amount-column", "", "CSV amount column name or index (default: amount)")
+	flags.StringVar(&mapping.Description, "description-column", "", "CSV description column name or index")
+	flags.StringVar(&mapping.Reference, "reference-column", "", "CSV external reference column name or index")
+	flags.StringVar(&mapping.Currency, "currency-column", "", "CSV currency column name or index")
+	flags.StringVar(&mapping.DateLayout, "date-layout", "", "CSV date layout in Go format (default: 2006-01-02)")
+	flags.StringVar(&mapping.Delimiter, "delimiter", "", "CSV delimiter (default: ,)")
+	flags.BoolVar(&mapping.NoHeader, "no-header", false, "CSV file has no header")
+	flags.Parse(args)
+
+	fatal := func(problem string, err error) {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Import()",
+			"problem": problem,
+		}).Fatal(err.Error())
+	}
+
+	parser, err := bankimport.NewParser(*format, mapping)
+	if err != nil {
+		fatal("parser error", err)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		fatal("file error", err)
+	}
+	defer file.Close()
+
+	parsed, err := parser.Parse(file)
+	if err != nil {
+		fatal("parsing file error", err)
+	}
+
+	entries := make([]domain.ImportEntry, 0, len(parsed))
+	for _, e := range parsed {
+		entries = append(entries, domain.ImportEntry{
+			ExternalRef: e.ExternalRef,
+			Date:        e.Date,
+			Amount:      e.Amount,
+			Currency:    e.Currency,
+			Description: e.Description,
+		})
+	}
+
+	cfg := loadConfig()
+
+	db := connectDB(cfg)
+	defer db.Close()
+
+	services := newServices(cfg, psql.NewRepositories(db), cache.NewMemoryCache(), metrics.New())
+
+	result, err := services.GetImportService().Import(context.Background(), *accountId, entries, *dryRun)
+	if err != nil {
+		fatal("service error", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fatal("output error", err)
+	}
+}
@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/Viquad/crud-app/pkg/config"
+	"github.com/Viquad/crud-app/pkg/migrate"
+	"github.com/Viquad/crud-app/schema"
+	"github.com/sirupsen/logrus"
+)
+
+// Migrate manages the DB schema from command line:
+//
+//	main migrate up
+//	main migrate down [-steps 1]
+//	main migrate status
+//	main migrate create -name add_cards [-dir schema]
+func Migrate(args []string) {
+	fatal := func(problem string, err error) {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Migrate()",
+			"problem": problem,
+		}).Fatal(err.Error())
+	}
+
+	if len(args) == 0 {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Migrate()",
+		}).Fatal("usage: migrate up | down [-steps 1] | status | create -name <name> [-dir schema]")
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	name := flags.String("name", "", "name of the new migration")
+	dir := flags.String("dir", "schema", "directory of migration files")
+	flags.Parse(args[1:])
+
+	// new files are written to the source tree, the DB isn't needed
+	if args[0] == "create" {
+		paths, err := migrate.Create(*dir, *name, time.Now())
+		if err != nil {
+			fatal("can't create migration", err)
+		}
+
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return
+	}
+
+	cfg := loadConfig()
+
+	db := connectDB(cfg)
+	defer db.Close()
+
+	m := newMigrator(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		if err != nil {
+			fatal("migration error", err)
+		}
+
+		for _, migration := range applied {
+			logrus.Infof("Migration %d_%s applied", migration.Version, migration.Name)
+		}
+		logrus.Infof("Schema is up to date, %d migrations applied", len(applied))
+	case "down":
+		rolledBack, err := m.Down(ctx, *steps)
+		if err != nil {
+			fatal("migration error", err)
+		}
+
+		for _, migration := range rolledBack {
+			logrus.Infof("Migration %d_%s rolled back", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			fatal("can't get status", err)
+		}
+
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%d_%s\t%s\n", s.Version, s.Name, state)
+		}
+
+		if _, err := m.Check(ctx); err != nil {
+			fatal("unsupported schema", err)
+		}
+	default:
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Migrate()",
+		}).Fatalf("unknown command %q", args[0])
+	}
+}
+
+func newMigrator(db *sql.DB) *migrate.Migrator {
+	m, err := migrate.New(db, schema.FS)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.newMigrator()",
+			"problem": "can't read migrations",
+		}).Fatal(err.Error())
+	}
+
+	return m
+}
+
+// migrateSchema applies pending migrations if configured and refuses to run against
+// a schema the app doesn't know.
+func migrateSchema(ctx context.Context, cfg *config.Config, m *migrate.Migrator) {
+	fatal := func(problem string, err error) {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.migrateSchema()",
+			"problem": problem,
+		}).Fatal(err.Error())
+	}
+
+	if cfg.Migrations.Auto {
+		applied, err := m.Up(ctx)
+		if err != nil {
+			fatal("migration error", err)
+		}
+
+		if len(applied) > 0 {
+			logrus.Infof("%d migrations applied", len(applied))
+		}
+	}
+
+	s, err := m.Check(ctx)
+	if err != nil {
+		fatal("unsupported schema", err)
+	}
+
+	if s.Version < m.Latest() {
+		logrus.Warnf("Schema version %d is behind %d, run migrate up", s.Version, m.Latest())
+	}
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/internal/repository/psql"
+	"github.com/Viquad/crud-app/pkg/metrics"
+	"github.com/Viquad/crud-app/pkg/pain"
+	cache "github.com/Viquad/simple-cache"
+	"github.com/sirupsen/logrus"
+)
+
+// Payments runs payment order batch operations from command line:
+//
+//	main payments export
+//	main payments status -file pain002.xml
+func Payments(args []string) {
+	fatal := func(problem string, err error) {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Payments()",
+			"problem": problem,
+		}).Fatal(err.Error())
+	}
+
+	if len(args) == 0 {
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Payments()",
+		}).Fatal("usage: payments export | payments status -file <pain.002 file>")
+	}
+
+	flags := flag.NewFlagSet("payments "+args[0], flag.ExitOnError)
+	path := flags.String("file", "", "path to pain.002 status report")
+	flags.Parse(args[1:])
+
+	cfg := loadConfig()
+
+	db := connectDB(cfg)
+	defer db.Close()
+
+	services := newServices(cfg, psql.NewRepositories(db), cache.NewMemoryCache(), metrics.New())
+	ctx := context.Background()
+
+	switch args[0] {
+	case "export":
+		if err := services.GetPaymentService().Export(ctx, time.Now().UTC()); err != nil {
+			fatal("export error", err)
+		}
+
+		logrus.Info("Payments exported")
+	case "status":
+		file, err := os.Open(*path)
+		if err != nil {
+			fatal("file error", err)
+		}
+		defer file.Close()
+
+		report, err := pain.ParseStatusReport(file)
+		if err != nil {
+			fatal("parsing file error", err)
+		}
+
+		updated, err := services.GetPaymentService().ApplyStatusReport(ctx, paymentStatusReport(report))
+		if err != nil {
+			fatal("service error", err)
+		}
+
+		logrus.Infof("Status report applied to %d payment orders", updated)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"context": "app.Payments()",
+		}).Fatalf("unknown command %q", args[0])
+	}
+}
+
+func paymentStatusReport(report *pain.StatusReport) domain.PaymentStatusReport {
+	result := domain.PaymentStatusReport{
+		MessageId: report.OriginalMessageId,
+		Status:    report.GroupStatus,
+		Reason:    report.GroupReason,
+	}
+
+	for _, t := range report.Transactions {
+		result.Transactions = append(result.Transactions, domain.PaymentTransactionStatus{
+			Reference: t.EndToEndId,
+			Status:    t.Status,
+			Reason:    t.Reason,
+		})
+	}
+
+	return result
+}
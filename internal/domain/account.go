@@ -5,19 +5,41 @@ import (
 	"time"
 )
 
+const (
+	AccountCurrent = "current"
+	AccountSavings = "savings"
+)
+
 type Account struct {
 	Id         int64     `form:"id" json:"id" example:"1"`
 	UserId     int64     `form:"id" json:"user_id" example:"1"`
+	Type       string    `form:"type" json:"type" example:"current"`
+	Iban       string    `form:"iban" json:"iban" example:"UA733223133686148276048075246"`
 	Balance    int64     `form:"balance" json:"balance" example:"1000"`
 	Currency   string    `form:"currency" json:"currency" example:"UAH"`
 	LastUpdate time.Time `form:"lastUpdate" json:"lastUpdate" example:"2022-08-25T14:58:16.413065Z"`
+	CreatedAt  time.Time `form:"createdAt" json:"createdAt" example:"2022-08-25T14:58:16.413065Z"`
 }
 
 type AccountCreateInput struct {
+	Type     string `form:"type" json:"type" binding:"omitempty,oneof=current savings" example:"current"`
 	Balance  int64  `form:"balance" json:"balance" binding:"required" example:"200"`
 	Currency string `form:"currency" json:"currency" binding:"required" example:"UAH"`
 }
 
+// AccountLookup is a public view of an account found by IBAN.
+type AccountLookup struct {
+	Iban     string `json:"iban" example:"UA733223133686148276048075246"`
+	Currency string `json:"currency" example:"UAH"`
+}
+
+// CurrencyBalance is the total balance of all accounts in the currency.
+type CurrencyBalance struct {
+	Currency string
+	Balance  int64
+	Accounts int64
+}
+
 type AccountUpdateInput struct {
 	Balance *int64 `form:"balance" json:"balance" example:"1000"`
 }
@@ -26,14 +48,28 @@ type AccountService interface {
 	Create(ctx context.Context, inp AccountCreateInput) (*Account, error)
 	List(ctx context.Context) ([]Account, error)
 	GetById(ctx context.Context, id int64) (*Account, error)
+	ListByIds(ctx context.Context, ids []int64) ([]Account, error)
+	ListRecentTransactions(ctx context.Context, ids []int64, limit int) ([]Transaction, error)
 	UpdateById(ctx context.Context, id int64, inp AccountUpdateInput) (*Account, error)
 	DeleteById(ctx context.Context, id int64) error
+	Transfer(ctx context.Context, id int64, inp TransferInput, dryRun bool) (*TransactionResult, error)
+	Withdraw(ctx context.Context, id int64, inp WithdrawInput, dryRun bool) (*TransactionResult, error)
+	LookupByIban(ctx context.Context, iban string) (*AccountLookup, error)
+	AssignIbans(ctx context.Context) (int, error)
 }
 
 type AccountRepository interface {
-	Create(ctx context.Context, inp AccountCreateInput) (*Account, error)
+	Create(ctx context.Context, inp AccountCreateInput, iban string) (*Account, error)
 	List(ctx context.Context) ([]Account, error)
 	GetById(ctx context.Context, id int64) (*Account, error)
+	ListByIds(ctx context.Context, ids []int64) ([]Account, error)
 	UpdateById(ctx context.Context, id int64, inp AccountUpdateInput) (*Account, error)
 	DeleteById(ctx context.Context, id int64) error
+	Lookup(ctx context.Context, id int64) (*Account, error)
+	AddBalance(ctx context.Context, id int64, delta int64) (*Account, error)
+	GetByIban(ctx context.Context, iban string) (*Account, error)
+	ListWithoutIban(ctx context.Context) ([]int64, error)
+	SetIban(ctx context.Context, id int64, iban string) error
+	Lock(ctx context.Context, id int64) error
+	SumBalances(ctx context.Context) ([]CurrencyBalance, error)
 }
@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	ClientIpKey  keyType = "client_ip"
+	RequestIdKey keyType = "request_id"
+	RouteKey     keyType = "route"
+)
+
+// Audited actions. Action is named by the entity and what happened to it.
+const (
+	AuditUserCreate         = "user.create"
+	AuditUserUpdate         = "user.update"
+	AuditUserVerifyEmail    = "user.verify_email"
+	AuditUserChangeEmail    = "user.change_email"
+	AuditUserChangePassword = "user.change_password"
+	AuditUserResetPassword  = "user.reset_password"
+	AuditUserEnableTotp     = "user.enable_totp"
+	AuditUserExport         = "user.export"
+	AuditUserErase          = "user.erase"
+	AuditAccountCreate      = "account.create"
+	AuditAccountUpdate      = "account.update"
+	AuditAccountDelete      = "account.delete"
+	AuditAccountSetIban     = "account.set_iban"
+	AuditAccountTransfer    = "account.transfer"
+	AuditAccountWithdraw    = "account.withdraw"
+	AuditAccountPayment     = "account.payment"
+	AuditAccountImport      = "account.import"
+	AuditAccountInterest    = "account.interest"
+	AuditLimitsLower        = "limits.lower"
+	AuditLimitsOverride     = "limits.override"
+	AuditUserLimitsOverride = "user_limits.override"
+	AuditPayeeCreate        = "payee.create"
+	AuditPayeeUpdate        = "payee.update"
+	AuditPayeeDelete        = "payee.delete"
+	AuditPaymentCreate      = "payment.create"
+	AuditPaymentStatus      = "payment.status"
+	AuditRiskReviewApprove  = "risk_review.approve"
+	AuditRiskReviewReject   = "risk_review.reject"
+	AuditWebhookCreate      = "webhook.create"
+	AuditWebhookUpdate      = "webhook.update"
+	AuditWebhookDelete      = "webhook.delete"
+)
+
+const (
+	AuditEntityUser       = "user"
+	AuditEntityAccount    = "account"
+	AuditEntityPayee      = "payee"
+	AuditEntityPayment    = "payment"
+	AuditEntityRiskReview = "risk_review"
+	AuditEntityWebhook    = "webhook"
+	AuditEntityUserLimits = "user_limits"
+)
+
+// AuditEvent records who changed what. Before and After are JSON snapshots of the entity.
+// For users and payees After holds only names of changed fields, so no personal data is kept.
+// Events form a hash chain: Hash covers the event and PrevHash of the previous one.
+type AuditEvent struct {
+	Id         int64           `json:"id" example:"1"`
+	ActorId    *int64          `json:"actor_id,omitempty" example:"1"`
+	Action     string          `json:"action" example:"account.update"`
+	EntityType string          `json:"entity_type" example:"account"`
+	EntityId   string          `json:"entity_id" example:"1"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	RequestId  string          `json:"request_id,omitempty" example:"5f0c6a3e9b1d4e7f8a2b3c4d5e6f7a8b"`
+	Ip         string          `json:"ip,omitempty" example:"127.0.0.1"`
+	CreatedAt  time.Time       `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+	PrevHash   string          `json:"prev_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Hash       string          `json:"hash" example:"60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"`
+}
+
+// ChainHash calculates hash of the event linked to PrevHash. Every field is length-prefixed,
+// so moving bytes between fields changes the hash.
+func (e AuditEvent) ChainHash() string {
+	var actor string
+	if e.ActorId != nil {
+		actor = strconv.FormatInt(*e.ActorId, 10)
+	}
+
+	h := sha256.New()
+	for _, field := range []string{
+		e.PrevHash, actor, e.Action, e.EntityType, e.EntityId, string(e.Before), string(e.After),
+		e.RequestId, e.Ip, e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	} {
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditFilter selects events. Empty fields match everything.
+type AuditFilter struct {
+	ActorId    *int64
+	Action     string
+	EntityType string
+	EntityId   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditVerification is a result of the hash chain check. BrokenId is the first event
+// which doesn't match its hash or the previous event.
+type AuditVerification struct {
+	Checked  int    `json:"checked" example:"1024"`
+	Valid    bool   `json:"valid" example:"true"`
+	BrokenId *int64 `json:"broken_id,omitempty" example:"42"`
+}
+
+type AuditService interface {
+	List(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+	Verify(ctx context.Context) (*AuditVerification, error)
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, e AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+	ListAfter(ctx context.Context, id int64, limit int) ([]AuditEvent, error)
+}
@@ -13,4 +13,30 @@ var (
 	ErrUserAlreadyExists   = errors.New("user already exists")
 	ErrAccessTokenExpired  = errors.New("access token expired")
 	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrCurrencyMismatch    = errors.New("currency mismatch")
+	ErrSameAccount         = errors.New("source and destination accounts are the same")
+	ErrNoFeeAccount        = errors.New("fee income account is not configured")
+	ErrInvalidPeriod       = errors.New("invalid period")
+	ErrInvalidDate         = errors.New("invalid date")
+	ErrInvalidIban         = errors.New("invalid iban")
+	ErrIbanAlreadyExists   = errors.New("iban already exists")
+	ErrPayeeAlreadyExists  = errors.New("payee with such iban already exists")
+	ErrLimitExceeded       = errors.New("limit exceeded")
+	ErrLimitRaise          = errors.New("limits can only be lowered")
+	ErrForbidden           = errors.New("forbidden")
+	ErrRiskChallenged      = errors.New("operation is held for review")
+	ErrRiskBlocked         = errors.New("operation is blocked")
+	ErrMfaRequired         = errors.New("second factor required")
+	ErrInvalidMfaCode      = errors.New("invalid second factor code")
+	ErrMfaNotEnrolled      = errors.New("totp is not enrolled")
+	ErrMfaAlreadyEnabled   = errors.New("totp is already enabled")
+	ErrMfaLocked           = errors.New("too many invalid second factor codes, try later")
+	ErrEmailNotVerified    = errors.New("email is not verified")
+	ErrEmailVerified       = errors.New("email is already verified")
+	ErrWrongPassword       = errors.New("wrong password")
+	ErrBalanceNotZero      = errors.New("account balance is not zero")
+	ErrAccountInUse        = errors.New("account has history and can't be deleted")
+	ErrInvalidWebhookURL   = errors.New("webhook url must be absolute https url")
+	ErrTooManyRequests     = errors.New("too many requests, try later")
 )
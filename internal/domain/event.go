@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Domain events published through the outbox.
+const (
+	EventUserRegistered       = "UserRegistered"
+	EventUserErased           = "UserErased"
+	EventAccountCreated       = "AccountCreated"
+	EventAccountUpdated       = "AccountUpdated"
+	EventAccountClosed        = "AccountClosed"
+	EventBalanceChanged       = "BalanceChanged"
+	EventPaymentStatusChanged = "PaymentStatusChanged"
+)
+
+// Aggregates events belong to. Events of the same aggregate are published in order.
+const (
+	AggregateUser    = "user"
+	AggregateAccount = "account"
+	AggregatePayment = "payment"
+)
+
+// Event is a domain event stored in the outbox in the same transaction as the change.
+type Event struct {
+	Id            int64           `json:"id" example:"1"`
+	AggregateType string          `json:"aggregate_type" example:"account"`
+	AggregateId   string          `json:"aggregate_id" example:"1"`
+	Type          string          `json:"type" example:"BalanceChanged"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt     time.Time       `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+	Attempts      int             `json:"-"`
+}
+
+// UserEvent is the payload of EventUserRegistered and EventUserErased. It carries only the id,
+// so personal data doesn't spread to the outbox and downstream systems.
+type UserEvent struct {
+	UserId int64 `json:"user_id" example:"1"`
+}
+
+// BalanceChanged is the payload of EventBalanceChanged: the ledger line and the balance after it.
+type BalanceChanged struct {
+	AccountId       int64  `json:"account_id" example:"1"`
+	UserId          int64  `json:"user_id" example:"1"`
+	Balance         int64  `json:"balance" example:"900"`
+	Currency        string `json:"currency" example:"UAH"`
+	Amount          int64  `json:"amount" example:"-100"`
+	TransactionId   int64  `json:"transaction_id" example:"1"`
+	TransactionType string `json:"transaction_type" example:"transfer"`
+	Reference       string `json:"reference" example:"4f1c2b8e9a7d6c5b"`
+}
+
+// PaymentStatusChanged is the payload of EventPaymentStatusChanged.
+type PaymentStatusChanged struct {
+	PaymentId int64  `json:"payment_id" example:"1"`
+	AccountId int64  `json:"account_id" example:"1"`
+	Reference string `json:"reference" example:"4f1c2b8e9a7d6c5b"`
+	Status    string `json:"status" example:"rejected"`
+	Reason    string `json:"reason,omitempty" example:"AC04"`
+}
+
+type OutboxService interface {
+	Relay(ctx context.Context, now time.Time) error
+}
+
+// StreamService pushes events of user's accounts to connected clients.
+type StreamService interface {
+	Subscribe(ctx context.Context, lastEventId int64) (<-chan Event, error)
+	Dispatch(ctx context.Context, eventId int64)
+	Close()
+}
+
+type OutboxRepository interface {
+	Create(ctx context.Context, e Event) error
+	GetById(ctx context.Context, id int64) (*Event, error)
+	ListAccountEvents(ctx context.Context, userId, lastEventId int64, since time.Time, afterId int64, limit int) ([]Event, error)
+	ClaimPending(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]Event, error)
+	ScrubUser(ctx context.Context, userId int64) error
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) error
+}
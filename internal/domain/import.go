@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const TransactionImport = "import"
+
+// ImportEntry is an entry of external bank file to be posted to an account.
+type ImportEntry struct {
+	ExternalRef string    `json:"external_ref" example:"2022080512345"`
+	Date        time.Time `json:"date" example:"2022-08-05T00:00:00Z"`
+	Amount      int64     `json:"amount" example:"-1250"`
+	Currency    string    `json:"currency" example:"UAH"`
+	Description string    `json:"description" example:"Coffee"`
+	Duplicate   bool      `json:"duplicate" example:"false"`
+}
+
+// ImportResult describes import. On dry run it is a preview and Transactions is empty.
+type ImportResult struct {
+	DryRun       bool          `json:"dry_run" example:"true"`
+	AccountId    int64         `json:"account_id" example:"1"`
+	Entries      []ImportEntry `json:"entries"`
+	Imported     int           `json:"imported" example:"2"`
+	Duplicates   int           `json:"duplicates" example:"1"`
+	Transactions []Transaction `json:"transactions,omitempty"`
+}
+
+type ImportService interface {
+	Import(ctx context.Context, accountId int64, entries []ImportEntry, dryRun bool) (*ImportResult, error)
+}
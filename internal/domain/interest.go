@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const TransactionInterest = "interest"
+
+// Kinds of interest accrual.
+const (
+	AccrualDaily = "daily"
+	AccrualCarry = "carry"
+)
+
+// InterestAccrual is interest accrued on savings account for one day, or a carry of the
+// fraction of minor unit left after capitalization, which is paid with the next one.
+// Amount is an exact decimal in minor units. Reference is set when accrual is capitalized.
+type InterestAccrual struct {
+	Id        int64
+	AccountId int64
+	Kind      string
+	Date      time.Time
+	Balance   int64
+	Rate      string
+	Amount    string
+	Reference *string
+}
+
+// AccountBalance is a balance of account at the end of some date.
+type AccountBalance struct {
+	AccountId int64
+	Currency  string
+	Balance   int64
+}
+
+type InterestService interface {
+	Run(ctx context.Context, now time.Time) error
+}
+
+type InterestRepository interface {
+	LastAccrualDate(ctx context.Context) (*time.Time, error)
+	ListSavingsBalances(ctx context.Context, date time.Time) ([]AccountBalance, error)
+	CreateAccrual(ctx context.Context, accrual InterestAccrual) error
+	ListAccountsToCapitalize(ctx context.Context, until time.Time) ([]int64, error)
+	ListUncapitalized(ctx context.Context, accountId int64, until time.Time) ([]InterestAccrual, error)
+	MarkCapitalized(ctx context.Context, accountId int64, until time.Time, reference string) error
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Limits set by admins override defaults, limits set by users can only lower them.
+const (
+	LimitsSetByAdmin = "admin"
+	LimitsSetByUser  = "user"
+)
+
+// Rolling windows of daily and monthly limits.
+const (
+	LimitDailyWindow   = 24 * time.Hour
+	LimitMonthlyWindow = 30 * 24 * time.Hour
+)
+
+// Limits restrict outgoing money movements of an account in minor units. Nil means unlimited.
+type Limits struct {
+	PerTransaction *int64 `form:"per_transaction" json:"per_transaction" binding:"omitempty,gte=0" example:"500000"`
+	Daily          *int64 `form:"daily" json:"daily" binding:"omitempty,gte=0" example:"1000000"`
+	Monthly        *int64 `form:"monthly" json:"monthly" binding:"omitempty,gte=0" example:"5000000"`
+}
+
+// AccountLimits are effective limits of the account with money spent in current windows.
+type AccountLimits struct {
+	Limits
+	AccountId    int64  `json:"account_id" example:"1"`
+	Currency     string `json:"currency" example:"UAH"`
+	DailySpent   int64  `json:"daily_spent" example:"20000"`
+	MonthlySpent int64  `json:"monthly_spent" example:"150000"`
+}
+
+// UserLimits are effective limits of all user's accounts in the currency with money spent
+// from them in current windows.
+type UserLimits struct {
+	Limits
+	Currency     string `json:"currency" example:"UAH"`
+	DailySpent   int64  `json:"daily_spent" example:"20000"`
+	MonthlySpent int64  `json:"monthly_spent" example:"150000"`
+}
+
+// UserLimitsInput overrides limits of all user's accounts in the currency.
+type UserLimitsInput struct {
+	Limits
+	Currency string `form:"currency" json:"currency" binding:"required,max=10" example:"UAH"`
+}
+
+// LimitExceededError is returned when operation exceeds one of account limits.
+// It matches ErrLimitExceeded with errors.Is.
+type LimitExceededError struct {
+	Limit     string `json:"limit" example:"daily"`
+	Remaining int64  `json:"remaining" example:"980000"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s %s, remaining allowance is %d", e.Limit, ErrLimitExceeded, e.Remaining)
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+type LimitService interface {
+	Get(ctx context.Context, accountId int64) (*AccountLimits, error)
+	Lower(ctx context.Context, accountId int64, inp Limits) (*AccountLimits, error)
+	Override(ctx context.Context, accountId int64, inp Limits) (*AccountLimits, error)
+	GetUser(ctx context.Context) ([]UserLimits, error)
+	OverrideUser(ctx context.Context, userId int64, inp UserLimitsInput) (*UserLimits, error)
+	Lock(ctx context.Context, account *Account) error
+	Check(ctx context.Context, account *Account, amount int64) error
+}
+
+type LimitRepository interface {
+	Get(ctx context.Context, accountId int64, setBy string) (*Limits, error)
+	Set(ctx context.Context, accountId int64, setBy string, limits Limits) error
+	GetUser(ctx context.Context, userId int64, currency string) (*Limits, error)
+	SetUser(ctx context.Context, userId int64, currency string, limits Limits) error
+	LockUser(ctx context.Context, userId int64) error
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MfaAtKey is the context key of the moment the second factor was passed, if it was.
+const MfaAtKey keyType = "mfa_at"
+
+// AccessClaims are claims of a valid access token.
+type AccessClaims struct {
+	UserId    int64
+	MfaAt     *time.Time
+	ExpiresAt time.Time
+}
+
+// TotpEnrollment is a new TOTP secret with key URI to show as QR code.
+type TotpEnrollment struct {
+	Secret string `json:"secret" example:"MBBO4DVJPHAG46KKWAAGOPVYZDFPT2D3"`
+	Uri    string `json:"uri" example:"otpauth://totp/CRUD%20bank:ofilatov@gmail.com?algorithm=SHA1&digits=6&issuer=CRUD+bank&period=30&secret=MBBO4DVJPHAG46KKWAAGOPVYZDFPT2D3"`
+}
+
+// TotpSettings is stored TOTP state of the user. Secret is empty until enrollment.
+type TotpSettings struct {
+	Secret         string
+	Enabled        bool
+	LastStep       int64
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+type MfaCodeInput struct {
+	Code string `form:"code" json:"code" binding:"required,max=32" example:"123456"`
+}
+
+type MfaSignInInput struct {
+	ChallengeToken string `form:"challenge_token" json:"challenge_token" binding:"required"`
+	Code           string `form:"code" json:"code" binding:"required,max=32" example:"123456"`
+}
+
+// MfaChallengeError is returned by sign in with password when the user has TOTP enabled.
+// ChallengeToken must be exchanged for tokens with a code. It matches ErrMfaRequired with errors.Is.
+type MfaChallengeError struct {
+	ChallengeToken string
+}
+
+func (e *MfaChallengeError) Error() string {
+	return ErrMfaRequired.Error()
+}
+
+func (e *MfaChallengeError) Unwrap() error {
+	return ErrMfaRequired
+}
+
+type MfaRepository interface {
+	GetTotp(ctx context.Context, userId int64) (*TotpSettings, error)
+	SetTotpSecret(ctx context.Context, userId int64, secret string) error
+	EnableTotp(ctx context.Context, userId int64, step int64) error
+	UseTotpStep(ctx context.Context, userId int64, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error)
+	FailAttempt(ctx context.Context, userId int64, maxAttempts int, lockUntil time.Time) error
+	ResetAttempts(ctx context.Context, userId int64) error
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Payee is a saved beneficiary of user's transfers.
+type Payee struct {
+	Id        int64      `json:"id" example:"1"`
+	UserId    int64      `json:"user_id" example:"1"`
+	Name      string     `json:"name" example:"Oleksii Filatov"`
+	Iban      string     `json:"iban" example:"UA733223133686148276048075246"`
+	Currency  string     `json:"currency" example:"UAH"`
+	CreatedAt time.Time  `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+	NameCheck *NameCheck `json:"name_check,omitempty"`
+}
+
+type PayeeInput struct {
+	Name     string `form:"name" json:"name" binding:"required,max=140" example:"Oleksii Filatov"`
+	Iban     string `form:"iban" json:"iban" binding:"required,max=42" example:"UA733223133686148276048075246"`
+	Currency string `form:"currency" json:"currency" binding:"required,max=10" example:"UAH"`
+}
+
+type PayeeUpdateInput struct {
+	Name     *string `form:"name" json:"name" binding:"omitempty,min=1,max=140" example:"Oleksii Filatov"`
+	Iban     *string `form:"iban" json:"iban" binding:"omitempty,max=42" example:"UA733223133686148276048075246"`
+	Currency *string `form:"currency" json:"currency" binding:"omitempty,min=1,max=10" example:"UAH"`
+}
+
+// NameCheck is a result of confirmation of payee: comparison of the name given
+// by payer with the holder of the target account, one of namematch results. On close
+// match Name is the masked holder's name, so payer can recognize the payee.
+type NameCheck struct {
+	Result string `json:"result" example:"close_match"`
+	Name   string `json:"name,omitempty" example:"O****** F******"`
+}
+
+type PayeeService interface {
+	Create(ctx context.Context, inp PayeeInput) (*Payee, error)
+	List(ctx context.Context) ([]Payee, error)
+	GetById(ctx context.Context, id int64) (*Payee, error)
+	UpdateById(ctx context.Context, id int64, inp PayeeUpdateInput) (*Payee, error)
+	DeleteById(ctx context.Context, id int64) error
+}
+
+type PayeeRepository interface {
+	Create(ctx context.Context, p Payee) (*Payee, error)
+	List(ctx context.Context) ([]Payee, error)
+	GetById(ctx context.Context, id int64) (*Payee, error)
+	UpdateById(ctx context.Context, id int64, inp PayeeUpdateInput) (*Payee, error)
+	DeleteById(ctx context.Context, id int64) error
+}
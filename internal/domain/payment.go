@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	PaymentQueued       = "queued"
+	PaymentExported     = "exported"
+	PaymentAcknowledged = "acknowledged"
+	PaymentRejected     = "rejected"
+)
+
+const (
+	TransactionPayment       = "payment"
+	TransactionPaymentRefund = "payment_refund"
+)
+
+// PaymentOrder is an outgoing transfer to another bank. Reference is both
+// the end-to-end id of the payment and the reference of its ledger lines.
+type PaymentOrder struct {
+	Id             int64      `json:"id" example:"1"`
+	AccountId      int64      `json:"account_id" example:"1"`
+	Amount         int64      `json:"amount" example:"10000"`
+	Currency       string     `json:"currency" example:"EUR"`
+	CreditorName   string     `json:"creditor_name" example:"John Doe"`
+	CreditorIban   string     `json:"creditor_iban" example:"DE89370400440532013000"`
+	CreditorBic    string     `json:"creditor_bic,omitempty" example:"COBADEFFXXX"`
+	RemittanceInfo string     `json:"remittance_info,omitempty" example:"Invoice 42"`
+	ExecutionDate  time.Time  `json:"execution_date" example:"2022-08-26T00:00:00Z"`
+	Status         string     `json:"status" example:"queued"`
+	StatusReason   string     `json:"status_reason,omitempty" example:"AC04"`
+	Reference      string     `json:"reference" example:"4f1c2b8e9a7d6c5b"`
+	MessageId      string     `json:"message_id,omitempty" example:"9a7d6c5b4f1c2b8e"`
+	ExportedAt     *time.Time `json:"exported_at,omitempty" example:"2022-08-26T00:30:00Z"`
+	CreatedAt      time.Time  `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+}
+
+type PaymentOrderInput struct {
+	CreditorName   string `form:"creditor_name" json:"creditor_name" binding:"required,max=70" example:"John Doe"`
+	CreditorIban   string `form:"creditor_iban" json:"creditor_iban" binding:"required,max=34" example:"DE89370400440532013000"`
+	CreditorBic    string `form:"creditor_bic" json:"creditor_bic" binding:"omitempty,min=8,max=11" example:"COBADEFFXXX"`
+	Amount         int64  `form:"amount" json:"amount" binding:"required,gt=0" example:"10000"`
+	RemittanceInfo string `form:"remittance_info" json:"remittance_info" binding:"max=140" example:"Invoice 42"`
+	ExecutionDate  string `form:"execution_date" json:"execution_date" binding:"omitempty,datetime=2006-01-02" example:"2022-08-26"`
+}
+
+// PaymentBatch is a group of queued orders exported to one file.
+type PaymentBatch struct {
+	Currency      string
+	ExecutionDate time.Time
+}
+
+// PaymentStatusReport is a status report of exported payments by clearing system.
+type PaymentStatusReport struct {
+	MessageId    string
+	Status       string
+	Reason       string
+	Transactions []PaymentTransactionStatus
+}
+
+type PaymentTransactionStatus struct {
+	Reference string
+	Status    string
+	Reason    string
+}
+
+type PaymentService interface {
+	Create(ctx context.Context, accountId int64, inp PaymentOrderInput) (*PaymentOrder, error)
+	List(ctx context.Context, accountId int64) ([]PaymentOrder, error)
+	Export(ctx context.Context, now time.Time) error
+	ApplyStatusReport(ctx context.Context, report PaymentStatusReport) (int, error)
+}
+
+type PaymentRepository interface {
+	Create(ctx context.Context, order PaymentOrder) (*PaymentOrder, error)
+	List(ctx context.Context, accountId int64) ([]PaymentOrder, error)
+	ListQueuedBatches(ctx context.Context) ([]PaymentBatch, error)
+	MarkExported(ctx context.Context, batch PaymentBatch, messageId string, exportedAt time.Time) (int64, error)
+	ListUnwritten(ctx context.Context) ([]PaymentOrder, error)
+	MarkWritten(ctx context.Context, messageId string, writtenAt time.Time) (int64, error)
+	ListByMessageId(ctx context.Context, messageId string) ([]PaymentOrder, error)
+	UpdateStatus(ctx context.Context, reference, status, reason string) (*PaymentOrder, error)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// UserExport is personal data of the user given on data subject access request.
+type UserExport struct {
+	Profile      *User
+	Accounts     []Account
+	Transactions []Transaction
+	Payees       []Payee
+	Sessions     []SessionExport
+}
+
+// SessionExport describes refresh session without its token.
+type SessionExport struct {
+	Id        int64     `json:"id" example:"1"`
+	ExpiresAt time.Time `json:"expires_at" example:"2022-08-25T14:58:16.413065Z"`
+}
+
+type PrivacyService interface {
+	Export(ctx context.Context) (*UserExport, error)
+	Erase(ctx context.Context) error
+}
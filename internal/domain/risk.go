@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Viquad/crud-app/pkg/risk"
+)
+
+const (
+	RiskReviewPending  = "pending"
+	RiskReviewApproved = "approved"
+	RiskReviewRejected = "rejected"
+	RiskReviewUsed     = "used"
+)
+
+// RiskApprovalTTL is how long an approved operation can be retried.
+const RiskApprovalTTL = 24 * time.Hour
+
+// RiskOperation is an outgoing money movement to be screened. ToAccount is nil for withdrawals.
+type RiskOperation struct {
+	Operation string
+	UserId    int64
+	Account   *Account
+	ToAccount *Account
+	Amount    int64
+}
+
+// RiskAssessment is the decision of risk engine: risk.Allow, risk.Challenge or risk.Block.
+type RiskAssessment struct {
+	Decision string   `json:"decision" example:"challenge"`
+	Rules    []string `json:"rules" example:"new_payee"`
+}
+
+// RiskReview is a challenged or blocked operation waiting for admin's decision.
+// Approved operation is allowed once when the user retries it.
+type RiskReview struct {
+	Id          int64      `json:"id" example:"1"`
+	UserId      int64      `json:"user_id" example:"1"`
+	AccountId   int64      `json:"account_id" example:"1"`
+	ToAccountId *int64     `json:"to_account_id,omitempty" example:"2"`
+	Operation   string     `json:"operation" example:"transfer"`
+	Amount      int64      `json:"amount" example:"500000"`
+	Currency    string     `json:"currency" example:"UAH"`
+	Decision    string     `json:"decision" example:"challenge"`
+	Rules       []string   `json:"rules" example:"new_payee"`
+	Status      string     `json:"status" example:"pending"`
+	ReviewedBy  *int64     `json:"reviewed_by,omitempty" example:"3"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty" example:"2022-08-25T15:00:00Z"`
+	CreatedAt   time.Time  `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+}
+
+// RiskError is returned for challenged and blocked operations.
+// It matches ErrRiskChallenged or ErrRiskBlocked with errors.Is.
+type RiskError struct {
+	Decision string   `json:"decision" example:"challenge"`
+	Rules    []string `json:"rules" example:"new_payee"`
+	ReviewId int64    `json:"review_id" example:"1"`
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("%s by rules %v, review %d", e.Unwrap(), e.Rules, e.ReviewId)
+}
+
+func (e *RiskError) Unwrap() error {
+	if e.Decision == risk.Block {
+		return ErrRiskBlocked
+	}
+
+	return ErrRiskChallenged
+}
+
+// RiskEngine assesses outgoing money movements before they are committed.
+type RiskEngine interface {
+	Assess(ctx context.Context, op RiskOperation) (*RiskAssessment, error)
+}
+
+type RiskService interface {
+	List(ctx context.Context, status string) ([]RiskReview, error)
+	Approve(ctx context.Context, id int64) (*RiskReview, error)
+	Reject(ctx context.Context, id int64) (*RiskReview, error)
+}
+
+type RiskRepository interface {
+	Create(ctx context.Context, review RiskReview) (*RiskReview, error)
+	List(ctx context.Context, status string) ([]RiskReview, error)
+	Resolve(ctx context.Context, id int64, status string, reviewer int64) (*RiskReview, error)
+	UseApproved(ctx context.Context, op RiskOperation, since time.Time) (bool, error)
+}
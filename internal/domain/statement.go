@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const StatementPeriodLayout = "2006-01"
+
+// Statement is an account statement for a calendar month.
+type Statement struct {
+	AccountId      int64           `json:"account_id" example:"1"`
+	Type           string          `json:"type" example:"current"`
+	Currency       string          `json:"currency" example:"UAH"`
+	Period         string          `json:"period" example:"2022-08"`
+	From           time.Time       `json:"from" example:"2022-08-01T00:00:00Z"`
+	To             time.Time       `json:"to" example:"2022-09-01T00:00:00Z"`
+	OpeningBalance int64           `json:"opening_balance" example:"1000"`
+	ClosingBalance int64           `json:"closing_balance" example:"900"`
+	Lines          []StatementLine `json:"lines"`
+	GeneratedAt    time.Time       `json:"generated_at" example:"2022-09-01T01:00:00Z"`
+}
+
+// StatementLine is a transaction with account balance after it.
+type StatementLine struct {
+	Transaction
+	Balance int64 `json:"balance" example:"900"`
+}
+
+type StatementSummary struct {
+	AccountId      int64     `json:"account_id" example:"1"`
+	Period         string    `json:"period" example:"2022-08"`
+	OpeningBalance int64     `json:"opening_balance" example:"1000"`
+	ClosingBalance int64     `json:"closing_balance" example:"900"`
+	GeneratedAt    time.Time `json:"generated_at" example:"2022-09-01T01:00:00Z"`
+}
+
+type StatementService interface {
+	List(ctx context.Context, accountId int64) ([]StatementSummary, error)
+	Get(ctx context.Context, accountId int64, period string) (*Statement, error)
+	Run(ctx context.Context, now time.Time) error
+}
+
+type StatementRepository interface {
+	Create(ctx context.Context, s Statement) error
+	Get(ctx context.Context, accountId int64, period string) (*Statement, error)
+	List(ctx context.Context, accountId int64) ([]StatementSummary, error)
+	ListAccountsWithout(ctx context.Context, period string, createdBefore time.Time) ([]int64, error)
+}
@@ -15,4 +15,12 @@ type RefreshSession struct {
 type TokenRepository interface {
 	Create(ctx context.Context, token RefreshSession) error
 	Get(ctx context.Context, token string) (*RefreshSession, error)
+	DeleteByUser(ctx context.Context, userId int64) error
+	ListByUser(ctx context.Context, userId int64) ([]RefreshSession, error)
+}
+
+// PasswordResetRepository stores hashes of single-use password reset tokens.
+type PasswordResetRepository interface {
+	Create(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error
+	Use(ctx context.Context, tokenHash string) (int64, error)
 }
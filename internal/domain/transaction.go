@@ -1,12 +1,69 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
-// TODO: still not implemented
+const (
+	TransactionTransfer   = "transfer"
+	TransactionWithdrawal = "withdrawal"
+	TransactionFee        = "fee"
+	TransactionAdjustment = "adjustment"
+)
+
+// Transaction is a single ledger line. Lines created by one operation share the same reference.
 type Transaction struct {
-	Id          int64
-	AccountId   int64
-	Amount      float64
-	Description string
-	Date        time.Time
+	Id          int64      `json:"id" example:"1"`
+	AccountId   int64      `json:"account_id" example:"1"`
+	Amount      int64      `json:"amount" example:"-100"`
+	Currency    string     `json:"currency" example:"UAH"`
+	Type        string     `json:"type" example:"transfer"`
+	Reference   string     `json:"reference" example:"4f1c2b8e9a7d6c5b"`
+	Description string     `json:"description" example:"rent"`
+	ExternalRef string     `json:"external_ref,omitempty" example:"2022080512345"`
+	BookedAt    *time.Time `json:"booked_at,omitempty" example:"2022-08-24T00:00:00Z"`
+	Date        time.Time  `json:"date" example:"2022-08-25T14:58:16.413065Z"`
+}
+
+type Fee struct {
+	Name     string `json:"name" example:"transfer"`
+	Amount   int64  `json:"amount" example:"5"`
+	Currency string `json:"currency" example:"UAH"`
+}
+
+type TransferInput struct {
+	ToAccountId int64  `form:"to_account_id" json:"to_account_id" binding:"required_without_all=ToIban ToPayeeId" example:"2"`
+	ToIban      string `form:"to_iban" json:"to_iban" binding:"required_without_all=ToAccountId ToPayeeId,max=42" example:"UA733223133686148276048075246"`
+	ToPayeeId   int64  `form:"to_payee_id" json:"to_payee_id" binding:"required_without_all=ToAccountId ToIban" example:"1"`
+	PayeeName   string `form:"payee_name" json:"payee_name" binding:"max=140" example:"Oleksii Filatov"`
+	Amount      int64  `form:"amount" json:"amount" binding:"required,gt=0" example:"100"`
+	Description string `form:"description" json:"description" binding:"lte=255" example:"rent"`
+}
+
+type WithdrawInput struct {
+	Amount      int64  `form:"amount" json:"amount" binding:"required,gt=0" example:"100"`
+	Description string `form:"description" json:"description" binding:"lte=255" example:"ATM"`
+}
+
+// TransactionResult describes money movement. On dry run it is a preview and Transactions is empty.
+type TransactionResult struct {
+	DryRun       bool          `json:"dry_run" example:"false"`
+	Amount       int64         `json:"amount" example:"100"`
+	Fees         []Fee         `json:"fees"`
+	Total        int64         `json:"total" example:"105"`
+	NameCheck    *NameCheck    `json:"name_check,omitempty"`
+	Transactions []Transaction `json:"transactions,omitempty"`
+}
+
+type TransactionRepository interface {
+	Create(ctx context.Context, t Transaction) (*Transaction, error)
+	List(ctx context.Context, accountId int64, from, to time.Time) ([]Transaction, error)
+	ListRecent(ctx context.Context, accountIds []int64, limit int) ([]Transaction, error)
+	SumBefore(ctx context.Context, accountId int64, before time.Time) (int64, error)
+	SumOutgoing(ctx context.Context, accountId int64, since time.Time) (int64, error)
+	SumUserOutgoing(ctx context.Context, userId int64, currency string, since time.Time) (int64, error)
+	CountOutgoing(ctx context.Context, accountId int64, since time.Time) (int, int64, error)
+	HasTransferred(ctx context.Context, fromId, toId int64) (bool, error)
+	ListExternalRefs(ctx context.Context, accountId int64, refs []string) ([]string, error)
 }
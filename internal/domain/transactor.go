@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// Transactor runs fn atomically: all repository calls made with fn's context
+// are committed or rolled back together.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -9,13 +9,22 @@ type keyType string
 
 const UserIdKey keyType = "user_id"
 
+// TokenExpiresAtKey is the context key of expiration time of the access token of the request.
+const TokenExpiresAtKey keyType = "token_expires_at"
+
 type User struct {
-	Id           int64     `form:"id" json:"id" example:"1"`
-	FirstName    string    `form:"firstName" json:"firstName" binding:"required"`
-	LastName     string    `form:"lastName" json:"lastName" binding:"required"`
-	Email        string    `form:"email" json:"email" binding:"required"`
-	Password     string    `form:"password" json:"password" binding:"required"`
-	RegisteredAt time.Time `form:"lastUpdate" json:"lastUpdate"`
+	Id                int64      `form:"id" json:"id" example:"1"`
+	FirstName         string     `form:"firstName" json:"firstName" binding:"required"`
+	LastName          string     `form:"lastName" json:"lastName" binding:"required"`
+	Email             string     `form:"email" json:"email" binding:"required"`
+	Phone             *string    `form:"phone" json:"phone,omitempty"`
+	Password          string     `form:"password" json:"-"`
+	Tier              string     `form:"tier" json:"tier"`
+	Role              string     `form:"role" json:"role"`
+	RegisteredAt      time.Time  `form:"lastUpdate" json:"lastUpdate"`
+	PasswordChangedAt *time.Time `form:"passwordChangedAt" json:"passwordChangedAt,omitempty"`
+	TotpEnabled       bool       `form:"totpEnabled" json:"totpEnabled"`
+	EmailVerifiedAt   *time.Time `form:"emailVerifiedAt" json:"emailVerifiedAt,omitempty"`
 }
 
 type SignUpInput struct {
@@ -30,14 +39,62 @@ type SignInInput struct {
 	Password string `form:"password" json:"password" binding:"required,gte=8" example:"TheBestGuy99"`
 }
 
+type ForgotPasswordInput struct {
+	Email string `form:"email" json:"email" binding:"required,email" example:"ofilatov@gmail.com"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `form:"token" json:"token" binding:"required"`
+	Password string `form:"password" json:"password" binding:"required,gte=8" example:"TheBestGuy99"`
+}
+
+// UserUpdateInput changes profile of the signed in user. Omitted fields are left unchanged.
+type UserUpdateInput struct {
+	FirstName *string `form:"firstName" json:"firstName" binding:"omitempty,gte=2,max=255" example:"Oleksii"`
+	LastName  *string `form:"lastName" json:"lastName" binding:"omitempty,gte=2,max=255" example:"Filatov"`
+	Phone     *string `form:"phone" json:"phone" binding:"omitempty,e164" example:"+380501234567"`
+}
+
+type ChangePasswordInput struct {
+	OldPassword string `form:"oldPassword" json:"oldPassword" binding:"required" example:"TheBestGuy99"`
+	NewPassword string `form:"newPassword" json:"newPassword" binding:"required,gte=8,nefield=OldPassword" example:"TheBestGuy100"`
+}
+
+type ChangeEmailInput struct {
+	Email    string `form:"email" json:"email" binding:"required,email" example:"ofilatov@gmail.com"`
+	Password string `form:"password" json:"password" binding:"required" example:"TheBestGuy99"`
+}
+
 type UserService interface {
 	Create(ctx context.Context, input SignUpInput) error
 	GetTokenByCredentials(ctx context.Context, input SignInInput) (string, string, error)
-	ParseToken(ctx context.Context, token string) (int64, error)
+	ParseToken(ctx context.Context, token string) (*AccessClaims, error)
 	RefreshTokens(ctx context.Context, token string) (string, string, error)
+	GetById(ctx context.Context, id int64) (*User, error)
+	EnrollTotp(ctx context.Context) (*TotpEnrollment, error)
+	ConfirmTotp(ctx context.Context, code string) ([]string, error)
+	SignInWithMfa(ctx context.Context, input MfaSignInInput) (string, string, error)
+	StepUp(ctx context.Context, code string) (string, string, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context) error
+	ForgotPassword(ctx context.Context, input ForgotPasswordInput) error
+	SendPasswordResets(ctx context.Context) error
+	ResetPassword(ctx context.Context, input ResetPasswordInput) error
+	GetProfile(ctx context.Context) (*User, error)
+	UpdateProfile(ctx context.Context, input UserUpdateInput) (*User, error)
+	ChangePassword(ctx context.Context, input ChangePasswordInput) (string, string, error)
+	ChangeEmail(ctx context.Context, input ChangeEmailInput) error
 }
 
 type UserRepository interface {
-	Create(ctx context.Context, input SignUpInput) error
+	Create(ctx context.Context, input SignUpInput) (int64, error)
 	GetByCredentials(ctx context.Context, input SignInInput) (*User, error)
+	GetById(ctx context.Context, id int64) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	SetEmailVerified(ctx context.Context, id int64, email string) error
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	UpdateById(ctx context.Context, id int64, input UserUpdateInput) (*User, error)
+	UpdateEmail(ctx context.Context, id int64, email string) error
+	Erase(ctx context.Context, id int64) error
+	IsActive(ctx context.Context, id int64) (bool, error)
 }
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Event types users can subscribe webhooks to.
+const (
+	WebhookBalanceChanged       = "balance.changed"
+	WebhookLargeTransaction     = "transaction.large"
+	WebhookAccountCreated       = "account.created"
+	WebhookAccountUpdated       = "account.updated"
+	WebhookAccountClosed        = "account.closed"
+	WebhookPaymentStatusChanged = "payment.status_changed"
+)
+
+// Statuses of webhook delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is user's endpoint receiving events of the subscribed types. It is disabled
+// after too many failed attempts in a row. Secret is returned only on creation.
+type Webhook struct {
+	Id         int64      `json:"id" example:"1"`
+	UserId     int64      `json:"user_id" example:"1"`
+	URL        string     `json:"url" example:"https://example.com/hooks/bank"`
+	EventTypes []string   `json:"event_types" example:"balance.changed,transaction.large"`
+	Enabled    bool       `json:"enabled" example:"true"`
+	Failures   int        `json:"failures" example:"0"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty" example:"2022-08-25T14:58:16.413065Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+	Secret     string     `json:"secret,omitempty" example:"whsec_9f86d081884c7d659a2feaa0c55ad015"`
+}
+
+type WebhookInput struct {
+	URL        string   `json:"url" binding:"required,url,max=2048" example:"https://example.com/hooks/bank"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=balance.changed transaction.large account.created account.updated account.closed payment.status_changed" example:"balance.changed"`
+}
+
+// WebhookUpdateInput changes the webhook. Enabling it again resets failures.
+type WebhookUpdateInput struct {
+	URL        *string  `json:"url" binding:"omitempty,url,max=2048" example:"https://example.com/hooks/bank"`
+	EventTypes []string `json:"event_types" binding:"omitempty,min=1,dive,oneof=balance.changed transaction.large account.created account.updated account.closed payment.status_changed" example:"balance.changed"`
+	Enabled    *bool    `json:"enabled" example:"true"`
+}
+
+// WebhookDelivery is an event queued for the webhook and the result of the last attempt.
+type WebhookDelivery struct {
+	Id             int64           `json:"id" example:"1"`
+	WebhookId      int64           `json:"webhook_id" example:"1"`
+	EventId        int64           `json:"event_id" example:"1"`
+	EventType      string          `json:"event_type" example:"balance.changed"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status" example:"failed"`
+	Attempts       int             `json:"attempts" example:"8"`
+	ResponseStatus *int            `json:"response_status,omitempty" example:"500"`
+	LastError      string          `json:"last_error,omitempty" example:"webhook responded 500 Internal Server Error"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" example:"2022-08-25T14:58:16.413065Z"`
+	CreatedAt      time.Time       `json:"created_at" example:"2022-08-25T14:58:16.413065Z"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" example:"2022-08-25T14:58:16.413065Z"`
+}
+
+// DueDelivery is a delivery due for an attempt with the endpoint it goes to.
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+type WebhookService interface {
+	Create(ctx context.Context, inp WebhookInput) (*Webhook, error)
+	List(ctx context.Context) ([]Webhook, error)
+	GetById(ctx context.Context, id int64) (*Webhook, error)
+	UpdateById(ctx context.Context, id int64, inp WebhookUpdateInput) (*Webhook, error)
+	DeleteById(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, id int64) ([]WebhookDelivery, error)
+	Resend(ctx context.Context, id, deliveryId int64) (*WebhookDelivery, error)
+	Deliver(ctx context.Context, now time.Time) error
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, w Webhook) (*Webhook, error)
+	List(ctx context.Context) ([]Webhook, error)
+	GetById(ctx context.Context, id int64) (*Webhook, error)
+	UpdateById(ctx context.Context, id int64, inp WebhookUpdateInput) (*Webhook, error)
+	DeleteById(ctx context.Context, id int64) error
+	ListSubscribed(ctx context.Context, userId int64, eventType string) ([]Webhook, error)
+	AddFailure(ctx context.Context, id int64, disableAfter int) (bool, error)
+	ResetFailures(ctx context.Context, id int64) error
+
+	CreateDelivery(ctx context.Context, d WebhookDelivery) error
+	ListDeliveries(ctx context.Context, id int64, limit int) ([]WebhookDelivery, error)
+	ResetDelivery(ctx context.Context, id, deliveryId int64) (*WebhookDelivery, error)
+	ClaimDue(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]DueDelivery, error)
+	MarkDelivered(ctx context.Context, deliveryId int64, responseStatus int) error
+	MarkAttemptFailed(ctx context.Context, deliveryId int64, responseStatus *int, lastError string, nextAttemptAt *time.Time) error
+}
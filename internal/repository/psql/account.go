@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/lib/pq"
 )
 
 type AccountRepository struct {
@@ -20,7 +21,7 @@ func NewAccountRepository(db *sql.DB) *AccountRepository {
 	}
 }
 
-func (b *AccountRepository) Create(ctx context.Context, inp domain.AccountCreateInput) (*domain.Account, error) {
+func (b *AccountRepository) Create(ctx context.Context, inp domain.AccountCreateInput, iban string) (*domain.Account, error) {
 	userId, ok := ctx.Value(domain.UserIdKey).(int64)
 	if !ok {
 		return nil, domain.ErrInvalidId
@@ -28,13 +29,23 @@ func (b *AccountRepository) Create(ctx context.Context, inp domain.AccountCreate
 
 	account := domain.Account{
 		UserId:   userId,
+		Type:     inp.Type,
+		Iban:     iban,
 		Balance:  inp.Balance,
 		Currency: inp.Currency,
 	}
 
-	query := "INSERT INTO accounts (user_id, balance, currency) VALUES ($1, $2, $3) RETURNING id, last_update"
-	err := b.db.QueryRowContext(ctx, query, userId, inp.Balance, inp.Currency).
-		Scan(&account.Id, &account.LastUpdate)
+	if account.Type == "" {
+		account.Type = domain.AccountCurrent
+	}
+
+	query := "INSERT INTO accounts (user_id, type, iban, balance, currency) VALUES ($1, $2, $3, $4, $5) RETURNING id, last_update, created_at"
+	err := conn(ctx, b.db).QueryRowContext(ctx, query, userId, account.Type, iban, inp.Balance, inp.Currency).
+		Scan(&account.Id, &account.LastUpdate, &account.CreatedAt)
+
+	if isUniqueViolation(err, "accounts_iban_key") {
+		return nil, domain.ErrIbanAlreadyExists
+	}
 
 	if err != nil {
 		return nil, err
@@ -51,10 +62,10 @@ func (b *AccountRepository) GetById(ctx context.Context, id int64) (*domain.Acco
 		return nil, domain.ErrInvalidId
 	}
 
-	query := "SELECT id, user_id, balance, currency, last_update FROM accounts WHERE id = $1 AND user_id = $2"
-	row := b.db.QueryRowContext(ctx, query, id, userId)
+	query := "SELECT id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at FROM accounts WHERE id = $1 AND user_id = $2"
+	row := conn(ctx, b.db).QueryRowContext(ctx, query, id, userId)
 
-	if err := row.Scan(&account.Id, &account.UserId, &account.Balance, &account.Currency, &account.LastUpdate); err != nil {
+	if err := row.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotExist
 		}
@@ -72,8 +83,8 @@ func (b *AccountRepository) List(ctx context.Context) ([]domain.Account, error)
 		return nil, domain.ErrInvalidId
 	}
 
-	query := "SELECT id, user_id, balance, currency, last_update FROM accounts WHERE user_id = $1"
-	rows, err := b.db.QueryContext(ctx, query, userId)
+	query := "SELECT id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at FROM accounts WHERE user_id = $1"
+	rows, err := conn(ctx, b.db).QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +92,7 @@ func (b *AccountRepository) List(ctx context.Context) ([]domain.Account, error)
 
 	for rows.Next() {
 		var account domain.Account
-		if err := rows.Scan(&account.Id, &account.UserId, &account.Balance, &account.Currency, &account.LastUpdate); err != nil {
+		if err := rows.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt); err != nil {
 			return nil, err
 		}
 
@@ -91,6 +102,34 @@ func (b *AccountRepository) List(ctx context.Context) ([]domain.Account, error)
 	return accounts, nil
 }
 
+// ListByIds returns user's accounts with given ids. Ids of other users' accounts are skipped.
+func (b *AccountRepository) ListByIds(ctx context.Context, ids []int64) ([]domain.Account, error) {
+	var accounts []domain.Account
+
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := "SELECT id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at FROM accounts WHERE id = ANY($1) AND user_id = $2"
+	rows, err := conn(ctx, b.db).QueryContext(ctx, query, pq.Array(ids), userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var account domain.Account
+		if err := rows.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, rows.Err()
+}
+
 func (b *AccountRepository) UpdateById(ctx context.Context, id int64, inp domain.AccountUpdateInput) (*domain.Account, error) {
 	var (
 		account   domain.Account
@@ -115,12 +154,12 @@ func (b *AccountRepository) UpdateById(ctx context.Context, id int64, inp domain
 	addArg("now()", "last_update")
 
 	setQuery := strings.Join(setValues, ", ")
-	query := fmt.Sprintf("UPDATE accounts SET %s WHERE id=$%d AND user_id=$%d RETURNING id, user_id, balance, currency, last_update", setQuery, argIndex, argIndex+1)
+	query := fmt.Sprintf("UPDATE accounts SET %s WHERE id=$%d AND user_id=$%d RETURNING id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at", setQuery, argIndex, argIndex+1)
 	argIndex++
 	args = append(args, id, userId)
 
-	row := b.db.QueryRowContext(ctx, query, args...)
-	err := row.Scan(&account.Id, &account.UserId, &account.Balance, &account.Currency, &account.LastUpdate)
+	row := conn(ctx, b.db).QueryRowContext(ctx, query, args...)
+	err := row.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt)
 	if err != nil {
 		return nil, domain.ErrUpdateFailed
 	}
@@ -134,7 +173,11 @@ func (b *AccountRepository) DeleteById(ctx context.Context, id int64) error {
 		return domain.ErrInvalidId
 	}
 
-	res, err := b.db.ExecContext(ctx, "DELETE FROM accounts WHERE id=$1 AND user_id=$2", id, userId)
+	res, err := conn(ctx, b.db).ExecContext(ctx, "DELETE FROM accounts WHERE id=$1 AND user_id=$2", id, userId)
+	if isForeignKeyViolation(err) {
+		// transactions, statements and other records of the account are kept
+		return domain.ErrAccountInUse
+	}
 	if err != nil {
 		return err
 	}
@@ -150,3 +193,120 @@ func (b *AccountRepository) DeleteById(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// Lookup returns account by id regardless of its owner.
+func (b *AccountRepository) Lookup(ctx context.Context, id int64) (*domain.Account, error) {
+	var account domain.Account
+
+	query := "SELECT id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at FROM accounts WHERE id = $1"
+	row := conn(ctx, b.db).QueryRowContext(ctx, query, id)
+
+	if err := row.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotExist
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// AddBalance atomically changes account balance by delta. Balance can't become negative.
+func (b *AccountRepository) AddBalance(ctx context.Context, id int64, delta int64) (*domain.Account, error) {
+	var account domain.Account
+
+	query := "UPDATE accounts SET balance = balance + $1, last_update = now() WHERE id = $2 AND balance + $1 >= 0 RETURNING id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at"
+	row := conn(ctx, b.db).QueryRowContext(ctx, query, delta, id)
+
+	if err := row.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInsufficientFunds
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// GetByIban returns account by IBAN regardless of its owner.
+func (b *AccountRepository) GetByIban(ctx context.Context, iban string) (*domain.Account, error) {
+	var account domain.Account
+
+	query := "SELECT id, user_id, type, COALESCE(iban, ''), balance, currency, last_update, created_at FROM accounts WHERE iban = $1"
+	row := conn(ctx, b.db).QueryRowContext(ctx, query, iban)
+
+	if err := row.Scan(&account.Id, &account.UserId, &account.Type, &account.Iban, &account.Balance, &account.Currency, &account.LastUpdate, &account.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotExist
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (b *AccountRepository) ListWithoutIban(ctx context.Context) ([]int64, error) {
+	var ids []int64
+
+	rows, err := conn(ctx, b.db).QueryContext(ctx, "SELECT id FROM accounts WHERE iban IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// SetIban assigns IBAN to account which has none.
+func (b *AccountRepository) SetIban(ctx context.Context, id int64, iban string) error {
+	_, err := conn(ctx, b.db).ExecContext(ctx, "UPDATE accounts SET iban = $1 WHERE id = $2 AND iban IS NULL", iban, id)
+	if isUniqueViolation(err, "accounts_iban_key") {
+		return domain.ErrIbanAlreadyExists
+	}
+
+	return err
+}
+
+// Lock locks account row until the end of the transaction to serialize operations on it.
+func (b *AccountRepository) Lock(ctx context.Context, id int64) error {
+	var locked int64
+
+	err := conn(ctx, b.db).QueryRowContext(ctx, "SELECT id FROM accounts WHERE id = $1 FOR UPDATE", id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotExist
+	}
+
+	return err
+}
+
+// SumBalances returns total balance of accounts of all users by currency.
+func (b *AccountRepository) SumBalances(ctx context.Context) ([]domain.CurrencyBalance, error) {
+	var balances []domain.CurrencyBalance
+
+	query := "SELECT currency, COALESCE(SUM(balance), 0), COUNT(*) FROM accounts GROUP BY currency"
+	rows, err := conn(ctx, b.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var balance domain.CurrencyBalance
+		if err := rows.Scan(&balance.Currency, &balance.Balance, &balance.Accounts); err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
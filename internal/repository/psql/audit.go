@@ -0,0 +1,134 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+const auditColumns = "id, actor_id, action, entity_type, entity_id, before, after, request_id, ip, created_at, prev_hash, hash"
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+// Create appends event to the hash chain. Only the chain head row is locked, right before
+// the insert and till the end of the transaction, so events are linked in the order they
+// are committed while the rest of the transaction runs concurrently with others.
+func (r *AuditRepository) Create(ctx context.Context, e domain.AuditEvent) error {
+	return NewTransactor(r.db).WithinTransaction(ctx, func(ctx context.Context) error {
+		err := conn(ctx, r.db).QueryRowContext(ctx, "SELECT hash FROM audit_chain_head WHERE id FOR UPDATE").Scan(&e.PrevHash)
+		if err != nil {
+			return err
+		}
+
+		e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		e.Hash = e.ChainHash()
+
+		query := `INSERT INTO audit_events (actor_id, action, entity_type, entity_id, before, after, request_id, ip, created_at, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		_, err = conn(ctx, r.db).ExecContext(ctx, query, e.ActorId, e.Action, e.EntityType, e.EntityId,
+			nullJSON(e.Before), nullJSON(e.After), e.RequestId, e.Ip, e.CreatedAt, e.PrevHash, e.Hash)
+		if err != nil {
+			return err
+		}
+
+		_, err = conn(ctx, r.db).ExecContext(ctx, "UPDATE audit_chain_head SET hash = $1 WHERE id", e.Hash)
+
+		return err
+	})
+}
+
+// List returns events matching the filter, newest first.
+func (r *AuditRepository) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	var (
+		where    []string
+		args     []interface{}
+		argIndex = 1
+		addArg   = func(i interface{}, cond string) {
+			where = append(where, fmt.Sprintf(cond, argIndex))
+			args = append(args, i)
+			argIndex++
+		}
+	)
+
+	if filter.ActorId != nil {
+		addArg(*filter.ActorId, "actor_id = $%d")
+	}
+	if filter.Action != "" {
+		addArg(filter.Action, "action = $%d")
+	}
+	if filter.EntityType != "" {
+		addArg(filter.EntityType, "entity_type = $%d")
+	}
+	if filter.EntityId != "" {
+		addArg(filter.EntityId, "entity_id = $%d")
+	}
+	if filter.From != nil {
+		addArg(*filter.From, "created_at >= $%d")
+	}
+	if filter.To != nil {
+		addArg(*filter.To, "created_at < $%d")
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	return r.list(ctx, query, args...)
+}
+
+// ListAfter returns up to limit events following the given id in chain order.
+func (r *AuditRepository) ListAfter(ctx context.Context, id int64, limit int) ([]domain.AuditEvent, error) {
+	query := "SELECT " + auditColumns + " FROM audit_events WHERE id > $1 ORDER BY id LIMIT $2"
+	return r.list(ctx, query, id, limit)
+}
+
+func (r *AuditRepository) list(ctx context.Context, query string, args ...interface{}) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e             domain.AuditEvent
+			before, after []byte
+		)
+		err := rows.Scan(&e.Id, &e.ActorId, &e.Action, &e.EntityType, &e.EntityId, &before, &after,
+			&e.RequestId, &e.Ip, &e.CreatedAt, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Before, e.After = before, after
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func nullJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+
+	return []byte(data)
+}
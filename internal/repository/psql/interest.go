@@ -0,0 +1,122 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type InterestRepository struct {
+	db *sql.DB
+}
+
+func NewInterestRepository(db *sql.DB) *InterestRepository {
+	return &InterestRepository{
+		db: db,
+	}
+}
+
+func (r *InterestRepository) LastAccrualDate(ctx context.Context) (*time.Time, error) {
+	var date sql.NullTime
+	query := "SELECT MAX(date) FROM interest_accruals WHERE kind = $1"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, domain.AccrualDaily).Scan(&date)
+	if err != nil || !date.Valid {
+		return nil, err
+	}
+
+	return &date.Time, nil
+}
+
+// ListSavingsBalances returns balances of savings accounts at the end of date restored from the ledger.
+func (r *InterestRepository) ListSavingsBalances(ctx context.Context, date time.Time) ([]domain.AccountBalance, error) {
+	var balances []domain.AccountBalance
+
+	query := `SELECT a.id, a.currency, a.balance - COALESCE(SUM(t.amount), 0)
+		FROM accounts a LEFT JOIN transactions t ON t.account_id = a.id AND t.created_at >= $2
+		WHERE a.type = $1
+		GROUP BY a.id, a.currency, a.balance`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, domain.AccountSavings, date.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b domain.AccountBalance
+		if err := rows.Scan(&b.AccountId, &b.Currency, &b.Balance); err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, b)
+	}
+
+	return balances, rows.Err()
+}
+
+// CreateAccrual stores accrual. Accrual of the same kind for the same account and date is ignored.
+func (r *InterestRepository) CreateAccrual(ctx context.Context, a domain.InterestAccrual) error {
+	if a.Kind == "" {
+		a.Kind = domain.AccrualDaily
+	}
+
+	query := `INSERT INTO interest_accruals (account_id, kind, date, balance, rate, amount) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (account_id, date, kind) DO NOTHING`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, a.AccountId, a.Kind, a.Date, a.Balance, a.Rate, a.Amount)
+
+	return err
+}
+
+func (r *InterestRepository) ListAccountsToCapitalize(ctx context.Context, until time.Time) ([]int64, error) {
+	var ids []int64
+
+	query := "SELECT DISTINCT account_id FROM interest_accruals WHERE reference IS NULL AND date <= $1"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ListUncapitalized locks and returns accruals of the account which are not paid yet.
+func (r *InterestRepository) ListUncapitalized(ctx context.Context, accountId int64, until time.Time) ([]domain.InterestAccrual, error) {
+	var accruals []domain.InterestAccrual
+
+	query := `SELECT id, account_id, kind, date, balance, rate, amount, reference FROM interest_accruals
+		WHERE account_id = $1 AND reference IS NULL AND date <= $2 ORDER BY date FOR UPDATE`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, accountId, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a domain.InterestAccrual
+		if err := rows.Scan(&a.Id, &a.AccountId, &a.Kind, &a.Date, &a.Balance, &a.Rate, &a.Amount, &a.Reference); err != nil {
+			return nil, err
+		}
+
+		accruals = append(accruals, a)
+	}
+
+	return accruals, rows.Err()
+}
+
+func (r *InterestRepository) MarkCapitalized(ctx context.Context, accountId int64, until time.Time, reference string) error {
+	query := "UPDATE interest_accruals SET reference = $1 WHERE account_id = $2 AND reference IS NULL AND date <= $3"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, reference, accountId, until)
+
+	return err
+}
@@ -0,0 +1,85 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type LimitRepository struct {
+	db *sql.DB
+}
+
+func NewLimitRepository(db *sql.DB) *LimitRepository {
+	return &LimitRepository{
+		db: db,
+	}
+}
+
+func (r *LimitRepository) Get(ctx context.Context, accountId int64, setBy string) (*domain.Limits, error) {
+	var limits domain.Limits
+
+	query := "SELECT per_transaction, daily, monthly FROM account_limits WHERE account_id = $1 AND set_by = $2"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, accountId, setBy).Scan(&limits.PerTransaction, &limits.Daily, &limits.Monthly)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &limits, nil
+}
+
+// Set replaces limits of the account set by admin or user.
+func (r *LimitRepository) Set(ctx context.Context, accountId int64, setBy string, limits domain.Limits) error {
+	query := `INSERT INTO account_limits (account_id, set_by, per_transaction, daily, monthly) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id, set_by) DO UPDATE
+		SET per_transaction = EXCLUDED.per_transaction, daily = EXCLUDED.daily, monthly = EXCLUDED.monthly, updated_at = NOW()`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, accountId, setBy, limits.PerTransaction, limits.Daily, limits.Monthly)
+
+	return err
+}
+
+func (r *LimitRepository) GetUser(ctx context.Context, userId int64, currency string) (*domain.Limits, error) {
+	var limits domain.Limits
+
+	query := "SELECT per_transaction, daily, monthly FROM user_limits WHERE user_id = $1 AND currency = $2"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userId, currency).Scan(&limits.PerTransaction, &limits.Daily, &limits.Monthly)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &limits, nil
+}
+
+// SetUser replaces limits of all user's accounts in the currency.
+func (r *LimitRepository) SetUser(ctx context.Context, userId int64, currency string, limits domain.Limits) error {
+	query := `INSERT INTO user_limits (user_id, currency, per_transaction, daily, monthly) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, currency) DO UPDATE
+		SET per_transaction = EXCLUDED.per_transaction, daily = EXCLUDED.daily, monthly = EXCLUDED.monthly, updated_at = NOW()`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userId, currency, limits.PerTransaction, limits.Daily, limits.Monthly)
+	if isForeignKeyViolation(err) {
+		return domain.ErrNotExist
+	}
+
+	return err
+}
+
+// LockUser locks the user till the end of transaction. Foreign keys to the user are still
+// checked meanwhile.
+func (r *LimitRepository) LockUser(ctx context.Context, userId int64) error {
+	var locked int64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE", userId).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotExist
+	}
+
+	return err
+}
@@ -0,0 +1,67 @@
+package psql
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// outboxChannel is notified with event id by trigger on insert into outbox.
+// Notifications are sent on commit, so the event is visible when it's received.
+const outboxChannel = "outbox"
+
+// OutboxListener receives ids of new outbox events committed by any app replica.
+type OutboxListener struct {
+	dsn string
+}
+
+func NewOutboxListener(dsn string) *OutboxListener {
+	return &OutboxListener{
+		dsn: dsn,
+	}
+}
+
+// Listen calls handle for every new event until ctx is done. Connection is reestablished
+// when it's lost; events committed meanwhile are not received.
+func (l *OutboxListener) Listen(ctx context.Context, handle func(ctx context.Context, id int64)) error {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logrus.WithContext(ctx).WithFields(logrus.Fields{
+				"context": "OutboxListener.Listen()",
+				"problem": "listener connection error",
+			}).Warn(err.Error())
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(outboxChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			// nil is sent after reconnect
+			if n == nil {
+				continue
+			}
+
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			handle(ctx, id)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
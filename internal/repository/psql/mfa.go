@@ -0,0 +1,118 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type MfaRepository struct {
+	db *sql.DB
+}
+
+func NewMfaRepository(db *sql.DB) *MfaRepository {
+	return &MfaRepository{
+		db: db,
+	}
+}
+
+func (r *MfaRepository) GetTotp(ctx context.Context, userId int64) (*domain.TotpSettings, error) {
+	var settings domain.TotpSettings
+
+	query := "SELECT COALESCE(totp_secret, ''), totp_enabled, totp_last_step, totp_failed_attempts, totp_locked_until FROM users WHERE id = $1"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userId).
+		Scan(&settings.Secret, &settings.Enabled, &settings.LastStep, &settings.FailedAttempts, &settings.LockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// SetTotpSecret stores secret of enrollment which is not confirmed yet.
+func (r *MfaRepository) SetTotpSecret(ctx context.Context, userId int64, secret string) error {
+	query := "UPDATE users SET totp_secret = $1, totp_last_step = 0 WHERE id = $2 AND NOT totp_enabled"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, secret, userId)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		return domain.ErrMfaAlreadyEnabled
+	}
+
+	return err
+}
+
+func (r *MfaRepository) EnableTotp(ctx context.Context, userId int64, step int64) error {
+	query := "UPDATE users SET totp_enabled = TRUE, totp_last_step = $1 WHERE id = $2 AND totp_secret IS NOT NULL"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, step, userId)
+
+	return err
+}
+
+// UseTotpStep remembers the step of accepted code. It reports false if the step or a later one was already used.
+func (r *MfaRepository) UseTotpStep(ctx context.Context, userId int64, step int64) (bool, error) {
+	query := "UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, step, userId)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return n > 0, err
+}
+
+func (r *MfaRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		query := "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, userId, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *MfaRepository) UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error) {
+	query := `UPDATE recovery_codes SET used_at = NOW() WHERE id = (
+		SELECT id FROM recovery_codes WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL LIMIT 1 FOR UPDATE)`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, userId, hash)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return n > 0, err
+}
+
+// FailAttempt counts invalid code. Reaching maxAttempts locks checks till lockUntil and
+// starts counting again.
+func (r *MfaRepository) FailAttempt(ctx context.Context, userId int64, maxAttempts int, lockUntil time.Time) error {
+	query := `UPDATE users SET
+			totp_failed_attempts = CASE WHEN totp_failed_attempts + 1 >= $1 THEN 0 ELSE totp_failed_attempts + 1 END,
+			totp_locked_until = CASE WHEN totp_failed_attempts + 1 >= $1 THEN $2 ELSE totp_locked_until END
+		WHERE id = $3`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, maxAttempts, lockUntil, userId)
+
+	return err
+}
+
+func (r *MfaRepository) ResetAttempts(ctx context.Context, userId int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE users SET totp_failed_attempts = 0, totp_locked_until = NULL WHERE id = $1", userId)
+
+	return err
+}
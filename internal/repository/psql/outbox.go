@@ -0,0 +1,142 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, e domain.Event) error {
+	query := "INSERT INTO outbox (aggregate_type, aggregate_id, type, payload) VALUES ($1, $2, $3, $4)"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, e.AggregateType, e.AggregateId, e.Type, []byte(e.Payload))
+
+	return err
+}
+
+func (r *OutboxRepository) GetById(ctx context.Context, id int64) (*domain.Event, error) {
+	query := "SELECT id, aggregate_type, aggregate_id, type, payload, created_at, attempts FROM outbox WHERE id = $1"
+	e, err := scanEvent(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+
+	return e, err
+}
+
+// ListAccountEvents returns events of user's accounts with id greater than lastEventId or
+// created since given time, in order of id and starting after afterId. Ids are taken on insert,
+// not on commit, so an event committed later may have a lower id than the last seen one.
+func (r *OutboxRepository) ListAccountEvents(ctx context.Context, userId, lastEventId int64, since time.Time, afterId int64, limit int) ([]domain.Event, error) {
+	var events []domain.Event
+
+	query := `SELECT id, aggregate_type, aggregate_id, type, payload, created_at, attempts FROM outbox
+		WHERE aggregate_type = $1 AND (payload->>'user_id')::BIGINT = $2 AND (id > $3 OR created_at >= $4) AND id > $5
+		ORDER BY id LIMIT $6`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, domain.AggregateAccount, userId, lastEventId, since, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, *e)
+	}
+
+	return events, rows.Err()
+}
+
+// ClaimPending returns events due for publishing at now and postpones them until leaseUntil,
+// so they are published outside of transaction and not picked again meanwhile. Only the oldest
+// unpublished event of every aggregate is returned, so events of an aggregate are published
+// one by one in order. Locked events are skipped, so concurrent relays claim different ones.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]domain.Event, error) {
+	var events []domain.Event
+
+	query := `WITH due AS (
+			SELECT id FROM outbox o
+			WHERE published_at IS NULL AND next_attempt_at <= $1
+				AND NOT EXISTS (SELECT 1 FROM outbox p WHERE p.aggregate_type = o.aggregate_type
+					AND p.aggregate_id = o.aggregate_id AND p.published_at IS NULL AND p.id < o.id)
+			ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox o SET next_attempt_at = $3 FROM due WHERE o.id = due.id
+		RETURNING o.id, o.aggregate_type, o.aggregate_id, o.type, o.payload, o.created_at, o.attempts`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, now, limit, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, *e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Id < events[j].Id })
+
+	return events, nil
+}
+
+// ScrubUser replaces payloads of the user's events with the user id, as events emitted
+// before ids only were used carry personal data.
+func (r *OutboxRepository) ScrubUser(ctx context.Context, userId int64) error {
+	query := "UPDATE outbox SET payload = jsonb_build_object('user_id', $1::BIGINT) WHERE aggregate_type = $2 AND aggregate_id = $3"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userId, domain.AggregateUser, strconv.FormatInt(userId, 10))
+
+	return err
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE outbox SET published_at = NOW(), attempts = attempts + 1 WHERE id = $1", id)
+
+	return err
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) error {
+	query := "UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2 WHERE id = $3"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, nextAttemptAt, lastError, id)
+
+	return err
+}
+
+func scanEvent(s scanner) (*domain.Event, error) {
+	var (
+		e       domain.Event
+		payload []byte
+	)
+	if err := s.Scan(&e.Id, &e.AggregateType, &e.AggregateId, &e.Type, &payload, &e.CreatedAt, &e.Attempts); err != nil {
+		return nil, err
+	}
+
+	e.Payload = payload
+
+	return &e, nil
+}
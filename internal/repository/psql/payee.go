@@ -0,0 +1,164 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+const payeeColumns = "id, user_id, name, iban, currency, created_at"
+
+type PayeeRepository struct {
+	db *sql.DB
+}
+
+func NewPayeeRepository(db *sql.DB) *PayeeRepository {
+	return &PayeeRepository{
+		db: db,
+	}
+}
+
+func (r *PayeeRepository) Create(ctx context.Context, p domain.Payee) (*domain.Payee, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	p.UserId = userId
+	query := "INSERT INTO payees (user_id, name, iban, currency) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userId, p.Name, p.Iban, p.Currency).Scan(&p.Id, &p.CreatedAt)
+	if isUniqueViolation(err, "payees_user_id_iban_key") {
+		return nil, domain.ErrPayeeAlreadyExists
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *PayeeRepository) List(ctx context.Context) ([]domain.Payee, error) {
+	payees := []domain.Payee{}
+
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := "SELECT " + payeeColumns + " FROM payees WHERE user_id = $1 ORDER BY name, id"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPayee(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		payees = append(payees, *p)
+	}
+
+	return payees, rows.Err()
+}
+
+func (r *PayeeRepository) GetById(ctx context.Context, id int64) (*domain.Payee, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := "SELECT " + payeeColumns + " FROM payees WHERE id = $1 AND user_id = $2"
+	p, err := scanPayee(conn(ctx, r.db).QueryRowContext(ctx, query, id, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+
+	return p, err
+}
+
+func (r *PayeeRepository) UpdateById(ctx context.Context, id int64, inp domain.PayeeUpdateInput) (*domain.Payee, error) {
+	var (
+		setValues []string
+		args      []interface{}
+		argIndex  = 1
+		addArg    = func(i interface{}, arg string) {
+			setValues = append(setValues, fmt.Sprintf("%s=$%d", arg, argIndex))
+			args = append(args, i)
+			argIndex++
+		}
+	)
+
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	if inp.Name != nil {
+		addArg(*inp.Name, "name")
+	}
+	if inp.Iban != nil {
+		addArg(*inp.Iban, "iban")
+	}
+	if inp.Currency != nil {
+		addArg(*inp.Currency, "currency")
+	}
+
+	if len(setValues) == 0 {
+		return r.GetById(ctx, id)
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+	query := fmt.Sprintf("UPDATE payees SET %s WHERE id=$%d AND user_id=$%d RETURNING %s", setQuery, argIndex, argIndex+1, payeeColumns)
+	args = append(args, id, userId)
+
+	p, err := scanPayee(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if isUniqueViolation(err, "payees_user_id_iban_key") {
+		return nil, domain.ErrPayeeAlreadyExists
+	}
+
+	if err != nil {
+		return nil, domain.ErrUpdateFailed
+	}
+
+	return p, nil
+}
+
+func (r *PayeeRepository) DeleteById(ctx context.Context, id int64) error {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return domain.ErrInvalidId
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM payees WHERE id=$1 AND user_id=$2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return domain.ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func scanPayee(s scanner) (*domain.Payee, error) {
+	var p domain.Payee
+	if err := s.Scan(&p.Id, &p.UserId, &p.Name, &p.Iban, &p.Currency, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,146 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+const paymentColumns = `id, account_id, amount, currency, creditor_name, creditor_iban, creditor_bic, remittance_info,
+	execution_date, status, status_reason, reference, COALESCE(message_id, ''), exported_at, created_at`
+
+type PaymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{
+		db: db,
+	}
+}
+
+func (r *PaymentRepository) Create(ctx context.Context, o domain.PaymentOrder) (*domain.PaymentOrder, error) {
+	query := `INSERT INTO payment_orders (account_id, amount, currency, creditor_name, creditor_iban, creditor_bic, remittance_info, execution_date, status, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, o.AccountId, o.Amount, o.Currency, o.CreditorName, o.CreditorIban,
+		o.CreditorBic, o.RemittanceInfo, o.ExecutionDate, o.Status, o.Reference).Scan(&o.Id, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+func (r *PaymentRepository) List(ctx context.Context, accountId int64) ([]domain.PaymentOrder, error) {
+	return r.list(ctx, "SELECT "+paymentColumns+" FROM payment_orders WHERE account_id = $1 ORDER BY id DESC", accountId)
+}
+
+func (r *PaymentRepository) ListQueuedBatches(ctx context.Context) ([]domain.PaymentBatch, error) {
+	var batches []domain.PaymentBatch
+
+	query := "SELECT DISTINCT currency, execution_date FROM payment_orders WHERE status = $1 ORDER BY execution_date, currency"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, domain.PaymentQueued)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b domain.PaymentBatch
+		if err := rows.Scan(&b.Currency, &b.ExecutionDate); err != nil {
+			return nil, err
+		}
+
+		batches = append(batches, b)
+	}
+
+	return batches, rows.Err()
+}
+
+// MarkExported assigns message id to all queued orders of the batch.
+func (r *PaymentRepository) MarkExported(ctx context.Context, batch domain.PaymentBatch, messageId string, exportedAt time.Time) (int64, error) {
+	query := `UPDATE payment_orders SET status = $1, message_id = $2, exported_at = $3
+		WHERE status = $4 AND currency = $5 AND execution_date = $6`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, domain.PaymentExported, messageId, exportedAt,
+		domain.PaymentQueued, batch.Currency, batch.ExecutionDate)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// ListUnwritten returns exported orders whose message file isn't written yet.
+func (r *PaymentRepository) ListUnwritten(ctx context.Context) ([]domain.PaymentOrder, error) {
+	query := "SELECT " + paymentColumns + " FROM payment_orders WHERE status = $1 AND file_written_at IS NULL ORDER BY message_id, id"
+	return r.list(ctx, query, domain.PaymentExported)
+}
+
+// MarkWritten records that file of the message is written. Orders are locked till the end
+// of the transaction, and zero is returned if another run has marked them already.
+func (r *PaymentRepository) MarkWritten(ctx context.Context, messageId string, writtenAt time.Time) (int64, error) {
+	query := "UPDATE payment_orders SET file_written_at = $1 WHERE message_id = $2 AND file_written_at IS NULL"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, writtenAt, messageId)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *PaymentRepository) ListByMessageId(ctx context.Context, messageId string) ([]domain.PaymentOrder, error) {
+	return r.list(ctx, "SELECT "+paymentColumns+" FROM payment_orders WHERE message_id = $1 ORDER BY id", messageId)
+}
+
+// UpdateStatus changes status of not rejected order. Rejected orders are final, so ErrNotExist
+// is returned for them as well as for unknown references.
+func (r *PaymentRepository) UpdateStatus(ctx context.Context, reference, status, reason string) (*domain.PaymentOrder, error) {
+	query := "UPDATE payment_orders SET status = $1, status_reason = $2 WHERE reference = $3 AND status <> $4 RETURNING " + paymentColumns
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, status, reason, reference, domain.PaymentRejected)
+
+	o, err := scanPaymentOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+
+	return o, err
+}
+
+func (r *PaymentRepository) list(ctx context.Context, query string, args ...interface{}) ([]domain.PaymentOrder, error) {
+	orders := []domain.PaymentOrder{}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		o, err := scanPaymentOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, *o)
+	}
+
+	return orders, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPaymentOrder(s scanner) (*domain.PaymentOrder, error) {
+	var o domain.PaymentOrder
+	err := s.Scan(&o.Id, &o.AccountId, &o.Amount, &o.Currency, &o.CreditorName, &o.CreditorIban, &o.CreditorBic, &o.RemittanceInfo,
+		&o.ExecutionDate, &o.Status, &o.StatusReason, &o.Reference, &o.MessageId, &o.ExportedAt, &o.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
@@ -7,9 +7,22 @@ import (
 )
 
 type Repositories struct {
-	accountRepository *AccountRepository
-	userRepository    *UserRepository
-	tokenRepository   *TokenRepository
+	accountRepository       *AccountRepository
+	userRepository          *UserRepository
+	tokenRepository         *TokenRepository
+	transactionRepository   *TransactionRepository
+	interestRepository      *InterestRepository
+	statementRepository     *StatementRepository
+	paymentRepository       *PaymentRepository
+	payeeRepository         *PayeeRepository
+	limitRepository         *LimitRepository
+	riskRepository          *RiskRepository
+	mfaRepository           *MfaRepository
+	passwordResetRepository *PasswordResetRepository
+	auditRepository         *AuditRepository
+	outboxRepository        *OutboxRepository
+	webhookRepository       *WebhookRepository
+	transactor              *Transactor
 }
 
 func (rs *Repositories) GetAccountRepository() domain.AccountRepository {
@@ -24,10 +37,75 @@ func (rs *Repositories) GetTokenRepository() domain.TokenRepository {
 	return rs.tokenRepository
 }
 
+func (rs *Repositories) GetTransactionRepository() domain.TransactionRepository {
+	return rs.transactionRepository
+}
+
+func (rs *Repositories) GetInterestRepository() domain.InterestRepository {
+	return rs.interestRepository
+}
+
+func (rs *Repositories) GetStatementRepository() domain.StatementRepository {
+	return rs.statementRepository
+}
+
+func (rs *Repositories) GetPaymentRepository() domain.PaymentRepository {
+	return rs.paymentRepository
+}
+
+func (rs *Repositories) GetPayeeRepository() domain.PayeeRepository {
+	return rs.payeeRepository
+}
+
+func (rs *Repositories) GetLimitRepository() domain.LimitRepository {
+	return rs.limitRepository
+}
+
+func (rs *Repositories) GetRiskRepository() domain.RiskRepository {
+	return rs.riskRepository
+}
+
+func (rs *Repositories) GetMfaRepository() domain.MfaRepository {
+	return rs.mfaRepository
+}
+
+func (rs *Repositories) GetPasswordResetRepository() domain.PasswordResetRepository {
+	return rs.passwordResetRepository
+}
+
+func (rs *Repositories) GetAuditRepository() domain.AuditRepository {
+	return rs.auditRepository
+}
+
+func (rs *Repositories) GetOutboxRepository() domain.OutboxRepository {
+	return rs.outboxRepository
+}
+
+func (rs *Repositories) GetWebhookRepository() domain.WebhookRepository {
+	return rs.webhookRepository
+}
+
+func (rs *Repositories) GetTransactor() domain.Transactor {
+	return rs.transactor
+}
+
 func NewRepositories(db *sql.DB) *Repositories {
 	return &Repositories{
-		accountRepository: NewAccountRepository(db),
-		userRepository:    NewUserRepository(db),
-		tokenRepository:   NewTokenRepository(db),
+		accountRepository:       NewAccountRepository(db),
+		userRepository:          NewUserRepository(db),
+		tokenRepository:         NewTokenRepository(db),
+		transactionRepository:   NewTransactionRepository(db),
+		interestRepository:      NewInterestRepository(db),
+		statementRepository:     NewStatementRepository(db),
+		paymentRepository:       NewPaymentRepository(db),
+		payeeRepository:         NewPayeeRepository(db),
+		limitRepository:         NewLimitRepository(db),
+		riskRepository:          NewRiskRepository(db),
+		mfaRepository:           NewMfaRepository(db),
+		passwordResetRepository: NewPasswordResetRepository(db),
+		auditRepository:         NewAuditRepository(db),
+		outboxRepository:        NewOutboxRepository(db),
+		webhookRepository:       NewWebhookRepository(db),
+		transactor:              NewTransactor(db),
 	}
 }
@@ -0,0 +1,103 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/lib/pq"
+)
+
+const riskReviewColumns = `id, user_id, account_id, to_account_id, operation, amount, currency, decision, rules,
+	status, reviewed_by, reviewed_at, created_at`
+
+type RiskRepository struct {
+	db *sql.DB
+}
+
+func NewRiskRepository(db *sql.DB) *RiskRepository {
+	return &RiskRepository{
+		db: db,
+	}
+}
+
+func (r *RiskRepository) Create(ctx context.Context, rv domain.RiskReview) (*domain.RiskReview, error) {
+	query := `INSERT INTO risk_reviews (user_id, account_id, to_account_id, operation, amount, currency, decision, rules, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, rv.UserId, rv.AccountId, rv.ToAccountId, rv.Operation, rv.Amount,
+		rv.Currency, rv.Decision, pq.Array(rv.Rules), rv.Status).Scan(&rv.Id, &rv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rv, nil
+}
+
+func (r *RiskRepository) List(ctx context.Context, status string) ([]domain.RiskReview, error) {
+	reviews := []domain.RiskReview{}
+
+	query := "SELECT " + riskReviewColumns + " FROM risk_reviews WHERE status = $1 ORDER BY id"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rv, err := scanRiskReview(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, *rv)
+	}
+
+	return reviews, rows.Err()
+}
+
+// Resolve sets admin's decision on pending review.
+func (r *RiskRepository) Resolve(ctx context.Context, id int64, status string, reviewer int64) (*domain.RiskReview, error) {
+	query := `UPDATE risk_reviews SET status = $1, reviewed_by = $2, reviewed_at = NOW()
+		WHERE id = $3 AND status = $4 RETURNING ` + riskReviewColumns
+	rv, err := scanRiskReview(conn(ctx, r.db).QueryRowContext(ctx, query, status, reviewer, id, domain.RiskReviewPending))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+
+	return rv, err
+}
+
+// UseApproved marks as used one review of the same operation approved since given moment.
+func (r *RiskRepository) UseApproved(ctx context.Context, op domain.RiskOperation, since time.Time) (bool, error) {
+	var toAccountId *int64
+	if op.ToAccount != nil {
+		toAccountId = &op.ToAccount.Id
+	}
+
+	query := `UPDATE risk_reviews SET status = $1 WHERE id = (
+		SELECT id FROM risk_reviews WHERE status = $2 AND reviewed_at >= $3 AND user_id = $4 AND account_id = $5
+		AND to_account_id IS NOT DISTINCT FROM $6 AND operation = $7 AND amount = $8
+		ORDER BY id LIMIT 1 FOR UPDATE)`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, domain.RiskReviewUsed, domain.RiskReviewApproved, since,
+		op.UserId, op.Account.Id, toAccountId, op.Operation, op.Amount)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return n > 0, err
+}
+
+func scanRiskReview(s scanner) (*domain.RiskReview, error) {
+	var rv domain.RiskReview
+	err := s.Scan(&rv.Id, &rv.UserId, &rv.AccountId, &rv.ToAccountId, &rv.Operation, &rv.Amount, &rv.Currency, &rv.Decision,
+		pq.Array(&rv.Rules), &rv.Status, &rv.ReviewedBy, &rv.ReviewedAt, &rv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rv, nil
+}
@@ -0,0 +1,103 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type StatementRepository struct {
+	db *sql.DB
+}
+
+func NewStatementRepository(db *sql.DB) *StatementRepository {
+	return &StatementRepository{
+		db: db,
+	}
+}
+
+// Create stores statement. Statement already generated for the same account and period is kept.
+func (r *StatementRepository) Create(ctx context.Context, s domain.Statement) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO statements (account_id, period, opening_balance, closing_balance, data, generated_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (account_id, period) DO NOTHING`
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, s.AccountId, s.Period, s.OpeningBalance, s.ClosingBalance, data, s.GeneratedAt)
+
+	return err
+}
+
+func (r *StatementRepository) Get(ctx context.Context, accountId int64, period string) (*domain.Statement, error) {
+	var (
+		data      []byte
+		statement domain.Statement
+	)
+
+	query := "SELECT data FROM statements WHERE account_id = $1 AND period = $2"
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, accountId, period).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotExist
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return nil, err
+	}
+
+	return &statement, nil
+}
+
+func (r *StatementRepository) List(ctx context.Context, accountId int64) ([]domain.StatementSummary, error) {
+	summaries := []domain.StatementSummary{}
+
+	query := "SELECT account_id, period, opening_balance, closing_balance, generated_at FROM statements WHERE account_id = $1 ORDER BY period DESC"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, accountId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s domain.StatementSummary
+		if err := rows.Scan(&s.AccountId, &s.Period, &s.OpeningBalance, &s.ClosingBalance, &s.GeneratedAt); err != nil {
+			return nil, err
+		}
+
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
+
+// ListAccountsWithout returns ids of accounts created before the end of the period which have
+// no statement for it.
+func (r *StatementRepository) ListAccountsWithout(ctx context.Context, period string, createdBefore time.Time) ([]int64, error) {
+	var ids []int64
+
+	query := `SELECT a.id FROM accounts a WHERE a.created_at < $2
+		AND NOT EXISTS (SELECT 1 FROM statements s WHERE s.account_id = a.id AND s.period = $1)`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, period, createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
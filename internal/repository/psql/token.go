@@ -3,6 +3,8 @@ package psql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/Viquad/crud-app/internal/domain"
 )
@@ -18,7 +20,7 @@ func NewTokenRepository(db *sql.DB) *TokenRepository {
 }
 
 func (r *TokenRepository) Create(ctx context.Context, token domain.RefreshSession) error {
-	_, err := r.db.Exec("INSERT INTO refresh_tokens (user_id, token, expires_at) values ($1, $2, $3)",
+	_, err := conn(ctx, r.db).ExecContext(ctx, "INSERT INTO refresh_tokens (user_id, token, expires_at) values ($1, $2, $3)",
 		token.UserID, token.Token, token.ExpiresAt)
 
 	return err
@@ -26,13 +28,74 @@ func (r *TokenRepository) Create(ctx context.Context, token domain.RefreshSessio
 
 func (r *TokenRepository) Get(ctx context.Context, token string) (*domain.RefreshSession, error) {
 	var t domain.RefreshSession
-	err := r.db.QueryRow("SELECT id, user_id, token, expires_at FROM refresh_tokens WHERE token=$1", token).
+	err := conn(ctx, r.db).QueryRowContext(ctx, "SELECT id, user_id, token, expires_at FROM refresh_tokens WHERE token=$1", token).
 		Scan(&t.ID, &t.UserID, &t.Token, &t.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrInvalidToken
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = r.db.Exec("DELETE FROM refresh_tokens WHERE user_id=$1", t.UserID)
+	_, err = conn(ctx, r.db).ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id=$1", t.UserID)
 
 	return &t, err
 }
+
+func (r *TokenRepository) DeleteByUser(ctx context.Context, userId int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM refresh_tokens WHERE user_id=$1", userId)
+
+	return err
+}
+
+func (r *TokenRepository) ListByUser(ctx context.Context, userId int64) ([]domain.RefreshSession, error) {
+	var sessions []domain.RefreshSession
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, "SELECT id, user_id, token, expires_at FROM refresh_tokens WHERE user_id=$1 ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t domain.RefreshSession
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Token, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, t)
+	}
+
+	return sessions, rows.Err()
+}
+
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		db: db,
+	}
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, userId int64, tokenHash string, expiresAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)",
+		userId, tokenHash, expiresAt)
+
+	return err
+}
+
+// Use marks unused and unexpired token as used and returns its user.
+func (r *PasswordResetRepository) Use(ctx context.Context, tokenHash string) (int64, error) {
+	var userId int64
+
+	query := `UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() RETURNING user_id`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tokenHash).Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domain.ErrInvalidToken
+	}
+
+	return userId, err
+}
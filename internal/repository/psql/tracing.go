@@ -0,0 +1,94 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Viquad/crud-app/internal/repository/psql")
+
+// tracedQuerier starts a span for each query, named after its SQL verb.
+type tracedQuerier struct {
+	q querier
+}
+
+func (t tracedQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	res, err := t.q.ExecContext(ctx, query, args...)
+	endQuerySpan(span, err)
+
+	return res, err
+}
+
+// QueryContext returns rows which end the span when closed, so it covers reading them.
+func (t tracedQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*tracedRows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		endQuerySpan(span, err)
+		return nil, err
+	}
+
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (t tracedQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	row := t.q.QueryRowContext(ctx, query, args...)
+
+	// no rows is a regular result of lookup, not a failure of the query
+	err := row.Err()
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	endQuerySpan(span, err)
+
+	return row
+}
+
+type tracedRows struct {
+	*sql.Rows
+	span trace.Span
+}
+
+// Close closes the rows and ends the span with the error of reading them, if any. It may be
+// called more than once.
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.span != nil {
+		endQuerySpan(r.span, r.Rows.Err())
+		r.span = nil
+	}
+
+	return err
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	return tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", query),
+		),
+	)
+}
+
+func endQuerySpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
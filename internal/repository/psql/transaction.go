@@ -0,0 +1,169 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/lib/pq"
+)
+
+type TransactionRepository struct {
+	db *sql.DB
+}
+
+func NewTransactionRepository(db *sql.DB) *TransactionRepository {
+	return &TransactionRepository{
+		db: db,
+	}
+}
+
+func (r *TransactionRepository) Create(ctx context.Context, t domain.Transaction) (*domain.Transaction, error) {
+	query := "INSERT INTO transactions (account_id, amount, currency, type, reference, description, external_ref, booked_at) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8) RETURNING id, created_at"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, t.AccountId, t.Amount, t.Currency, t.Type, t.Reference, t.Description, t.ExternalRef, t.BookedAt).
+		Scan(&t.Id, &t.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// List returns account transactions created in [from, to) ordered by creation.
+func (r *TransactionRepository) List(ctx context.Context, accountId int64, from, to time.Time) ([]domain.Transaction, error) {
+	var transactions []domain.Transaction
+
+	query := `SELECT id, account_id, amount, currency, type, reference, description, COALESCE(external_ref, ''), booked_at, created_at FROM transactions
+		WHERE account_id = $1 AND created_at >= $2 AND created_at < $3 ORDER BY created_at, id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, accountId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t domain.Transaction
+		if err := rows.Scan(&t.Id, &t.AccountId, &t.Amount, &t.Currency, &t.Type, &t.Reference, &t.Description, &t.ExternalRef, &t.BookedAt, &t.Date); err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// ListRecent returns up to limit latest transactions of every given account, newest first.
+func (r *TransactionRepository) ListRecent(ctx context.Context, accountIds []int64, limit int) ([]domain.Transaction, error) {
+	var transactions []domain.Transaction
+
+	query := `SELECT id, account_id, amount, currency, type, reference, description, external_ref, booked_at, created_at FROM (
+			SELECT id, account_id, amount, currency, type, reference, description, COALESCE(external_ref, '') AS external_ref, booked_at, created_at,
+				ROW_NUMBER() OVER (PARTITION BY account_id ORDER BY created_at DESC, id DESC) AS n
+			FROM transactions WHERE account_id = ANY($1)
+		) t WHERE n <= $2 ORDER BY account_id, created_at DESC, id DESC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(accountIds), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t domain.Transaction
+		if err := rows.Scan(&t.Id, &t.AccountId, &t.Amount, &t.Currency, &t.Type, &t.Reference, &t.Description, &t.ExternalRef, &t.BookedAt, &t.Date); err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// SumBefore returns sum of account transactions created before given moment, i.e. the balance
+// at that moment.
+func (r *TransactionRepository) SumBefore(ctx context.Context, accountId int64, before time.Time) (int64, error) {
+	var sum int64
+
+	query := "SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE account_id = $1 AND created_at < $2"
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, accountId, before).Scan(&sum)
+
+	return sum, err
+}
+
+// SumOutgoing returns money sent from the account by transfers, withdrawals and payments since
+// given moment as a positive number. Fees are not counted.
+func (r *TransactionRepository) SumOutgoing(ctx context.Context, accountId int64, since time.Time) (int64, error) {
+	var sum int64
+
+	query := `SELECT COALESCE(-SUM(amount), 0) FROM transactions
+		WHERE account_id = $1 AND created_at >= $2 AND amount < 0 AND type = ANY($3)`
+	types := []string{domain.TransactionTransfer, domain.TransactionWithdrawal, domain.TransactionPayment}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, accountId, since, pq.Array(types)).Scan(&sum)
+
+	return sum, err
+}
+
+// SumUserOutgoing returns money sent from all user's accounts in the currency since given
+// moment, counted the same way as SumOutgoing.
+func (r *TransactionRepository) SumUserOutgoing(ctx context.Context, userId int64, currency string, since time.Time) (int64, error) {
+	var sum int64
+
+	query := `SELECT COALESCE(-SUM(t.amount), 0) FROM transactions t JOIN accounts a ON a.id = t.account_id
+		WHERE a.user_id = $1 AND a.currency = $2 AND t.created_at >= $3 AND t.amount < 0 AND t.type = ANY($4)`
+	types := []string{domain.TransactionTransfer, domain.TransactionWithdrawal, domain.TransactionPayment}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userId, currency, since, pq.Array(types)).Scan(&sum)
+
+	return sum, err
+}
+
+// CountOutgoing returns number and sum of transfers, withdrawals and payments sent
+// from the account since given moment. Fees are not counted.
+func (r *TransactionRepository) CountOutgoing(ctx context.Context, accountId int64, since time.Time) (int, int64, error) {
+	var (
+		count int
+		sum   int64
+	)
+
+	query := `SELECT COUNT(DISTINCT reference), COALESCE(-SUM(amount), 0) FROM transactions
+		WHERE account_id = $1 AND created_at >= $2 AND amount < 0 AND type = ANY($3)`
+	types := []string{domain.TransactionTransfer, domain.TransactionWithdrawal, domain.TransactionPayment}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, accountId, since, pq.Array(types)).Scan(&count, &sum)
+
+	return count, sum, err
+}
+
+// HasTransferred reports whether money was ever transferred between the accounts.
+func (r *TransactionRepository) HasTransferred(ctx context.Context, fromId, toId int64) (bool, error) {
+	var exists bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM transactions f JOIN transactions t ON t.reference = f.reference
+		WHERE f.account_id = $1 AND f.amount < 0 AND t.account_id = $2 AND t.amount > 0 AND f.type = $3 AND t.type = $3)`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, fromId, toId, domain.TransactionTransfer).Scan(&exists)
+
+	return exists, err
+}
+
+// ListExternalRefs returns those of refs which are already posted to the account.
+func (r *TransactionRepository) ListExternalRefs(ctx context.Context, accountId int64, refs []string) ([]string, error) {
+	var existing []string
+
+	query := "SELECT external_ref FROM transactions WHERE account_id = $1 AND external_ref = ANY($2)"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, accountId, pq.Array(refs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, err
+		}
+
+		existing = append(existing, ref)
+	}
+
+	return existing, rows.Err()
+}
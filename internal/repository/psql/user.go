@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/Viquad/crud-app/internal/domain"
 )
 
+const userColumns = "id, first_name, last_name, email, phone, password, tier, role, registered_at, password_changed_at, totp_enabled, email_verified_at"
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -18,24 +22,144 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	}
 }
 
-func (r *UserRepository) Create(ctx context.Context, input domain.SignUpInput) error {
+func (r *UserRepository) Create(ctx context.Context, input domain.SignUpInput) (int64, error) {
 	query := "SELECT email FROM users WHERE email=$1"
-	err := r.db.QueryRowContext(ctx, query, input.Email).Scan()
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, input.Email).Scan()
 	if !errors.Is(err, sql.ErrNoRows) {
-		return domain.ErrUserAlreadyExists
+		return 0, domain.ErrUserAlreadyExists
 	}
 
-	query = "INSERT INTO users (first_name, last_name, email, password) VALUES ($1, $2, $3, $4)"
-	_, err = r.db.ExecContext(ctx, query, input.FirstName, input.LastName, input.Email, input.Password)
+	var id int64
+	query = "INSERT INTO users (first_name, last_name, email, password) VALUES ($1, $2, $3, $4) RETURNING id"
+	err = conn(ctx, r.db).QueryRowContext(ctx, query, input.FirstName, input.LastName, input.Email, input.Password).Scan(&id)
 
-	return err
+	return id, err
 }
 
 func (r *UserRepository) GetByCredentials(ctx context.Context, input domain.SignInInput) (*domain.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email=$1 AND password=$2"
+	return r.get(ctx, query, input.Email, input.Password)
+}
+
+func (r *UserRepository) GetById(ctx context.Context, id int64) (*domain.User, error) {
+	return r.get(ctx, "SELECT "+userColumns+" FROM users WHERE id=$1", id)
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.get(ctx, "SELECT "+userColumns+" FROM users WHERE email=$1", email)
+}
+
+// SetEmailVerified marks email of the user as verified if it's still the same.
+func (r *UserRepository) SetEmailVerified(ctx context.Context, id int64, email string) error {
+	query := "UPDATE users SET email_verified_at = NOW() WHERE id=$1 AND email=$2 AND email_verified_at IS NULL"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, email)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		return domain.ErrUpdateFailed
+	}
+
+	return err
+}
+
+// UpdatePassword sets password hash and remembers when it was changed.
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	query := "UPDATE users SET password = $1, password_changed_at = NOW() WHERE id=$2"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, password, id)
+
+	return err
+}
+
+func (r *UserRepository) UpdateById(ctx context.Context, id int64, input domain.UserUpdateInput) (*domain.User, error) {
+	var (
+		setValues []string
+		args      []interface{}
+		argIndex  = 1
+		addArg    = func(i interface{}, arg string) {
+			setValues = append(setValues, fmt.Sprintf("%s=$%d", arg, argIndex))
+			args = append(args, i)
+			argIndex++
+		}
+	)
+
+	if input.FirstName != nil {
+		addArg(*input.FirstName, "first_name")
+	}
+	if input.LastName != nil {
+		addArg(*input.LastName, "last_name")
+	}
+	if input.Phone != nil {
+		addArg(*input.Phone, "phone")
+	}
+
+	if len(setValues) == 0 {
+		return r.GetById(ctx, id)
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+	query := fmt.Sprintf("UPDATE users SET %s WHERE id=$%d RETURNING %s", setQuery, argIndex, userColumns)
+	args = append(args, id)
+
+	user, err := r.get(ctx, query, args...)
+	if err != nil {
+		return nil, domain.ErrUpdateFailed
+	}
+
+	return user, nil
+}
+
+// UpdateEmail sets new email, which has to be verified again.
+func (r *UserRepository) UpdateEmail(ctx context.Context, id int64, email string) error {
+	query := "UPDATE users SET email = $1, email_verified_at = NULL WHERE id=$2"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, email, id)
+	if isUniqueViolation(err, "users_email_key") {
+		return domain.ErrUserAlreadyExists
+	}
+
+	return err
+}
+
+// Erase pseudonymizes personal fields of the user and deletes data kept only for the user.
+// Ledger rows are kept, they refer to the user by id only.
+func (r *UserRepository) Erase(ctx context.Context, id int64) error {
+	query := `UPDATE users SET first_name = 'Deleted', last_name = 'User', email = 'erased-' || id || '@invalid',
+		phone = NULL, password = '', totp_secret = NULL, totp_enabled = FALSE, email_verified_at = NULL, erased_at = NOW()
+		WHERE id = $1 AND erased_at IS NULL`
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrUpdateFailed
+	}
+
+	for _, query := range []string{
+		"DELETE FROM recovery_codes WHERE user_id=$1",
+		"DELETE FROM password_reset_tokens WHERE user_id=$1",
+		"DELETE FROM payees WHERE user_id=$1",
+		"DELETE FROM webhooks WHERE user_id=$1",
+	} {
+		if _, err := conn(ctx, r.db).ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *UserRepository) get(ctx context.Context, query string, args ...interface{}) (*domain.User, error) {
 	var user domain.User
-	query := "SELECT id, first_name, last_name, email, password, registered_at FROM users WHERE email=$1 AND password=$2"
-	err := r.db.QueryRowContext(ctx, query, input.Email, input.Password).
-		Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.RegisteredAt)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&user.Id, &user.FirstName, &user.LastName, &user.Email, &user.Phone, &user.Password, &user.Tier, &user.Role,
+			&user.RegisteredAt, &user.PasswordChangedAt, &user.TotpEnabled, &user.EmailVerifiedAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, domain.ErrUserNotFound
@@ -43,3 +167,15 @@ func (r *UserRepository) GetByCredentials(ctx context.Context, input domain.Sign
 
 	return &user, err
 }
+
+// IsActive reports whether the user exists and is not erased.
+func (r *UserRepository) IsActive(ctx context.Context, id int64) (bool, error) {
+	var active bool
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, "SELECT erased_at IS NULL FROM users WHERE id = $1", id).Scan(&active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return active, err
+}
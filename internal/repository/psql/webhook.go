@@ -0,0 +1,350 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/lib/pq"
+)
+
+const (
+	webhookColumns  = "id, user_id, url, event_types, enabled, failures, disabled_at, created_at"
+	deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.response_status,
+	d.last_error, d.next_attempt_at, d.created_at, d.delivered_at`
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, w domain.Webhook) (*domain.Webhook, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := "INSERT INTO webhooks (user_id, url, secret, event_types) VALUES ($1, $2, $3, $4) RETURNING " + webhookColumns
+	return scanWebhook(conn(ctx, r.db).QueryRowContext(ctx, query, userId, w.URL, w.Secret, pq.Array(w.EventTypes)))
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]domain.Webhook, error) {
+	webhooks := []domain.Webhook{}
+
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, *w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *WebhookRepository) GetById(ctx context.Context, id int64) (*domain.Webhook, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE id = $1 AND user_id = $2"
+	w, err := scanWebhook(conn(ctx, r.db).QueryRowContext(ctx, query, id, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+
+	return w, err
+}
+
+func (r *WebhookRepository) UpdateById(ctx context.Context, id int64, inp domain.WebhookUpdateInput) (*domain.Webhook, error) {
+	var (
+		setValues []string
+		args      []interface{}
+		argIndex  = 1
+		addArg    = func(i interface{}, arg string) {
+			setValues = append(setValues, fmt.Sprintf("%s=$%d", arg, argIndex))
+			args = append(args, i)
+			argIndex++
+		}
+	)
+
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	if inp.URL != nil {
+		addArg(*inp.URL, "url")
+	}
+	if inp.EventTypes != nil {
+		addArg(pq.Array(inp.EventTypes), "event_types")
+	}
+	if inp.Enabled != nil {
+		addArg(*inp.Enabled, "enabled")
+		if *inp.Enabled {
+			setValues = append(setValues, "failures=0", "disabled_at=NULL")
+		} else {
+			setValues = append(setValues, "disabled_at=COALESCE(disabled_at, NOW())")
+		}
+	}
+
+	if len(setValues) == 0 {
+		return r.GetById(ctx, id)
+	}
+
+	setQuery := strings.Join(setValues, ", ")
+	query := fmt.Sprintf("UPDATE webhooks SET %s WHERE id=$%d AND user_id=$%d RETURNING %s", setQuery, argIndex, argIndex+1, webhookColumns)
+	args = append(args, id, userId)
+
+	w, err := scanWebhook(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, domain.ErrUpdateFailed
+	}
+
+	return w, nil
+}
+
+func (r *WebhookRepository) DeleteById(ctx context.Context, id int64) error {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return domain.ErrInvalidId
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM webhooks WHERE id=$1 AND user_id=$2", id, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return domain.ErrDeleteFailed
+	}
+
+	return nil
+}
+
+// ListSubscribed returns enabled webhooks of the user subscribed to the event type.
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, userId int64, eventType string) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE user_id = $1 AND enabled AND $2 = ANY(event_types) ORDER BY id"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userId, eventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, *w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// AddFailure counts failed attempt of the webhook and disables it when failures in a row
+// reach disableAfter. It reports whether the webhook has been disabled by this call.
+func (r *WebhookRepository) AddFailure(ctx context.Context, id int64, disableAfter int) (bool, error) {
+	var disabled bool
+
+	query := `UPDATE webhooks SET failures = failures + 1,
+			enabled = enabled AND failures + 1 < $1,
+			disabled_at = CASE WHEN enabled AND failures + 1 >= $1 THEN NOW() ELSE disabled_at END
+		WHERE id = $2 RETURNING disabled_at IS NOT NULL AND failures = $1`
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, disableAfter, id).Scan(&disabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return disabled, err
+}
+
+func (r *WebhookRepository) ResetFailures(ctx context.Context, id int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "UPDATE webhooks SET failures = 0 WHERE id = $1 AND failures > 0", id)
+
+	return err
+}
+
+// CreateDelivery queues the event for the webhook. Event already queued for it is ignored,
+// so events handled again don't make duplicates.
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d domain.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload) VALUES ($1, $2, $3, $4)
+		ON CONFLICT ON CONSTRAINT webhook_deliveries_event_key DO NOTHING`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, d.WebhookId, d.EventId, d.EventType, []byte(d.Payload))
+
+	return err
+}
+
+// ListDeliveries returns the latest deliveries of user's webhook.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, id int64, limit int) ([]domain.WebhookDelivery, error) {
+	deliveries := []domain.WebhookDelivery{}
+
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := "SELECT " + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = $1 AND w.user_id = $2 ORDER BY d.id DESC LIMIT $3`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id, userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, *d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ResetDelivery queues delivery of user's webhook again with attempts counted from zero.
+func (r *WebhookRepository) ResetDelivery(ctx context.Context, id, deliveryId int64) (*domain.WebhookDelivery, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	query := `UPDATE webhook_deliveries d SET status = $1, attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+		FROM webhooks w WHERE w.id = d.webhook_id AND d.id = $2 AND d.webhook_id = $3 AND w.user_id = $4
+		RETURNING ` + deliveryColumns
+	d, err := scanDelivery(conn(ctx, r.db).QueryRowContext(ctx, query, domain.DeliveryPending, deliveryId, id, userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotExist
+	}
+
+	return d, err
+}
+
+// ClaimDue returns deliveries of enabled webhooks due at now and postpones them until leaseUntil,
+// so they are sent outside of transaction and not picked again meanwhile. Deliveries left
+// unrecorded, e.g. after a crash, become due again when the lease ends. Locked deliveries
+// are skipped, so concurrent workers claim different ones.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]domain.DueDelivery, error) {
+	var deliveries []domain.DueDelivery
+
+	query := `WITH due AS (
+			SELECT d.id FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = $1 AND d.next_attempt_at <= $2 AND w.enabled
+			ORDER BY d.id LIMIT $3 FOR UPDATE OF d SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = $4 FROM due WHERE d.id = due.id RETURNING d.*
+		)
+		SELECT ` + deliveryColumns + `, w.url, w.secret FROM claimed d JOIN webhooks w ON w.id = d.webhook_id ORDER BY d.id`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, domain.DeliveryPending, now, limit, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d domain.DueDelivery
+		if err := scanDeliveryInto(rows, &d.WebhookDelivery, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, deliveryId int64, responseStatus int) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, response_status = $2, last_error = '',
+		delivered_at = NOW() WHERE id = $3`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, domain.DeliveryDelivered, responseStatus, deliveryId)
+
+	return err
+}
+
+// MarkAttemptFailed records failed attempt. Delivery is retried at nextAttemptAt, or fails
+// for good if it is nil.
+func (r *WebhookRepository) MarkAttemptFailed(ctx context.Context, deliveryId int64, responseStatus *int, lastError string, nextAttemptAt *time.Time) error {
+	status, next := domain.DeliveryPending, sql.NullTime{}
+	if nextAttemptAt == nil {
+		status = domain.DeliveryFailed
+	} else {
+		next = sql.NullTime{Time: *nextAttemptAt, Valid: true}
+	}
+
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, response_status = $2, last_error = $3,
+		next_attempt_at = COALESCE($4, next_attempt_at) WHERE id = $5`
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, status, responseStatus, lastError, next, deliveryId)
+
+	return err
+}
+
+func scanWebhook(s scanner) (*domain.Webhook, error) {
+	var w domain.Webhook
+	if err := s.Scan(&w.Id, &w.UserId, &w.URL, pq.Array(&w.EventTypes), &w.Enabled, &w.Failures, &w.DisabledAt, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func scanDelivery(s scanner) (*domain.WebhookDelivery, error) {
+	var d domain.WebhookDelivery
+	if err := scanDeliveryInto(s, &d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func scanDeliveryInto(s scanner, d *domain.WebhookDelivery, extra ...interface{}) error {
+	var (
+		payload       []byte
+		nextAttemptAt time.Time
+	)
+
+	dest := append([]interface{}{&d.Id, &d.WebhookId, &d.EventId, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &nextAttemptAt, &d.CreatedAt, &d.DeliveredAt}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return err
+	}
+
+	d.Payload = payload
+	if d.Status == domain.DeliveryPending {
+		d.NextAttemptAt = &nextAttemptAt
+	}
+
+	return nil
+}
@@ -2,28 +2,71 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/iban"
 	cache "github.com/Viquad/simple-cache"
 	"github.com/sirupsen/logrus"
 )
 
 const cache_key_template = "user[%d]/account[%d]"
 const listId int64 = 0
+const ibanAttempts = 5
+const recentTransactionsLimit = 100
+
+// IbanSettings identify the bank in generated IBANs.
+type IbanSettings struct {
+	CountryCode string
+	BankCode    string
+}
 
 type AccountService struct {
-	repo  domain.AccountRepository
-	cache cache.Cache
-	ttl   time.Duration
+	repo struct {
+		account     domain.AccountRepository
+		user        domain.UserRepository
+		transaction domain.TransactionRepository
+		payee       domain.PayeeRepository
+		audit       domain.AuditRepository
+		outbox      domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	cache      *tracedCache
+	ledger     *ledger
+	limits     LimitChecker
+	metrics    MetricsRecorder
+	mfa        MfaSettings
+	iban       IbanSettings
+	ttl        time.Duration
 }
 
-func NewAccountService(repo Repositories, cache cache.Cache, ttl time.Duration) *AccountService {
+func NewAccountService(repos Repositories, cache cache.Cache, fees FeeCalculator, limits LimitChecker, risk RiskScreener, metrics MetricsRecorder, mfa MfaSettings, iban IbanSettings, ttl time.Duration) *AccountService {
 	return &AccountService{
-		repo:  repo.GetAccountRepository(),
-		cache: cache,
-		ttl:   ttl,
+		repo: struct {
+			account     domain.AccountRepository
+			user        domain.UserRepository
+			transaction domain.TransactionRepository
+			payee       domain.PayeeRepository
+			audit       domain.AuditRepository
+			outbox      domain.OutboxRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			user:        repos.GetUserRepository(),
+			transaction: repos.GetTransactionRepository(),
+			payee:       repos.GetPayeeRepository(),
+			audit:       repos.GetAuditRepository(),
+			outbox:      repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		cache:      newTracedCache(cache),
+		ledger:     newLedger(repos, cache, fees, limits, risk),
+		limits:     limits,
+		metrics:    metrics,
+		mfa:        mfa,
+		iban:       iban,
+		ttl:        ttl,
 	}
 }
 
@@ -33,10 +76,42 @@ func (s *AccountService) Create(ctx context.Context, input domain.AccountCreateI
 		return nil, domain.ErrInvalidId
 	}
 
-	account, err := s.repo.Create(ctx, input)
+	var (
+		account *domain.Account
+		err     error
+	)
+
+	// random account numbers may collide, so retry with a new one
+	for attempt := 0; attempt < ibanAttempts; attempt++ {
+		var number string
+		if number, err = iban.Generate(s.iban.CountryCode, s.iban.BankCode); err != nil {
+			return nil, err
+		}
+
+		err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			if account, err = s.repo.account.Create(ctx, input, number); err != nil {
+				return err
+			}
+
+			if err := s.adjust(ctx, account, input.Balance, "opening balance"); err != nil {
+				return err
+			}
+
+			if err := emit(ctx, s.repo.outbox, domain.AggregateAccount, account.Id, domain.EventAccountCreated, account); err != nil {
+				return err
+			}
+
+			return audit(ctx, s.repo.audit, domain.AuditAccountCreate, domain.AuditEntityAccount, account.Id, nil, account)
+		})
+		if !errors.Is(err, domain.ErrIbanAlreadyExists) {
+			break
+		}
+	}
+
 	if err == nil {
-		s.cache.Set(cacheKey(userId, account.Id), account, s.ttl)
-		s.cache.Delete(cacheKey(userId, listId))
+		s.cache.Set(ctx, cacheKey(userId, account.Id), account, s.ttl)
+		s.cache.Delete(ctx, cacheKey(userId, listId))
 	}
 
 	return account, err
@@ -49,23 +124,24 @@ func (s *AccountService) GetById(ctx context.Context, id int64) (account *domain
 	}
 
 	var cached bool
-	i, err := s.cache.Get(cacheKey(userId, id))
+	i, err := s.cache.Get(ctx, cacheKey(userId, id))
 	if err == nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"context": "AccountService.GetById()",
 		}).Debug("Get account from cache")
 		account, cached = i.(*domain.Account)
 	}
+	s.metrics.CacheLookup("account", cached)
 
 	if !cached {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"context": "AccountService.GetById()",
 		}).Debug("Get account from repo")
-		account, err = s.repo.GetById(ctx, id)
+		account, err = s.repo.account.GetById(ctx, id)
 	}
 
 	if err == nil {
-		s.cache.Set(cacheKey(userId, id), account, s.ttl)
+		s.cache.Set(ctx, cacheKey(userId, id), account, s.ttl)
 	}
 
 	return account, err
@@ -78,41 +154,122 @@ func (s *AccountService) List(ctx context.Context) (accounts []domain.Account, e
 	}
 
 	var cached bool
-	i, err := s.cache.Get(cacheKey(userId, listId))
+	i, err := s.cache.Get(ctx, cacheKey(userId, listId))
 	if err == nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"context": "AccountService.List()",
 		}).Debug("Get accounts from cache")
 		accounts, cached = i.([]domain.Account)
 	}
+	s.metrics.CacheLookup("accounts", cached)
 
 	if !cached {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
 			"context": "AccountService.List()",
 		}).Debug("Get accounts from repo")
-		accounts, err = s.repo.List(ctx)
+		accounts, err = s.repo.account.List(ctx)
 	}
 
 	if err == nil {
-		s.cache.Set(cacheKey(userId, listId), accounts, s.ttl)
+		s.cache.Set(ctx, cacheKey(userId, listId), accounts, s.ttl)
 	}
 
 	return accounts, err
 }
 
+// ListByIds returns user's accounts with given ids in one query. Unknown ids are skipped.
+func (s *AccountService) ListByIds(ctx context.Context, ids []int64) ([]domain.Account, error) {
+	return s.repo.account.ListByIds(ctx, ids)
+}
+
+// ListRecentTransactions returns up to limit latest transactions of every given user's account,
+// newest first. Limit is capped at recentTransactionsLimit.
+func (s *AccountService) ListRecentTransactions(ctx context.Context, ids []int64, limit int) ([]domain.Transaction, error) {
+	if limit <= 0 || limit > recentTransactionsLimit {
+		limit = recentTransactionsLimit
+	}
+
+	accounts, err := s.repo.account.ListByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+
+	owned := make([]int64, 0, len(accounts))
+	for _, a := range accounts {
+		owned = append(owned, a.Id)
+	}
+
+	return s.repo.transaction.ListRecent(ctx, owned, limit)
+}
+
 func (s *AccountService) UpdateById(ctx context.Context, id int64, inp domain.AccountUpdateInput) (*domain.Account, error) {
 	userId, ok := ctx.Value(domain.UserIdKey).(int64)
 	if !ok {
 		return nil, domain.ErrInvalidId
 	}
 
-	account, err := s.repo.UpdateById(ctx, id, inp)
-	if err == nil {
-		s.cache.Set(cacheKey(userId, account.Id), account, s.ttl)
-		s.cache.Delete(cacheKey(userId, listId))
+	var account *domain.Account
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.account.GetById(ctx, id)
+		if errors.Is(err, domain.ErrNotExist) {
+			return domain.ErrUpdateFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		if account, err = s.repo.account.UpdateById(ctx, id, inp); err != nil {
+			return err
+		}
+
+		if err := s.adjust(ctx, account, account.Balance-before.Balance, "balance adjustment"); err != nil {
+			return err
+		}
+
+		if err := emit(ctx, s.repo.outbox, domain.AggregateAccount, id, domain.EventAccountUpdated, account); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditAccountUpdate, domain.AuditEntityAccount, id, before, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(ctx, cacheKey(userId, account.Id), account, s.ttl)
+	s.cache.Delete(ctx, cacheKey(userId, listId))
+
+	return account, nil
+}
+
+// adjust posts the balance set directly to the ledger, so balances can be restored from it.
+func (s *AccountService) adjust(ctx context.Context, account *domain.Account, amount int64, description string) error {
+	if amount == 0 {
+		return nil
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return err
+	}
+
+	t, err := s.repo.transaction.Create(ctx, domain.Transaction{
+		AccountId:   account.Id,
+		Amount:      amount,
+		Currency:    account.Currency,
+		Type:        domain.TransactionAdjustment,
+		Reference:   reference,
+		Description: description,
+	})
+	if err != nil {
+		return err
 	}
 
-	return s.repo.UpdateById(ctx, id, inp)
+	return emitBalanceChanged(ctx, s.repo.outbox, account, t)
 }
 
 func (s *AccountService) DeleteById(ctx context.Context, id int64) error {
@@ -121,14 +278,88 @@ func (s *AccountService) DeleteById(ctx context.Context, id int64) error {
 		return domain.ErrInvalidId
 	}
 
-	err := s.repo.DeleteById(ctx, id)
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.account.GetById(ctx, id)
+		if errors.Is(err, domain.ErrNotExist) {
+			return domain.ErrDeleteFailed
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.account.DeleteById(ctx, id); err != nil {
+			return err
+		}
+
+		if err := emit(ctx, s.repo.outbox, domain.AggregateAccount, id, domain.EventAccountClosed, before); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditAccountDelete, domain.AuditEntityAccount, id, before, nil)
+	})
 	if err == nil {
-		s.cache.Delete(cacheKey(userId, id))
+		s.cache.Delete(ctx, cacheKey(userId, id))
+		s.cache.Delete(ctx, cacheKey(userId, listId))
 	}
 
 	return err
 }
 
+// LookupByIban resolves IBAN to an account of any user showing only what is needed to transfer to it.
+func (s *AccountService) LookupByIban(ctx context.Context, number string) (*domain.AccountLookup, error) {
+	account, err := s.getByIban(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AccountLookup{Iban: account.Iban, Currency: account.Currency}, nil
+}
+
+// AssignIbans generates IBANs for accounts created before IBANs were introduced.
+func (s *AccountService) AssignIbans(ctx context.Context) (int, error) {
+	ids, err := s.repo.account.ListWithoutIban(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var assigned int
+	for _, id := range ids {
+		for attempt := 0; attempt < ibanAttempts; attempt++ {
+			var number string
+			if number, err = iban.Generate(s.iban.CountryCode, s.iban.BankCode); err != nil {
+				return assigned, err
+			}
+
+			err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				if err := s.repo.account.SetIban(ctx, id, number); err != nil {
+					return err
+				}
+
+				return audit(ctx, s.repo.audit, domain.AuditAccountSetIban, domain.AuditEntityAccount, id, nil, map[string]string{"iban": number})
+			})
+			if !errors.Is(err, domain.ErrIbanAlreadyExists) {
+				break
+			}
+		}
+
+		if err != nil {
+			return assigned, err
+		}
+		assigned++
+	}
+
+	return assigned, nil
+}
+
+func (s *AccountService) getByIban(ctx context.Context, number string) (*domain.Account, error) {
+	number = iban.Normalize(number)
+	if err := iban.Validate(number); err != nil {
+		return nil, domain.ErrInvalidIban
+	}
+
+	return s.repo.account.GetByIban(ctx, number)
+}
+
 func cacheKey(user_id, id int64) string {
 	return fmt.Sprintf(cache_key_template, user_id, id)
 }
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+// personalEntities are audited with names of changed fields instead of snapshots. Their values
+// are personal data which must be erasable, while audit events are append-only.
+var personalEntities = map[string]bool{
+	domain.AuditEntityUser:  true,
+	domain.AuditEntityPayee: true,
+}
+
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+	auditVerifyBatch  = 1000
+)
+
+type AuditService struct {
+	repo struct {
+		audit domain.AuditRepository
+	}
+}
+
+func NewAuditService(repos Repositories) *AuditService {
+	return &AuditService{
+		repo: struct {
+			audit domain.AuditRepository
+		}{
+			audit: repos.GetAuditRepository(),
+		},
+	}
+}
+
+func (s *AuditService) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultLimit
+	}
+	if filter.Limit > auditMaxLimit {
+		filter.Limit = auditMaxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.repo.audit.List(ctx, filter)
+}
+
+// Verify walks the whole hash chain from the first event. Events written before
+// the chain was introduced have no hash and are skipped.
+func (s *AuditService) Verify(ctx context.Context) (*domain.AuditVerification, error) {
+	var (
+		result  = domain.AuditVerification{Valid: true}
+		lastId  int64
+		prev    string
+		chained bool
+	)
+
+	for {
+		events, err := s.repo.audit.ListAfter(ctx, lastId, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			lastId = e.Id
+			result.Checked++
+
+			if !chained && e.Hash == "" && e.PrevHash == "" {
+				continue
+			}
+			chained = true
+
+			if e.PrevHash != prev || e.ChainHash() != e.Hash {
+				result.Valid = false
+				result.BrokenId = &e.Id
+				return &result, nil
+			}
+
+			prev = e.Hash
+		}
+
+		if len(events) < auditVerifyBatch {
+			return &result, nil
+		}
+	}
+}
+
+// audit records action on the entity made by signed in user or by the system if there is
+// no user. Call it within the transaction of the change, so it's recorded only if committed.
+func audit(ctx context.Context, events domain.AuditRepository, action, entityType string, entityId int64, before, after interface{}) error {
+	e := domain.AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   strconv.FormatInt(entityId, 10),
+	}
+
+	if actorId, ok := ctx.Value(domain.UserIdKey).(int64); ok {
+		e.ActorId = &actorId
+	}
+
+	if ip, ok := ctx.Value(domain.ClientIpKey).(string); ok {
+		e.Ip = ip
+	}
+
+	if requestId, ok := ctx.Value(domain.RequestIdKey).(string); ok {
+		e.RequestId = requestId
+	}
+
+	var err error
+	if personalEntities[entityType] {
+		if after, err = changedFields(before, after); err != nil {
+			return err
+		}
+		before = nil
+	}
+
+	if before != nil {
+		if e.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+
+	if after != nil {
+		if e.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+
+	return events.Create(ctx, e)
+}
+
+// changedFields returns sorted names of fields which differ between JSON snapshots, nil if
+// there are no snapshots.
+func changedFields(before, after interface{}) (interface{}, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+
+	var snapshots [2]map[string]json.RawMessage
+	for i, v := range []interface{}{before, after} {
+		if v == nil {
+			continue
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, &snapshots[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	fields := []string{}
+	for name, value := range snapshots[1] {
+		if old, ok := snapshots[0][name]; !ok || !bytes.Equal(old, value) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range snapshots[0] {
+		if _, ok := snapshots[1][name]; !ok {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+
+	return fields, nil
+}
+
+// withActor is used to audit actions of users who are not signed in, e.g. sign up or password reset.
+func withActor(ctx context.Context, userId int64) context.Context {
+	return context.WithValue(ctx, domain.UserIdKey, userId)
+}
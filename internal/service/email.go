@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/mailer"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+const verificationPurpose = "email_verification"
+
+// passwordResetTimeout bounds sending of a single password reset.
+const passwordResetTimeout = time.Minute
+
+type Mailer interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+// EmailSettings configure links sent to users. BaseURL is the address of the API.
+// Up to ResetQueue password resets wait to be sent, others are dropped. Within ResetWindow
+// up to ResetEmailLimit resets are sent to an address and ResetIpLimit are requested
+// from a client IP.
+type EmailSettings struct {
+	BaseURL         string
+	VerificationTTL time.Duration
+	ResetTTL        time.Duration
+	ResetQueue      int
+	ResetWindow     time.Duration
+	ResetEmailLimit int
+	ResetIpLimit    int
+}
+
+// passwordReset is a queued request of ForgotPassword. Its context has values of the request,
+// e.g. for logging, but isn't canceled with it.
+type passwordReset struct {
+	ctx   context.Context
+	email string
+}
+
+// VerifyEmail marks email from the signed verification link as verified.
+// Link for an address the user doesn't have anymore is rejected.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.parseClaims(token)
+	if err != nil {
+		return err
+	}
+
+	if claims.Purpose != verificationPurpose {
+		return domain.ErrInvalidToken
+	}
+
+	userId, err := strconv.ParseInt(claims.ID, 10, 64)
+	if err != nil {
+		return domain.ErrInvalidId
+	}
+
+	user, err := s.repo.user.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.Email != claims.Subject {
+		return domain.ErrInvalidToken
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return domain.ErrEmailVerified
+	}
+
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.user.SetEmailVerified(ctx, userId, claims.Subject); err != nil {
+			return err
+		}
+
+		after := map[string]string{"email": claims.Subject}
+		return audit(withActor(ctx, userId), s.repo.audit, domain.AuditUserVerifyEmail, domain.AuditEntityUser, userId, nil, after)
+	})
+}
+
+// ResendVerification sends verification link to signed in user again.
+func (s *UserService) ResendVerification(ctx context.Context) error {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return domain.ErrInvalidId
+	}
+
+	user, err := s.repo.user.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return domain.ErrEmailVerified
+	}
+
+	return s.sendVerification(ctx, user.Id, user.Email)
+}
+
+// ForgotPassword queues single-use password reset link. The lookup and the email are made
+// in background, so neither the response nor its timing tell whether the user exists.
+// Requests over the limit of the address are dropped silently for the same reason, requests
+// over the limit of the client IP fail.
+func (s *UserService) ForgotPassword(ctx context.Context, input domain.ForgotPasswordInput) error {
+	now := time.Now()
+
+	if ip, ok := ctx.Value(domain.ClientIpKey).(string); ok && !s.resets.byIp.Allow(ip, now) {
+		return domain.ErrTooManyRequests
+	}
+
+	if !s.resets.byEmail.Allow(strings.ToLower(input.Email), now) {
+		return nil
+	}
+
+	select {
+	case s.resets.queue <- passwordReset{ctx: context.WithoutCancel(ctx), email: input.Email}:
+	default:
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context": "UserService.ForgotPassword()",
+			"problem": "password reset queue is full",
+		}).Warn("password reset dropped")
+	}
+
+	return nil
+}
+
+// SendPasswordResets sends queued password resets one by one until ctx is done.
+func (s *UserService) SendPasswordResets(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case r := <-s.resets.queue:
+			s.sendQueuedReset(ctx, r)
+		}
+	}
+}
+
+func (s *UserService) sendQueuedReset(ctx context.Context, r passwordReset) {
+	resetCtx, cancel := context.WithTimeout(r.ctx, passwordResetTimeout)
+	defer cancel()
+
+	// stop sending on shutdown
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	if err := s.sendPasswordReset(resetCtx, r.email); err != nil {
+		logrus.WithContext(resetCtx).WithFields(logrus.Fields{
+			"context": "UserService.SendPasswordResets()",
+			"problem": "can't send password reset email",
+		}).Error(err)
+	}
+}
+
+func (s *UserService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.user.GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := fmt.Sprintf("%x", b)
+
+	if err := s.repo.passwordReset.Create(ctx, user.Id, hashToken(token), time.Now().Add(s.email.ResetTTL)); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\nUse this token to reset your password within %s:\n\n%s\n\n"+
+			"Send it with a new password to %s/auth/password/reset.\n"+
+			"If you didn't request it, just ignore this email.\n",
+			user.FirstName, s.email.ResetTTL, token, s.email.BaseURL),
+	})
+}
+
+// ResetPassword sets new password with the reset token and signs the user out everywhere.
+func (s *UserService) ResetPassword(ctx context.Context, input domain.ResetPasswordInput) error {
+	password, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		return err
+	}
+
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		userId, err := s.repo.passwordReset.Use(ctx, hashToken(input.Token))
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.user.UpdatePassword(ctx, userId, password); err != nil {
+			return err
+		}
+
+		if err := s.repo.token.DeleteByUser(ctx, userId); err != nil {
+			return err
+		}
+
+		return audit(withActor(ctx, userId), s.repo.audit, domain.AuditUserResetPassword, domain.AuditEntityUser, userId, nil, nil)
+	})
+}
+
+func (s *UserService) sendVerification(ctx context.Context, userId int64, email string) error {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strconv.FormatInt(userId, 10),
+			Subject:   email,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.email.VerificationTTL)),
+		},
+		Purpose: verificationPurpose,
+	})
+
+	signed, err := token.SignedString(s.hmacSecret)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify?token=%s", s.email.BaseURL, url.QueryEscape(signed))
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body:    fmt.Sprintf("Please confirm your email within %s by following the link:\n\n%s\n", s.email.VerificationTTL, link),
+	})
+}
+
+// requireVerifiedEmail returns ErrEmailNotVerified if the signed in user hasn't verified the email yet.
+func requireVerifiedEmail(ctx context.Context, users domain.UserRepository) error {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return domain.ErrInvalidId
+	}
+
+	user, err := users.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		return domain.ErrEmailNotVerified
+	}
+
+	return nil
+}
+
+// hashToken hashes random single-use token, so stolen database doesn't give valid tokens.
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
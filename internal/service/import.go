@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	cache "github.com/Viquad/simple-cache"
+)
+
+type ImportService struct {
+	repo struct {
+		account     domain.AccountRepository
+		transaction domain.TransactionRepository
+		audit       domain.AuditRepository
+		outbox      domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	cache      *tracedCache
+}
+
+func NewImportService(repos Repositories, cache cache.Cache) *ImportService {
+	return &ImportService{
+		repo: struct {
+			account     domain.AccountRepository
+			transaction domain.TransactionRepository
+			audit       domain.AuditRepository
+			outbox      domain.OutboxRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			transaction: repos.GetTransactionRepository(),
+			audit:       repos.GetAuditRepository(),
+			outbox:      repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		cache:      newTracedCache(cache),
+	}
+}
+
+// Import posts entries of external bank file to the account. It credits money, so it's
+// only for admins and operators: the account isn't scoped to the caller. Entries whose
+// external reference is already posted to the account or repeated in the file are skipped
+// as duplicates.
+func (s *ImportService) Import(ctx context.Context, accountId int64, entries []domain.ImportEntry, dryRun bool) (*domain.ImportResult, error) {
+	account, err := s.repo.account.Lookup(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, len(entries))
+	for i := range entries {
+		if entries[i].Currency == "" {
+			entries[i].Currency = account.Currency
+		}
+
+		if entries[i].Currency != account.Currency {
+			return nil, domain.ErrCurrencyMismatch
+		}
+
+		refs = append(refs, entries[i].ExternalRef)
+	}
+
+	existing, err := s.repo.transaction.ListExternalRefs(ctx, accountId, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for _, ref := range existing {
+		seen[ref] = true
+	}
+
+	result := domain.ImportResult{
+		DryRun:    dryRun,
+		AccountId: accountId,
+		Entries:   entries,
+	}
+
+	for i := range entries {
+		if seen[entries[i].ExternalRef] {
+			entries[i].Duplicate = true
+			result.Duplicates++
+			continue
+		}
+
+		seen[entries[i].ExternalRef] = true
+		result.Imported++
+	}
+
+	if dryRun || result.Imported == 0 {
+		return &result, nil
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, e := range entries {
+			if e.Duplicate {
+				continue
+			}
+
+			account, err := s.repo.account.AddBalance(ctx, accountId, e.Amount)
+			if err != nil {
+				return err
+			}
+
+			t, err := s.repo.transaction.Create(ctx, domain.Transaction{
+				AccountId:   accountId,
+				Amount:      e.Amount,
+				Currency:    e.Currency,
+				Type:        domain.TransactionImport,
+				Reference:   reference,
+				Description: e.Description,
+				ExternalRef: e.ExternalRef,
+				BookedAt:    bookingDate(e.Date),
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := emitBalanceChanged(ctx, s.repo.outbox, account, t); err != nil {
+				return err
+			}
+
+			result.Transactions = append(result.Transactions, *t)
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditAccountImport, domain.AuditEntityAccount, accountId, nil, result.Transactions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Delete(ctx, cacheKey(account.UserId, account.Id))
+	s.cache.Delete(ctx, cacheKey(account.UserId, listId))
+
+	return &result, nil
+}
+
+func bookingDate(date time.Time) *time.Time {
+	if date.IsZero() {
+		return nil
+	}
+
+	return &date
+}
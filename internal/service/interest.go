@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/interest"
+	cache "github.com/Viquad/simple-cache"
+	"github.com/sirupsen/logrus"
+)
+
+// accrualScale is a number of decimal places kept for daily accrued interest of minor units.
+const accrualScale = 18
+
+type InterestRates interface {
+	Rate(currency string) (*big.Rat, bool, error)
+}
+
+type InterestService struct {
+	repo struct {
+		account     domain.AccountRepository
+		interest    domain.InterestRepository
+		transaction domain.TransactionRepository
+		audit       domain.AuditRepository
+		outbox      domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	cache      *tracedCache
+	rates      InterestRates
+}
+
+func NewInterestService(repos Repositories, cache cache.Cache, rates InterestRates) *InterestService {
+	return &InterestService{
+		repo: struct {
+			account     domain.AccountRepository
+			interest    domain.InterestRepository
+			transaction domain.TransactionRepository
+			audit       domain.AuditRepository
+			outbox      domain.OutboxRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			interest:    repos.GetInterestRepository(),
+			transaction: repos.GetTransactionRepository(),
+			audit:       repos.GetAuditRepository(),
+			outbox:      repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		cache:      newTracedCache(cache),
+		rates:      rates,
+	}
+}
+
+// Run accrues interest for every day since the last accrual up to yesterday and
+// capitalizes accrued interest when a month is over. Dates already processed are skipped,
+// so Run can be safely restarted.
+func (s *InterestService) Run(ctx context.Context, now time.Time) error {
+	yesterday := truncateDay(now).AddDate(0, 0, -1)
+
+	date := yesterday
+	last, err := s.repo.interest.LastAccrualDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the last date is processed again in case the previous run was interrupted
+	if last != nil {
+		date = truncateDay(*last)
+	}
+
+	for ; !date.After(yesterday); date = date.AddDate(0, 0, 1) {
+		if err := s.accrue(ctx, date); err != nil {
+			return err
+		}
+
+		if date.AddDate(0, 0, 1).Day() == 1 {
+			if err := s.capitalize(ctx, date); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *InterestService) accrue(ctx context.Context, date time.Time) error {
+	balances, err := s.repo.interest.ListSavingsBalances(ctx, date)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range balances {
+		rate, ok, err := s.rates.Rate(b.Currency)
+		if err != nil {
+			return err
+		}
+
+		if !ok || b.Balance <= 0 {
+			continue
+		}
+
+		accrual := domain.InterestAccrual{
+			AccountId: b.AccountId,
+			Date:      date,
+			Balance:   b.Balance,
+			Rate:      rate.FloatString(8),
+			Amount:    interest.Daily(b.Balance, rate, date).FloatString(accrualScale),
+		}
+
+		if err := s.repo.interest.CreateAccrual(ctx, accrual); err != nil {
+			return err
+		}
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"context": "InterestService.accrue()",
+		"date":    date.Format("2006-01-02"),
+	}).Debugf("Accrued interest for %d accounts", len(balances))
+
+	return nil
+}
+
+// capitalize pays accrued interest up to the date to savings accounts. Accrued interest is
+// rounded to minor units; the difference, which can be negative, is carried to the next
+// capitalization as an accrual of the next day, so nothing is lost or overpaid over time.
+func (s *InterestService) capitalize(ctx context.Context, until time.Time) error {
+	ids, err := s.repo.interest.ListAccountsToCapitalize(ctx, until)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		var account *domain.Account
+
+		err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			accruals, err := s.repo.interest.ListUncapitalized(ctx, id, until)
+			if err != nil || len(accruals) == 0 {
+				return err
+			}
+
+			total := new(big.Rat)
+			for _, a := range accruals {
+				amount, ok := new(big.Rat).SetString(a.Amount)
+				if !ok {
+					return fmt.Errorf("invalid accrual amount %q", a.Amount)
+				}
+				total.Add(total, amount)
+			}
+
+			amount := interest.Round(total)
+			if amount == 0 {
+				return nil
+			}
+
+			reference, err := newReference()
+			if err != nil {
+				return err
+			}
+
+			account, err = s.repo.account.AddBalance(ctx, id, amount)
+			if err != nil {
+				return err
+			}
+
+			t, err := s.repo.transaction.Create(ctx, domain.Transaction{
+				AccountId:   id,
+				Amount:      amount,
+				Currency:    account.Currency,
+				Type:        domain.TransactionInterest,
+				Reference:   reference,
+				Description: "interest for " + until.Format("2006-01"),
+			})
+			if err != nil {
+				return err
+			}
+
+			if err := emitBalanceChanged(ctx, s.repo.outbox, account, t); err != nil {
+				return err
+			}
+
+			if err := audit(ctx, s.repo.audit, domain.AuditAccountInterest, domain.AuditEntityAccount, id, nil, t); err != nil {
+				return err
+			}
+
+			if err := s.repo.interest.MarkCapitalized(ctx, id, until, reference); err != nil {
+				return err
+			}
+
+			carry := new(big.Rat).Sub(total, new(big.Rat).SetInt64(amount))
+			if carry.Sign() == 0 {
+				return nil
+			}
+
+			return s.repo.interest.CreateAccrual(ctx, domain.InterestAccrual{
+				AccountId: id,
+				Kind:      domain.AccrualCarry,
+				Date:      until.AddDate(0, 0, 1),
+				Balance:   account.Balance,
+				Rate:      "0",
+				Amount:    carry.FloatString(accrualScale),
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		if account != nil {
+			s.cache.Delete(ctx, cacheKey(account.UserId, account.Id))
+			s.cache.Delete(ctx, cacheKey(account.UserId, listId))
+		}
+	}
+
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
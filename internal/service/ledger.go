@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	cache "github.com/Viquad/simple-cache"
+)
+
+// errRiskHeld rolls back the operation held by risk screening.
+var errRiskHeld = errors.New("operation is held by risk screening")
+
+// posting is an outgoing money movement: ledger lines of the operation and fees charged
+// from the account. Lines get a new reference unless it's set. Within runs in the
+// transaction of the lines, after they are posted.
+type posting struct {
+	action    string
+	op        domain.RiskOperation
+	lines     []domain.Transaction
+	fees      []domain.Fee
+	reference string
+	within    func(ctx context.Context) error
+}
+
+// ledger posts outgoing money movements of transfers, withdrawals and payments the same way:
+// with fees, limits and risk screening.
+type ledger struct {
+	repo struct {
+		account     domain.AccountRepository
+		user        domain.UserRepository
+		transaction domain.TransactionRepository
+		audit       domain.AuditRepository
+		outbox      domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	cache      *tracedCache
+	fees       FeeCalculator
+	limits     LimitChecker
+	risk       RiskScreener
+}
+
+func newLedger(repos Repositories, cache cache.Cache, fees FeeCalculator, limits LimitChecker, risk RiskScreener) *ledger {
+	return &ledger{
+		repo: struct {
+			account     domain.AccountRepository
+			user        domain.UserRepository
+			transaction domain.TransactionRepository
+			audit       domain.AuditRepository
+			outbox      domain.OutboxRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			user:        repos.GetUserRepository(),
+			transaction: repos.GetTransactionRepository(),
+			audit:       repos.GetAuditRepository(),
+			outbox:      repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		cache:      newTracedCache(cache),
+		fees:       fees,
+		limits:     limits,
+		risk:       risk,
+	}
+}
+
+// preview calculates fees of the operation without moving money.
+func (l *ledger) preview(ctx context.Context, userId int64, operation, currency string, amount int64) (*domain.TransactionResult, error) {
+	user, err := l.repo.user.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := domain.TransactionResult{
+		DryRun: true,
+		Amount: amount,
+		Fees:   []domain.Fee{},
+		Total:  amount,
+	}
+
+	for _, charge := range l.fees.Calculate(operation, currency, user.Tier, amount) {
+		result.Fees = append(result.Fees, domain.Fee{Name: charge.Name, Amount: charge.Amount, Currency: charge.Currency})
+		result.Total += charge.Amount
+	}
+
+	return &result, nil
+}
+
+// post writes ledger lines and fees charged from the account in a single DB transaction
+// and audits them as the action. Limits of the account are checked again under lock,
+// as concurrent operations could use them up. The operation is screened for risk in the
+// same transaction, so an approval is used up only if the operation is committed.
+func (l *ledger) post(ctx context.Context, p posting) ([]domain.Transaction, error) {
+	from := p.op.Account
+	lines := p.lines
+	if len(p.fees) > 0 {
+		income, ok := l.fees.IncomeAccount(from.Currency)
+		if !ok {
+			return nil, domain.ErrNoFeeAccount
+		}
+
+		for _, f := range p.fees {
+			lines = append(lines,
+				domain.Transaction{AccountId: from.Id, Amount: -f.Amount, Type: domain.TransactionFee, Description: f.Name},
+				domain.Transaction{AccountId: income, Amount: f.Amount, Type: domain.TransactionFee, Description: f.Name},
+			)
+		}
+	}
+
+	reference := p.reference
+	if reference == "" {
+		var err error
+		if reference, err = newReference(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		posted  []domain.Transaction
+		touched []*domain.Account
+		held    *domain.RiskReview
+	)
+
+	err := l.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := l.limits.Lock(ctx, from); err != nil {
+			return err
+		}
+
+		if err := l.limits.Check(ctx, from, p.op.Amount); err != nil {
+			return err
+		}
+
+		var err error
+		if held, err = l.risk.Screen(ctx, p.op); err != nil {
+			return err
+		}
+		if held != nil {
+			return errRiskHeld
+		}
+
+		for _, line := range lines {
+			account, err := l.repo.account.AddBalance(ctx, line.AccountId, line.Amount)
+			if err != nil {
+				return err
+			}
+
+			line.Currency = from.Currency
+			line.Reference = reference
+			t, err := l.repo.transaction.Create(ctx, line)
+			if err != nil {
+				return err
+			}
+
+			if err := emitBalanceChanged(ctx, l.repo.outbox, account, t); err != nil {
+				return err
+			}
+
+			posted = append(posted, *t)
+			touched = append(touched, account)
+		}
+
+		if err := audit(ctx, l.repo.audit, p.action, domain.AuditEntityAccount, from.Id, nil, posted); err != nil {
+			return err
+		}
+
+		if p.within != nil {
+			return p.within(ctx)
+		}
+
+		return nil
+	})
+	if errors.Is(err, errRiskHeld) {
+		return nil, l.risk.Hold(ctx, *held)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range touched {
+		l.cache.Delete(ctx, cacheKey(account.UserId, account.Id))
+		l.cache.Delete(ctx, cacheKey(account.UserId, listId))
+	}
+
+	return posted, nil
+}
@@ -0,0 +1,425 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+// limitsChange is audited on admin override, which changes limits set by both admin and user.
+type limitsChange struct {
+	Admin domain.Limits `json:"admin"`
+	User  domain.Limits `json:"user"`
+}
+
+// LimitDefaults are account or user limits by currency used unless overridden by admin.
+type LimitDefaults map[string]domain.Limits
+
+type LimitService struct {
+	repo struct {
+		account     domain.AccountRepository
+		limit       domain.LimitRepository
+		transaction domain.TransactionRepository
+		audit       domain.AuditRepository
+	}
+	transactor   domain.Transactor
+	defaults     LimitDefaults
+	userDefaults LimitDefaults
+}
+
+func NewLimitService(repos Repositories, defaults, userDefaults LimitDefaults) *LimitService {
+	return &LimitService{
+		repo: struct {
+			account     domain.AccountRepository
+			limit       domain.LimitRepository
+			transaction domain.TransactionRepository
+			audit       domain.AuditRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			limit:       repos.GetLimitRepository(),
+			transaction: repos.GetTransactionRepository(),
+			audit:       repos.GetAuditRepository(),
+		},
+		transactor:   repos.GetTransactor(),
+		defaults:     defaults,
+		userDefaults: userDefaults,
+	}
+}
+
+// Get returns effective limits of user's account and money spent in current windows.
+func (s *LimitService) Get(ctx context.Context, accountId int64) (*domain.AccountLimits, error) {
+	account, err := s.repo.account.GetById(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.get(ctx, account)
+}
+
+// Lower sets limits of user's account. Given limits must not be above effective ones.
+func (s *LimitService) Lower(ctx context.Context, accountId int64, inp domain.Limits) (*domain.AccountLimits, error) {
+	account, err := s.repo.account.GetById(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.effective(ctx, account)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.stored(ctx, account.Id, domain.LimitsSetByUser)
+		if err != nil {
+			return err
+		}
+
+		before := user
+		cur, in, set := limitFields(&current), limitFields(&inp), limitFields(&user)
+		for i := range in {
+			if *in[i] == nil {
+				continue
+			}
+			if *cur[i] != nil && **in[i] > **cur[i] {
+				return domain.ErrLimitRaise
+			}
+			*set[i] = *in[i]
+		}
+
+		if err := s.repo.limit.Set(ctx, account.Id, domain.LimitsSetByUser, user); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditLimitsLower, domain.AuditEntityAccount, account.Id, before, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.get(ctx, account)
+}
+
+// Override sets limits of any account instead of defaults. Limits lowered by the owner
+// are reset for the overridden fields, so admin can raise them back.
+func (s *LimitService) Override(ctx context.Context, accountId int64, inp domain.Limits) (*domain.AccountLimits, error) {
+	account, err := s.repo.account.Lookup(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		admin, err := s.stored(ctx, account.Id, domain.LimitsSetByAdmin)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.stored(ctx, account.Id, domain.LimitsSetByUser)
+		if err != nil {
+			return err
+		}
+
+		before := limitsChange{Admin: admin, User: user}
+		in, adminSet, userSet := limitFields(&inp), limitFields(&admin), limitFields(&user)
+		for i := range in {
+			if *in[i] != nil {
+				*adminSet[i] = *in[i]
+				*userSet[i] = nil
+			}
+		}
+
+		if err := s.repo.limit.Set(ctx, account.Id, domain.LimitsSetByAdmin, admin); err != nil {
+			return err
+		}
+
+		if err := s.repo.limit.Set(ctx, account.Id, domain.LimitsSetByUser, user); err != nil {
+			return err
+		}
+
+		after := limitsChange{Admin: admin, User: user}
+		return audit(ctx, s.repo.audit, domain.AuditLimitsOverride, domain.AuditEntityAccount, account.Id, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.get(ctx, account)
+}
+
+// GetUser returns effective limits of the signed in user in currencies of their accounts.
+func (s *LimitService) GetUser(ctx context.Context) ([]domain.UserLimits, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	accounts, err := s.repo.account.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.UserLimits, 0, len(accounts))
+	seen := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		if seen[a.Currency] {
+			continue
+		}
+		seen[a.Currency] = true
+
+		limits, err := s.getUser(ctx, userId, a.Currency)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *limits)
+	}
+
+	return result, nil
+}
+
+// OverrideUser sets limits of all accounts of any user in the currency instead of defaults.
+// Omitted fields are left unchanged.
+func (s *LimitService) OverrideUser(ctx context.Context, userId int64, inp domain.UserLimitsInput) (*domain.UserLimits, error) {
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		limits, err := s.storedUser(ctx, userId, inp.Currency)
+		if err != nil {
+			return err
+		}
+
+		before := domain.UserLimitsInput{Limits: limits, Currency: inp.Currency}
+		in, set := limitFields(&inp.Limits), limitFields(&limits)
+		for i := range in {
+			if *in[i] != nil {
+				*set[i] = *in[i]
+			}
+		}
+
+		if err := s.repo.limit.SetUser(ctx, userId, inp.Currency, limits); err != nil {
+			return err
+		}
+
+		after := domain.UserLimitsInput{Limits: limits, Currency: inp.Currency}
+		return audit(ctx, s.repo.audit, domain.AuditUserLimitsOverride, domain.AuditEntityUserLimits, userId, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getUser(ctx, userId, inp.Currency)
+}
+
+// Lock locks the owner and then the account till the end of transaction, so operations
+// of the user are checked against limits one by one. Owner goes first, so operations
+// from different accounts of the user don't deadlock.
+func (s *LimitService) Lock(ctx context.Context, account *domain.Account) error {
+	if err := s.repo.limit.LockUser(ctx, account.UserId); err != nil {
+		return err
+	}
+
+	return s.repo.account.Lock(ctx, account.Id)
+}
+
+// Check returns LimitExceededError if the amount can't be sent from the account now, by limits of the account or of all accounts of its owner in
+// the currency. Call it within transaction after Lock to take concurrent operations into account.
+func (s *LimitService) Check(ctx context.Context, account *domain.Account, amount int64) error {
+	limits, err := s.effective(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	if limits.PerTransaction != nil && amount > *limits.PerTransaction {
+		return &domain.LimitExceededError{Limit: "per_transaction", Remaining: *limits.PerTransaction}
+	}
+
+	now := time.Now()
+	windows := []struct {
+		name   string
+		limit  *int64
+		window time.Duration
+	}{
+		{"daily", limits.Daily, domain.LimitDailyWindow},
+		{"monthly", limits.Monthly, domain.LimitMonthlyWindow},
+	}
+
+	for _, w := range windows {
+		if w.limit == nil {
+			continue
+		}
+
+		spent, err := s.repo.transaction.SumOutgoing(ctx, account.Id, now.Add(-w.window))
+		if err != nil {
+			return err
+		}
+
+		remaining := *w.limit - spent
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		if amount > remaining {
+			return &domain.LimitExceededError{Limit: w.name, Remaining: remaining}
+		}
+	}
+
+	return s.checkUser(ctx, account, amount)
+}
+
+// checkUser checks the amount against limits of the owner, named with "user_" prefix.
+func (s *LimitService) checkUser(ctx context.Context, account *domain.Account, amount int64) error {
+	limits, err := s.effectiveUser(ctx, account.UserId, account.Currency)
+	if err != nil {
+		return err
+	}
+
+	if limits.PerTransaction != nil && amount > *limits.PerTransaction {
+		return &domain.LimitExceededError{Limit: "user_per_transaction", Remaining: *limits.PerTransaction}
+	}
+
+	now := time.Now()
+	windows := []struct {
+		name   string
+		limit  *int64
+		window time.Duration
+	}{
+		{"user_daily", limits.Daily, domain.LimitDailyWindow},
+		{"user_monthly", limits.Monthly, domain.LimitMonthlyWindow},
+	}
+
+	for _, w := range windows {
+		if w.limit == nil {
+			continue
+		}
+
+		spent, err := s.repo.transaction.SumUserOutgoing(ctx, account.UserId, account.Currency, now.Add(-w.window))
+		if err != nil {
+			return err
+		}
+
+		remaining := *w.limit - spent
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		if amount > remaining {
+			return &domain.LimitExceededError{Limit: w.name, Remaining: remaining}
+		}
+	}
+
+	return nil
+}
+
+func (s *LimitService) get(ctx context.Context, account *domain.Account) (*domain.AccountLimits, error) {
+	limits, err := s.effective(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+
+	result := domain.AccountLimits{Limits: limits, AccountId: account.Id, Currency: account.Currency}
+
+	now := time.Now()
+	if result.DailySpent, err = s.repo.transaction.SumOutgoing(ctx, account.Id, now.Add(-domain.LimitDailyWindow)); err != nil {
+		return nil, err
+	}
+	if result.MonthlySpent, err = s.repo.transaction.SumOutgoing(ctx, account.Id, now.Add(-domain.LimitMonthlyWindow)); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *LimitService) getUser(ctx context.Context, userId int64, currency string) (*domain.UserLimits, error) {
+	limits, err := s.effectiveUser(ctx, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	result := domain.UserLimits{Limits: limits, Currency: currency}
+
+	now := time.Now()
+	if result.DailySpent, err = s.repo.transaction.SumUserOutgoing(ctx, userId, currency, now.Add(-domain.LimitDailyWindow)); err != nil {
+		return nil, err
+	}
+	if result.MonthlySpent, err = s.repo.transaction.SumUserOutgoing(ctx, userId, currency, now.Add(-domain.LimitMonthlyWindow)); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// effectiveUser combines user defaults of the currency with admin overrides.
+func (s *LimitService) effectiveUser(ctx context.Context, userId int64, currency string) (domain.Limits, error) {
+	limits := s.userDefaults[currency]
+
+	admin, err := s.storedUser(ctx, userId, currency)
+	if err != nil {
+		return limits, err
+	}
+
+	eff, adminSet := limitFields(&limits), limitFields(&admin)
+	for i := range eff {
+		if *adminSet[i] != nil {
+			*eff[i] = *adminSet[i]
+		}
+	}
+
+	return limits, nil
+}
+
+// storedUser returns limits of the user in the currency set by admin, empty if there are none.
+func (s *LimitService) storedUser(ctx context.Context, userId int64, currency string) (domain.Limits, error) {
+	limits, err := s.repo.limit.GetUser(ctx, userId, currency)
+	if errors.Is(err, domain.ErrNotExist) {
+		return domain.Limits{}, nil
+	}
+	if err != nil {
+		return domain.Limits{}, err
+	}
+
+	return *limits, nil
+}
+
+// effective combines defaults of account currency, admin overrides and limits lowered by the owner.
+func (s *LimitService) effective(ctx context.Context, account *domain.Account) (domain.Limits, error) {
+	limits := s.defaults[account.Currency]
+
+	admin, err := s.stored(ctx, account.Id, domain.LimitsSetByAdmin)
+	if err != nil {
+		return limits, err
+	}
+
+	user, err := s.stored(ctx, account.Id, domain.LimitsSetByUser)
+	if err != nil {
+		return limits, err
+	}
+
+	eff, adminSet, userSet := limitFields(&limits), limitFields(&admin), limitFields(&user)
+	for i := range eff {
+		if *adminSet[i] != nil {
+			*eff[i] = *adminSet[i]
+		}
+		if *userSet[i] != nil && (*eff[i] == nil || **userSet[i] < **eff[i]) {
+			*eff[i] = *userSet[i]
+		}
+	}
+
+	return limits, nil
+}
+
+// stored returns limits of the account set by admin or user, empty if there are none.
+func (s *LimitService) stored(ctx context.Context, accountId int64, setBy string) (domain.Limits, error) {
+	limits, err := s.repo.limit.Get(ctx, accountId, setBy)
+	if errors.Is(err, domain.ErrNotExist) {
+		return domain.Limits{}, nil
+	}
+	if err != nil {
+		return domain.Limits{}, err
+	}
+
+	return *limits, nil
+}
+
+// limitFields returns pointers to fields of limits in the same order for any value.
+func limitFields(l *domain.Limits) []**int64 {
+	return []**int64{&l.PerTransaction, &l.Daily, &l.Monthly}
+}
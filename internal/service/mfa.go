@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/totp"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	challengePurpose   = "mfa_challenge"
+	recoveryCodesCount = 10
+	recoveryCodeSize   = 5
+	// totpSkew is the number of time steps accepted before and after current one for clock drift.
+	totpSkew = 1
+)
+
+// MfaSettings configure TOTP second factor. MaxAge is how long passed second factor allows
+// sensitive operations, transfers, withdrawals and payments from LargeTransfer amount are
+// sensitive. After MaxAttempts invalid codes in a row checks of the user are locked for Lockout.
+type MfaSettings struct {
+	Issuer        string
+	ChallengeTTL  time.Duration
+	MaxAge        time.Duration
+	LargeTransfer int64
+	MaxAttempts   int
+	Lockout       time.Duration
+}
+
+// requireForAmount requires recent second factor for money moved out from LargeTransfer amount.
+func (m MfaSettings) requireForAmount(ctx context.Context, users domain.UserRepository, amount int64) error {
+	if m.LargeTransfer > 0 && amount >= m.LargeTransfer {
+		return requireRecentMfa(ctx, users, m.MaxAge)
+	}
+
+	return nil
+}
+
+// EnrollTotp generates new TOTP secret for the user. It's enabled once confirmed with a code.
+func (s *UserService) EnrollTotp(ctx context.Context) (*domain.TotpEnrollment, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	user, err := s.repo.user.GetById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.mfa.SetTotpSecret(ctx, userId, secret); err != nil {
+		return nil, err
+	}
+
+	return &domain.TotpEnrollment{Secret: secret, Uri: totp.Default.URI(s.mfa.Issuer, user.Email, secret)}, nil
+}
+
+// ConfirmTotp enables enrolled TOTP and returns recovery codes. They are shown only once.
+func (s *UserService) ConfirmTotp(ctx context.Context, code string) ([]string, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	settings, err := s.repo.mfa.GetTotp(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings.Enabled {
+		return nil, domain.ErrMfaAlreadyEnabled
+	}
+
+	if settings.Secret == "" {
+		return nil, domain.ErrMfaNotEnrolled
+	}
+
+	key, err := totp.Decode(settings.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Default.Validate(key, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, domain.ErrInvalidMfaCode
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := s.hasher.Hash(normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.mfa.EnableTotp(ctx, userId, step); err != nil {
+			return err
+		}
+
+		if err := s.repo.mfa.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditUserEnableTotp, domain.AuditEntityUser, userId, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// SignInWithMfa exchanges challenge token from sign in and TOTP or recovery code for tokens.
+func (s *UserService) SignInWithMfa(ctx context.Context, input domain.MfaSignInInput) (string, string, error) {
+	accessToken, refreshToken, err := s.signInWithMfa(ctx, input)
+	s.metrics.SignIn("mfa", signInResult(err))
+
+	return accessToken, refreshToken, err
+}
+
+func (s *UserService) signInWithMfa(ctx context.Context, input domain.MfaSignInInput) (string, string, error) {
+	claims, err := s.parseClaims(input.ChallengeToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	if claims.Purpose != challengePurpose {
+		return "", "", domain.ErrInvalidToken
+	}
+
+	userId, err := strconv.ParseInt(claims.ID, 10, 64)
+	if err != nil {
+		return "", "", domain.ErrInvalidId
+	}
+
+	if err := s.checkCode(ctx, userId, input.Code); err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+
+	return s.generateTokens(ctx, userId, &now)
+}
+
+// StepUp issues tokens with fresh second factor claim to signed in user.
+func (s *UserService) StepUp(ctx context.Context, code string) (string, string, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return "", "", domain.ErrInvalidId
+	}
+
+	if err := s.checkCode(ctx, userId, code); err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+
+	return s.generateTokens(ctx, userId, &now)
+}
+
+// checkCode accepts TOTP code once or unused recovery code. Invalid codes are counted,
+// and too many of them in a row lock checks of the user, so codes can't be brute-forced.
+func (s *UserService) checkCode(ctx context.Context, userId int64, code string) error {
+	settings, err := s.repo.mfa.GetTotp(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !settings.Enabled {
+		return domain.ErrMfaNotEnrolled
+	}
+
+	now := time.Now().UTC()
+	if settings.LockedUntil != nil && now.Before(*settings.LockedUntil) {
+		return domain.ErrMfaLocked
+	}
+
+	err = s.verifyCode(ctx, userId, settings, code)
+	switch {
+	case errors.Is(err, domain.ErrInvalidMfaCode) && s.mfa.MaxAttempts > 0:
+		if err := s.repo.mfa.FailAttempt(ctx, userId, s.mfa.MaxAttempts, now.Add(s.mfa.Lockout)); err != nil {
+			return err
+		}
+	case err == nil && settings.FailedAttempts > 0:
+		return s.repo.mfa.ResetAttempts(ctx, userId)
+	}
+
+	return err
+}
+
+func (s *UserService) verifyCode(ctx context.Context, userId int64, settings *domain.TotpSettings, code string) error {
+	if len(code) == totp.Default.Digits {
+		key, err := totp.Decode(settings.Secret)
+		if err != nil {
+			return err
+		}
+
+		step, ok := totp.Default.Validate(key, code, time.Now(), totpSkew)
+		if !ok {
+			return domain.ErrInvalidMfaCode
+		}
+
+		// a code can't be replayed within its time step
+		fresh, err := s.repo.mfa.UseTotpStep(ctx, userId, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return domain.ErrInvalidMfaCode
+		}
+
+		return nil
+	}
+
+	hash, err := s.hasher.Hash(normalizeRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+
+	used, err := s.repo.mfa.UseRecoveryCode(ctx, userId, hash)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidMfaCode
+	}
+
+	return nil
+}
+
+func (s *UserService) newChallengeToken(userId int64) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strconv.FormatInt(userId, 10),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.mfa.ChallengeTTL)),
+		},
+		Purpose: challengePurpose,
+	})
+
+	return token.SignedString(s.hmacSecret)
+}
+
+// requireRecentMfa returns ErrMfaRequired if the user has TOTP enabled and
+// hasn't passed second factor within max age.
+func requireRecentMfa(ctx context.Context, users domain.UserRepository, maxAge time.Duration) error {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return domain.ErrInvalidId
+	}
+
+	user, err := users.GetById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if !user.TotpEnabled {
+		return nil
+	}
+
+	mfaAt, ok := ctx.Value(domain.MfaAtKey).(time.Time)
+	if !ok || time.Since(mfaAt) > maxAge {
+		return domain.ErrMfaRequired
+	}
+
+	return nil
+}
+
+func newRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+
+	return code[:4] + "-" + code[4:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/publisher"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultOutboxLease = time.Minute
+
+type EventPublisher interface {
+	Publish(ctx context.Context, msg publisher.Message) error
+}
+
+// EventHandler consumes events after they are published, in the transaction marking them
+// published. Events are handled again if it fails, so handlers must be idempotent.
+type EventHandler interface {
+	Handle(ctx context.Context, e domain.Event) error
+}
+
+// OutboxSettings configure the relay. Failed events are retried with exponential backoff
+// from MinBackoff up to MaxBackoff. Claimed events are not picked by other relays for Lease.
+type OutboxSettings struct {
+	BatchSize  int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Lease      time.Duration
+}
+
+// OutboxService relays events from the outbox to the publisher. Every event is published
+// at least once; receivers should deduplicate them by id.
+type OutboxService struct {
+	repo struct {
+		outbox domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	publisher  EventPublisher
+	handlers   []EventHandler
+	settings   OutboxSettings
+}
+
+func NewOutboxService(repos Repositories, publisher EventPublisher, settings OutboxSettings, handlers ...EventHandler) *OutboxService {
+	if settings.Lease <= 0 {
+		settings.Lease = defaultOutboxLease
+	}
+
+	return &OutboxService{
+		repo: struct {
+			outbox domain.OutboxRepository
+		}{
+			outbox: repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		publisher:  publisher,
+		handlers:   handlers,
+		settings:   settings,
+	}
+}
+
+// Relay publishes events due at now until there are no more of them. A batch holds only
+// the oldest event of every aggregate, so it goes on while anything is claimed: events of
+// a busy aggregate are drained one per batch rather than one per poll.
+func (s *OutboxService) Relay(ctx context.Context, now time.Time) error {
+	for {
+		n, err := s.relayBatch(ctx, now)
+		if err != nil || n == 0 {
+			return err
+		}
+	}
+}
+
+// relayBatch claims due events and publishes them outside of transaction, so a slow
+// publisher doesn't hold locks. Results are recorded with handlers in a short transaction
+// for every event.
+func (s *OutboxService) relayBatch(ctx context.Context, now time.Time) (int, error) {
+	events, err := s.repo.outbox.ClaimPending(ctx, now, s.settings.BatchSize, time.Now().Add(s.settings.Lease))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		if err := s.relay(ctx, e); err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
+
+func (s *OutboxService) relay(ctx context.Context, e domain.Event) error {
+	publishErr := s.publisher.Publish(ctx, eventMessage(e))
+	if publishErr != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context":  "OutboxService.Relay()",
+			"problem":  "can't publish event",
+			"event_id": e.Id,
+			"attempts": e.Attempts + 1,
+		}).Warn(publishErr.Error())
+
+		next := time.Now().Add(backoff(s.settings.MinBackoff, s.settings.MaxBackoff, e.Attempts))
+		return s.repo.outbox.MarkFailed(ctx, e.Id, next, publishErr.Error())
+	}
+
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, h := range s.handlers {
+			if err := h.Handle(ctx, e); err != nil {
+				return err
+			}
+		}
+
+		return s.repo.outbox.MarkPublished(ctx, e.Id)
+	})
+}
+
+// backoff returns delay before the next attempt: min doubled for every failed attempt, up to max.
+func backoff(min, max time.Duration, attempts int) time.Duration {
+	d := min
+	for i := 0; i < attempts && d < max; i++ {
+		d *= 2
+	}
+
+	if d > max {
+		d = max
+	}
+
+	return d
+}
+
+func eventMessage(e domain.Event) publisher.Message {
+	return publisher.Message{
+		Id:        strconv.FormatInt(e.Id, 10),
+		Type:      e.Type,
+		Key:       e.AggregateType + ":" + e.AggregateId,
+		Payload:   e.Payload,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// emit stores domain event in the outbox. Call it within the transaction of the change after
+// the row of the aggregate is updated: the row lock makes ids of its events follow commit order.
+func emit(ctx context.Context, outbox domain.OutboxRepository, aggregateType string, aggregateId int64, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Create(ctx, domain.Event{
+		AggregateType: aggregateType,
+		AggregateId:   strconv.FormatInt(aggregateId, 10),
+		Type:          eventType,
+		Payload:       data,
+	})
+}
+
+// emitBalanceChanged emits BalanceChanged for ledger line t posted to the account.
+func emitBalanceChanged(ctx context.Context, outbox domain.OutboxRepository, account *domain.Account, t *domain.Transaction) error {
+	return emit(ctx, outbox, domain.AggregateAccount, account.Id, domain.EventBalanceChanged, domain.BalanceChanged{
+		AccountId:       account.Id,
+		UserId:          account.UserId,
+		Balance:         account.Balance,
+		Currency:        account.Currency,
+		Amount:          t.Amount,
+		TransactionId:   t.Id,
+		TransactionType: t.Type,
+		Reference:       t.Reference,
+	})
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/iban"
+	"github.com/Viquad/crud-app/pkg/namematch"
+)
+
+type PayeeService struct {
+	repo struct {
+		payee   domain.PayeeRepository
+		account domain.AccountRepository
+		user    domain.UserRepository
+		audit   domain.AuditRepository
+	}
+	transactor domain.Transactor
+	mfa        MfaSettings
+}
+
+func NewPayeeService(repos Repositories, mfa MfaSettings) *PayeeService {
+	return &PayeeService{
+		repo: struct {
+			payee   domain.PayeeRepository
+			account domain.AccountRepository
+			user    domain.UserRepository
+			audit   domain.AuditRepository
+		}{
+			payee:   repos.GetPayeeRepository(),
+			account: repos.GetAccountRepository(),
+			user:    repos.GetUserRepository(),
+			audit:   repos.GetAuditRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		mfa:        mfa,
+	}
+}
+
+// Create saves a payee. Users with TOTP enabled must pass second factor recently.
+func (s *PayeeService) Create(ctx context.Context, inp domain.PayeeInput) (*domain.Payee, error) {
+	if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+		return nil, err
+	}
+
+	number := iban.Normalize(inp.Iban)
+	if err := iban.Validate(number); err != nil {
+		return nil, domain.ErrInvalidIban
+	}
+
+	payee := domain.Payee{Name: inp.Name, Iban: number, Currency: inp.Currency}
+	check, err := s.check(ctx, payee)
+	if err != nil {
+		return nil, err
+	}
+
+	var created *domain.Payee
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = s.repo.payee.Create(ctx, payee); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditPayeeCreate, domain.AuditEntityPayee, created.Id, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created.NameCheck = check
+
+	return created, nil
+}
+
+func (s *PayeeService) List(ctx context.Context) ([]domain.Payee, error) {
+	return s.repo.payee.List(ctx)
+}
+
+func (s *PayeeService) GetById(ctx context.Context, id int64) (*domain.Payee, error) {
+	return s.repo.payee.GetById(ctx, id)
+}
+
+// UpdateById changes the payee and checks the name again if name or IBAN changed.
+func (s *PayeeService) UpdateById(ctx context.Context, id int64, inp domain.PayeeUpdateInput) (*domain.Payee, error) {
+	payee, err := s.repo.payee.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := *payee
+
+	if inp.Iban != nil {
+		// changing IBAN is the same as adding a new payee
+		if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+			return nil, err
+		}
+
+		number := iban.Normalize(*inp.Iban)
+		if err := iban.Validate(number); err != nil {
+			return nil, domain.ErrInvalidIban
+		}
+		inp.Iban = &number
+		payee.Iban = number
+	}
+	if inp.Name != nil {
+		payee.Name = *inp.Name
+	}
+	if inp.Currency != nil {
+		payee.Currency = *inp.Currency
+	}
+
+	check, err := s.check(ctx, *payee)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *domain.Payee
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = s.repo.payee.UpdateById(ctx, id, inp); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditPayeeUpdate, domain.AuditEntityPayee, id, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if inp.Name != nil || inp.Iban != nil {
+		updated.NameCheck = check
+	}
+
+	return updated, nil
+}
+
+func (s *PayeeService) DeleteById(ctx context.Context, id int64) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.payee.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.payee.DeleteById(ctx, id); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditPayeeDelete, domain.AuditEntityPayee, id, before, nil)
+	})
+}
+
+// check confirms the payee name against the holder of the account with payee's IBAN.
+// Currency of the payee must match the account if it is held in this bank.
+func (s *PayeeService) check(ctx context.Context, payee domain.Payee) (*domain.NameCheck, error) {
+	account, err := s.repo.account.GetByIban(ctx, payee.Iban)
+	if errors.Is(err, domain.ErrNotExist) {
+		return &domain.NameCheck{Result: namematch.Unavailable}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if account.Currency != payee.Currency {
+		return nil, domain.ErrCurrencyMismatch
+	}
+
+	return confirmPayee(ctx, s.repo.user, account, payee.Name)
+}
+
+// confirmPayee compares the name given by payer with the holder of the account.
+// Holder's name is never disclosed: on close match only its masked form is returned.
+func confirmPayee(ctx context.Context, users domain.UserRepository, account *domain.Account, name string) (*domain.NameCheck, error) {
+	holder, err := users.GetById(ctx, account.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	check := domain.NameCheck{Result: namematch.Compare(name, holder.FirstName, holder.LastName)}
+	if check.Result == namematch.CloseMatch {
+		check.Name = namematch.Mask(holder.FirstName, holder.LastName)
+	}
+
+	return &check, nil
+}
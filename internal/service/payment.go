@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/fee"
+	"github.com/Viquad/crud-app/pkg/iban"
+	"github.com/Viquad/crud-app/pkg/pain"
+	cache "github.com/Viquad/simple-cache"
+)
+
+// PaymentExportSettings describe where pain.001 files are written and who is the debtor in them.
+type PaymentExportSettings struct {
+	Dir        string
+	Initiator  string
+	DebtorName string
+	DebtorIban string
+	DebtorBic  string
+}
+
+type PaymentService struct {
+	repo struct {
+		account     domain.AccountRepository
+		user        domain.UserRepository
+		payment     domain.PaymentRepository
+		transaction domain.TransactionRepository
+		audit       domain.AuditRepository
+		outbox      domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	cache      *tracedCache
+	ledger     *ledger
+	limits     LimitChecker
+	mfa        MfaSettings
+	settings   PaymentExportSettings
+}
+
+func NewPaymentService(repos Repositories, cache cache.Cache, fees FeeCalculator, limits LimitChecker, risk RiskScreener, mfa MfaSettings, settings PaymentExportSettings) *PaymentService {
+	return &PaymentService{
+		repo: struct {
+			account     domain.AccountRepository
+			user        domain.UserRepository
+			payment     domain.PaymentRepository
+			transaction domain.TransactionRepository
+			audit       domain.AuditRepository
+			outbox      domain.OutboxRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			user:        repos.GetUserRepository(),
+			payment:     repos.GetPaymentRepository(),
+			transaction: repos.GetTransactionRepository(),
+			audit:       repos.GetAuditRepository(),
+			outbox:      repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		cache:      newTracedCache(cache),
+		ledger:     newLedger(repos, cache, fees, limits, risk),
+		limits:     limits,
+		mfa:        mfa,
+		settings:   settings,
+	}
+}
+
+// Create debits user's account with fees and queues payment order for export. The debit is
+// posted like withdrawals: screened for risk and checked against limits in its transaction.
+func (s *PaymentService) Create(ctx context.Context, accountId int64, inp domain.PaymentOrderInput) (*domain.PaymentOrder, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	if err := requireVerifiedEmail(ctx, s.repo.user); err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.account.GetById(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	inp.CreditorIban = iban.Normalize(inp.CreditorIban)
+	if err := iban.Validate(inp.CreditorIban); err != nil {
+		return nil, domain.ErrInvalidIban
+	}
+
+	today := truncateDay(time.Now())
+	executionDate := today
+	if inp.ExecutionDate != "" {
+		executionDate, err = time.Parse("2006-01-02", inp.ExecutionDate)
+		if err != nil || executionDate.Before(today) {
+			return nil, domain.ErrInvalidDate
+		}
+	}
+
+	if err := s.limits.Check(ctx, account, inp.Amount); err != nil {
+		return nil, err
+	}
+
+	result, err := s.ledger.preview(ctx, userId, fee.OperationPayment, account.Currency, inp.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.requireForAmount(ctx, s.repo.user, inp.Amount); err != nil {
+		return nil, err
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return nil, err
+	}
+
+	order := domain.PaymentOrder{
+		AccountId:      account.Id,
+		Amount:         inp.Amount,
+		Currency:       account.Currency,
+		CreditorName:   inp.CreditorName,
+		CreditorIban:   inp.CreditorIban,
+		CreditorBic:    inp.CreditorBic,
+		RemittanceInfo: inp.RemittanceInfo,
+		ExecutionDate:  executionDate,
+		Status:         domain.PaymentQueued,
+		Reference:      reference,
+	}
+
+	op := domain.RiskOperation{Operation: domain.TransactionPayment, UserId: userId, Account: account, Amount: inp.Amount}
+	lines := []domain.Transaction{
+		{AccountId: account.Id, Amount: -inp.Amount, Type: domain.TransactionPayment, Description: inp.RemittanceInfo},
+	}
+
+	var created *domain.PaymentOrder
+	_, err = s.ledger.post(ctx, posting{
+		action:    domain.AuditAccountPayment,
+		op:        op,
+		lines:     lines,
+		fees:      result.Fees,
+		reference: reference,
+		within: func(ctx context.Context) error {
+			var err error
+			if created, err = s.repo.payment.Create(ctx, order); err != nil {
+				return err
+			}
+
+			return audit(ctx, s.repo.audit, domain.AuditPaymentCreate, domain.AuditEntityPayment, created.Id, nil, created)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *PaymentService) List(ctx context.Context, accountId int64) ([]domain.PaymentOrder, error) {
+	if _, err := s.repo.account.GetById(ctx, accountId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.payment.List(ctx, accountId)
+}
+
+// Export groups queued orders by currency and execution date into messages and writes
+// a pain.001 file for every exported message which has no file yet. Message ids are stored
+// before files are written. A file is written in the transaction which records it, so it's
+// never written again once recorded, even if it's already moved out of the directory; a file
+// written right before a failed commit is rewritten with the same message id and content.
+func (s *PaymentService) Export(ctx context.Context, now time.Time) error {
+	batches, err := s.repo.payment.ListQueuedBatches(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		messageId, err := newReference()
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.repo.payment.MarkExported(ctx, batch, messageId, now); err != nil {
+			return err
+		}
+	}
+
+	orders, err := s.repo.payment.ListUnwritten(ctx)
+	if err != nil {
+		return err
+	}
+
+	messages := make(map[string][]domain.PaymentOrder)
+	for _, o := range orders {
+		messages[o.MessageId] = append(messages[o.MessageId], o)
+	}
+
+	if err := os.MkdirAll(s.settings.Dir, 0o755); err != nil {
+		return err
+	}
+
+	for messageId, orders := range messages {
+		messageId, orders := messageId, orders
+
+		err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			n, err := s.repo.payment.MarkWritten(ctx, messageId, now)
+			if err != nil || n == 0 {
+				return err
+			}
+
+			return s.writeMessage(filepath.Join(s.settings.Dir, messageId+".xml"), messageId, orders)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PaymentService) writeMessage(path, messageId string, orders []domain.PaymentOrder) error {
+	first := orders[0]
+	payment := pain.Payment{
+		Id:            messageId,
+		Currency:      first.Currency,
+		ExecutionDate: first.ExecutionDate,
+	}
+
+	for _, o := range orders {
+		payment.Transactions = append(payment.Transactions, pain.Transaction{
+			EndToEndId:     o.Reference,
+			Amount:         o.Amount,
+			Creditor:       pain.Party{Name: o.CreditorName, IBAN: o.CreditorIban, BIC: o.CreditorBic},
+			RemittanceInfo: o.RemittanceInfo,
+		})
+	}
+
+	message := pain.Message{
+		Id:        messageId,
+		CreatedAt: *first.ExportedAt,
+		Initiator: s.settings.Initiator,
+		Debtor:    pain.Party{Name: s.settings.DebtorName, IBAN: s.settings.DebtorIban, BIC: s.settings.DebtorBic},
+		Payments:  []pain.Payment{payment},
+	}
+
+	data, err := message.Marshal()
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// ApplyStatusReport updates orders of the exported message by pain.002 report and refunds
+// rejected ones. It returns number of updated orders.
+func (s *PaymentService) ApplyStatusReport(ctx context.Context, report domain.PaymentStatusReport) (int, error) {
+	orders, err := s.repo.payment.ListByMessageId(ctx, report.MessageId)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(orders) == 0 {
+		return 0, domain.ErrNotExist
+	}
+
+	statuses := make(map[string]domain.PaymentTransactionStatus, len(report.Transactions))
+	for _, t := range report.Transactions {
+		statuses[t.Reference] = t
+	}
+
+	var updated int
+	for _, o := range orders {
+		code, reason := report.Status, report.Reason
+		if t, ok := statuses[o.Reference]; ok {
+			code, reason = t.Status, t.Reason
+		}
+
+		status, ok := paymentStatus(code)
+		if !ok || status == o.Status {
+			continue
+		}
+
+		changed, err := s.updateStatus(ctx, o, status, reason)
+		if err != nil {
+			return updated, err
+		}
+
+		if changed {
+			updated++
+		}
+	}
+
+	return updated, nil
+}
+
+func (s *PaymentService) updateStatus(ctx context.Context, o domain.PaymentOrder, status, reason string) (bool, error) {
+	var account *domain.Account
+
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.payment.UpdateStatus(ctx, o.Reference, status, reason); err != nil {
+			return err
+		}
+
+		before, after := map[string]string{"status": o.Status}, map[string]string{"status": status, "reason": reason}
+		if err := audit(ctx, s.repo.audit, domain.AuditPaymentStatus, domain.AuditEntityPayment, o.Id, before, after); err != nil {
+			return err
+		}
+
+		err := emit(ctx, s.repo.outbox, domain.AggregatePayment, o.Id, domain.EventPaymentStatusChanged, domain.PaymentStatusChanged{
+			PaymentId: o.Id,
+			AccountId: o.AccountId,
+			Reference: o.Reference,
+			Status:    status,
+			Reason:    reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		if status != domain.PaymentRejected {
+			return nil
+		}
+
+		account, err = s.repo.account.AddBalance(ctx, o.AccountId, o.Amount)
+		if err != nil {
+			return err
+		}
+
+		t, err := s.repo.transaction.Create(ctx, domain.Transaction{
+			AccountId:   o.AccountId,
+			Amount:      o.Amount,
+			Currency:    o.Currency,
+			Type:        domain.TransactionPaymentRefund,
+			Reference:   o.Reference,
+			Description: reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		return emitBalanceChanged(ctx, s.repo.outbox, account, t)
+	})
+	if errors.Is(err, domain.ErrNotExist) {
+		// order has been rejected already
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if account != nil {
+		s.cache.Delete(ctx, cacheKey(account.UserId, account.Id))
+		s.cache.Delete(ctx, cacheKey(account.UserId, listId))
+	}
+
+	return true, nil
+}
+
+// paymentStatus maps ISO 20022 payment status code to order status.
+func paymentStatus(code string) (string, bool) {
+	switch code {
+	case "ACCP", "ACSC", "ACSP", "ACTC", "ACWC", "ACCC":
+		return domain.PaymentAcknowledged, true
+	case "RJCT":
+		return domain.PaymentRejected, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+// PrivacyService serves data subject requests of the signed in user.
+type PrivacyService struct {
+	repo struct {
+		user        domain.UserRepository
+		token       domain.TokenRepository
+		account     domain.AccountRepository
+		transaction domain.TransactionRepository
+		payee       domain.PayeeRepository
+		audit       domain.AuditRepository
+		outbox      domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	mfa        MfaSettings
+}
+
+func NewPrivacyService(repos Repositories, mfa MfaSettings) *PrivacyService {
+	return &PrivacyService{
+		repo: struct {
+			user        domain.UserRepository
+			token       domain.TokenRepository
+			account     domain.AccountRepository
+			transaction domain.TransactionRepository
+			payee       domain.PayeeRepository
+			audit       domain.AuditRepository
+			outbox      domain.OutboxRepository
+		}{
+			user:        repos.GetUserRepository(),
+			token:       repos.GetTokenRepository(),
+			account:     repos.GetAccountRepository(),
+			transaction: repos.GetTransactionRepository(),
+			payee:       repos.GetPayeeRepository(),
+			audit:       repos.GetAuditRepository(),
+			outbox:      repos.GetOutboxRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		mfa:        mfa,
+	}
+}
+
+// Export collects profile, accounts with all their transactions, payees and sessions of the user.
+func (s *PrivacyService) Export(ctx context.Context) (*domain.UserExport, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+		return nil, err
+	}
+
+	var export domain.UserExport
+	var err error
+
+	if export.Profile, err = s.repo.user.GetById(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	if export.Accounts, err = s.repo.account.List(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, a := range export.Accounts {
+		transactions, err := s.repo.transaction.List(ctx, a.Id, time.Time{}, time.Now().Add(time.Second))
+		if err != nil {
+			return nil, err
+		}
+
+		export.Transactions = append(export.Transactions, transactions...)
+	}
+
+	if export.Payees, err = s.repo.payee.List(ctx); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.repo.token.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, domain.SessionExport{Id: session.ID, ExpiresAt: session.ExpiresAt})
+	}
+
+	if err := audit(ctx, s.repo.audit, domain.AuditUserExport, domain.AuditEntityUser, userId, nil, nil); err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// Erase pseudonymizes the user and signs them out everywhere. Accounts and ledger are kept
+// for the retention period, so it's refused while any account has money on it.
+func (s *PrivacyService) Erase(ctx context.Context) error {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return domain.ErrInvalidId
+	}
+
+	if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+		return err
+	}
+
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		accounts, err := s.repo.account.List(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range accounts {
+			if err := s.repo.account.Lock(ctx, a.Id); err != nil {
+				return err
+			}
+
+			// balance is read again after the lock, money could come in meanwhile
+			locked, err := s.repo.account.GetById(ctx, a.Id)
+			if err != nil {
+				return err
+			}
+
+			if locked.Balance != 0 {
+				return domain.ErrBalanceNotZero
+			}
+		}
+
+		if err := s.repo.user.Erase(ctx, userId); err != nil {
+			return err
+		}
+
+		if err := s.repo.token.DeleteByUser(ctx, userId); err != nil {
+			return err
+		}
+
+		if err := s.repo.outbox.ScrubUser(ctx, userId); err != nil {
+			return err
+		}
+
+		if err := emit(ctx, s.repo.outbox, domain.AggregateUser, userId, domain.EventUserErased, domain.UserEvent{UserId: userId}); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditUserErase, domain.AuditEntityUser, userId, nil, nil)
+	})
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *UserService) GetProfile(ctx context.Context) (*domain.User, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	return s.repo.user.GetById(ctx, userId)
+}
+
+func (s *UserService) UpdateProfile(ctx context.Context, input domain.UserUpdateInput) (*domain.User, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	var user *domain.User
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.user.GetById(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		if user, err = s.repo.user.UpdateById(ctx, userId, input); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditUserUpdate, domain.AuditEntityUser, userId, before, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ChangePassword sets new password after checking the old one and signs the user out of
+// all other sessions. New tokens are returned for the current one.
+func (s *UserService) ChangePassword(ctx context.Context, input domain.ChangePasswordInput) (string, string, error) {
+	user, err := s.checkPassword(ctx, input.OldPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	password, err := s.hasher.Hash(input.NewPassword)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.user.UpdatePassword(ctx, user.Id, password); err != nil {
+			return err
+		}
+
+		if err := s.repo.token.DeleteByUser(ctx, user.Id); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditUserChangePassword, domain.AuditEntityUser, user.Id, nil, nil)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var mfaAt *time.Time
+	if t, ok := ctx.Value(domain.MfaAtKey).(time.Time); ok {
+		mfaAt = &t
+	}
+
+	return s.generateTokens(ctx, user.Id, mfaAt)
+}
+
+// ChangeEmail sets new email of the user and sends verification link to it.
+// Until it's verified the user can't move money.
+func (s *UserService) ChangeEmail(ctx context.Context, input domain.ChangeEmailInput) error {
+	user, err := s.checkPassword(ctx, input.Password)
+	if err != nil {
+		return err
+	}
+
+	if user.Email == input.Email {
+		return nil
+	}
+
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.user.UpdateEmail(ctx, user.Id, input.Email); err != nil {
+			return err
+		}
+
+		before, after := map[string]string{"email": user.Email}, map[string]string{"email": input.Email}
+		return audit(ctx, s.repo.audit, domain.AuditUserChangeEmail, domain.AuditEntityUser, user.Id, before, after)
+	})
+	if err != nil {
+		return err
+	}
+
+	// user can request the link again, so failed delivery doesn't fail the change
+	if err := s.sendVerification(ctx, user.Id, input.Email); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context": "UserService.ChangeEmail()",
+			"problem": "can't send verification email",
+		}).Error(err)
+	}
+
+	return nil
+}
+
+// checkPassword returns signed in user if the password is right. Users with TOTP
+// enabled have to pass the second factor recently as well.
+func (s *UserService) checkPassword(ctx context.Context, password string) (*domain.User, error) {
+	user, err := s.GetProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(user.Password)) != 1 {
+		return nil, domain.ErrWrongPassword
+	}
+
+	if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
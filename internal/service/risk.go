@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/risk"
+)
+
+// RiskScreener consults risk engine before outgoing money movement is committed.
+// Screen is called within the transaction of the operation, so an approval used by it is
+// rolled back with the operation. Challenged or blocked operation is rolled back, and then
+// Hold puts its review to the queue.
+type RiskScreener interface {
+	Screen(ctx context.Context, op domain.RiskOperation) (*domain.RiskReview, error)
+	Hold(ctx context.Context, review domain.RiskReview) error
+}
+
+// RuleRiskEngine is the built-in risk engine evaluating configurable rules against account history.
+type RuleRiskEngine struct {
+	repo struct {
+		user        domain.UserRepository
+		transaction domain.TransactionRepository
+	}
+	rules risk.Rules
+}
+
+func NewRuleRiskEngine(repos Repositories, rules risk.Rules) *RuleRiskEngine {
+	return &RuleRiskEngine{
+		repo: struct {
+			user        domain.UserRepository
+			transaction domain.TransactionRepository
+		}{
+			user:        repos.GetUserRepository(),
+			transaction: repos.GetTransactionRepository(),
+		},
+		rules: rules,
+	}
+}
+
+func (e *RuleRiskEngine) Assess(ctx context.Context, op domain.RiskOperation) (*domain.RiskAssessment, error) {
+	var err error
+
+	now := time.Now()
+	facts := risk.Facts{Amount: op.Amount}
+
+	if e.rules.Velocity.Decision != "" {
+		facts.RecentCount, _, err = e.repo.transaction.CountOutgoing(ctx, op.Account.Id, now.Add(-e.rules.Velocity.Window))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.rules.NewPayee.Decision != "" && op.ToAccount != nil {
+		known, err := e.repo.transaction.HasTransferred(ctx, op.Account.Id, op.ToAccount.Id)
+		if err != nil {
+			return nil, err
+		}
+		facts.NewPayee = !known
+	}
+
+	if e.rules.AverageMultiple.Decision != "" {
+		facts.HistoryCount, facts.HistorySum, err = e.repo.transaction.CountOutgoing(ctx, op.Account.Id, now.Add(-risk.AverageWindow))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.rules.PasswordChange.Decision != "" {
+		user, err := e.repo.user.GetById(ctx, op.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		if user.PasswordChangedAt != nil {
+			facts.PasswordChangedAt = *user.PasswordChangedAt
+			facts.OperationsSincePasswordChange, _, err = e.repo.transaction.CountOutgoing(ctx, op.Account.Id, *user.PasswordChangedAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	a := e.rules.Evaluate(facts, now)
+
+	return &domain.RiskAssessment{Decision: a.Decision, Rules: a.Rules}, nil
+}
+
+type RiskService struct {
+	repo struct {
+		risk  domain.RiskRepository
+		audit domain.AuditRepository
+	}
+	transactor domain.Transactor
+	engine     domain.RiskEngine
+}
+
+func NewRiskService(repos Repositories, engine domain.RiskEngine) *RiskService {
+	return &RiskService{
+		repo: struct {
+			risk  domain.RiskRepository
+			audit domain.AuditRepository
+		}{
+			risk:  repos.GetRiskRepository(),
+			audit: repos.GetAuditRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		engine:     engine,
+	}
+}
+
+// Screen returns review of the operation if it is challenged or blocked, nil if it is allowed.
+// Operation approved by admin recently is allowed once. Call it within transaction of the operation.
+func (s *RiskService) Screen(ctx context.Context, op domain.RiskOperation) (*domain.RiskReview, error) {
+	assessment, err := s.engine.Assess(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	if assessment.Decision == risk.Allow {
+		return nil, nil
+	}
+
+	approved, err := s.repo.risk.UseApproved(ctx, op, time.Now().Add(-domain.RiskApprovalTTL))
+	if err != nil || approved {
+		return nil, err
+	}
+
+	review := domain.RiskReview{
+		UserId:    op.UserId,
+		AccountId: op.Account.Id,
+		Operation: op.Operation,
+		Amount:    op.Amount,
+		Currency:  op.Account.Currency,
+		Decision:  assessment.Decision,
+		Rules:     assessment.Rules,
+		Status:    domain.RiskReviewPending,
+	}
+	if op.ToAccount != nil {
+		review.ToAccountId = &op.ToAccount.Id
+	}
+
+	return &review, nil
+}
+
+// Hold puts the review to the queue and returns RiskError. Call it after the operation
+// is rolled back.
+func (s *RiskService) Hold(ctx context.Context, review domain.RiskReview) error {
+	created, err := s.repo.risk.Create(ctx, review)
+	if err != nil {
+		return err
+	}
+
+	return &domain.RiskError{Decision: created.Decision, Rules: created.Rules, ReviewId: created.Id}
+}
+
+func (s *RiskService) List(ctx context.Context, status string) ([]domain.RiskReview, error) {
+	return s.repo.risk.List(ctx, status)
+}
+
+func (s *RiskService) Approve(ctx context.Context, id int64) (*domain.RiskReview, error) {
+	return s.resolve(ctx, id, domain.RiskReviewApproved, domain.AuditRiskReviewApprove)
+}
+
+func (s *RiskService) Reject(ctx context.Context, id int64) (*domain.RiskReview, error) {
+	return s.resolve(ctx, id, domain.RiskReviewRejected, domain.AuditRiskReviewReject)
+}
+
+func (s *RiskService) resolve(ctx context.Context, id int64, status, action string) (*domain.RiskReview, error) {
+	reviewer, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	var review *domain.RiskReview
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if review, err = s.repo.risk.Resolve(ctx, id, status, reviewer); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, action, domain.AuditEntityRiskReview, id, nil, review)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
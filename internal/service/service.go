@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"time"
 
 	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/fee"
 	cache "github.com/Viquad/simple-cache"
 )
 
@@ -11,15 +13,83 @@ type Repositories interface {
 	GetAccountRepository() domain.AccountRepository
 	GetUserRepository() domain.UserRepository
 	GetTokenRepository() domain.TokenRepository
+	GetTransactionRepository() domain.TransactionRepository
+	GetInterestRepository() domain.InterestRepository
+	GetStatementRepository() domain.StatementRepository
+	GetPaymentRepository() domain.PaymentRepository
+	GetPayeeRepository() domain.PayeeRepository
+	GetLimitRepository() domain.LimitRepository
+	GetRiskRepository() domain.RiskRepository
+	GetMfaRepository() domain.MfaRepository
+	GetPasswordResetRepository() domain.PasswordResetRepository
+	GetAuditRepository() domain.AuditRepository
+	GetOutboxRepository() domain.OutboxRepository
+	GetWebhookRepository() domain.WebhookRepository
+	GetTransactor() domain.Transactor
 }
 
 type PasswordHasher interface {
 	Hash(password string) (string, error)
 }
 
+type FeeCalculator interface {
+	Calculate(operation, currency, tier string, amount int64) []fee.Charge
+	IncomeAccount(currency string) (int64, bool)
+}
+
+// LimitChecker checks outgoing money movements against limits of the account and its owner.
+type LimitChecker interface {
+	Lock(ctx context.Context, account *domain.Account) error
+	Check(ctx context.Context, account *domain.Account, amount int64) error
+}
+
+// MetricsRecorder counts events of services for monitoring.
+type MetricsRecorder interface {
+	CacheLookup(cache string, hit bool)
+	SignIn(method, result string)
+}
+
+type Deps struct {
+	Repos           Repositories
+	Cache           cache.Cache
+	Hasher          PasswordHasher
+	Mailer          Mailer
+	Publisher       EventPublisher
+	WebhookSender   WebhookSender
+	Fees            FeeCalculator
+	InterestRates   InterestRates
+	PaymentExport   PaymentExportSettings
+	Iban            IbanSettings
+	Limits          LimitDefaults
+	UserLimits      LimitDefaults
+	RiskEngine      domain.RiskEngine
+	Mfa             MfaSettings
+	Email           EmailSettings
+	Outbox          OutboxSettings
+	Webhooks        WebhookSettings
+	Stream          StreamSettings
+	Metrics         MetricsRecorder
+	HmacSecret      []byte
+	CacheTTL        time.Duration
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
 type Services struct {
-	accountService *AccountService
-	userService    *UserService
+	accountService   domain.AccountService
+	userService      domain.UserService
+	interestService  *InterestService
+	statementService *StatementService
+	importService    *ImportService
+	paymentService   *PaymentService
+	payeeService     *PayeeService
+	limitService     *LimitService
+	riskService      *RiskService
+	privacyService   *PrivacyService
+	auditService     *AuditService
+	outboxService    *OutboxService
+	webhookService   *WebhookService
+	streamService    *StreamService
 }
 
 func (ss *Services) GetAccountService() domain.AccountService {
@@ -30,9 +100,73 @@ func (ss *Services) GetUserService() domain.UserService {
 	return ss.userService
 }
 
-func NewServices(repo Repositories, cache cache.Cache, hasher PasswordHasher, secret []byte, cachettl, accessttl, refreshttl time.Duration) *Services {
+func (ss *Services) GetInterestService() domain.InterestService {
+	return ss.interestService
+}
+
+func (ss *Services) GetStatementService() domain.StatementService {
+	return ss.statementService
+}
+
+func (ss *Services) GetImportService() domain.ImportService {
+	return ss.importService
+}
+
+func (ss *Services) GetPaymentService() domain.PaymentService {
+	return ss.paymentService
+}
+
+func (ss *Services) GetPayeeService() domain.PayeeService {
+	return ss.payeeService
+}
+
+func (ss *Services) GetLimitService() domain.LimitService {
+	return ss.limitService
+}
+
+func (ss *Services) GetRiskService() domain.RiskService {
+	return ss.riskService
+}
+
+func (ss *Services) GetPrivacyService() domain.PrivacyService {
+	return ss.privacyService
+}
+
+func (ss *Services) GetAuditService() domain.AuditService {
+	return ss.auditService
+}
+
+func (ss *Services) GetOutboxService() domain.OutboxService {
+	return ss.outboxService
+}
+
+func (ss *Services) GetWebhookService() domain.WebhookService {
+	return ss.webhookService
+}
+
+func (ss *Services) GetStreamService() domain.StreamService {
+	return ss.streamService
+}
+
+func NewServices(deps Deps) *Services {
+	limitService := NewLimitService(deps.Repos, deps.Limits, deps.UserLimits)
+	riskService := NewRiskService(deps.Repos, deps.RiskEngine)
+	webhookService := NewWebhookService(deps.Repos, deps.WebhookSender, deps.Mfa, deps.Webhooks)
+
 	return &Services{
-		accountService: NewAccountService(repo, cache, cachettl),
-		userService:    NewUserService(repo, hasher, secret, accessttl, refreshttl),
+		accountService:   &tracedAccountService{NewAccountService(deps.Repos, deps.Cache, deps.Fees, limitService, riskService, deps.Metrics, deps.Mfa, deps.Iban, deps.CacheTTL)},
+		userService:      &tracedUserService{NewUserService(deps.Repos, deps.Hasher, deps.Mailer, deps.Metrics, deps.Mfa, deps.Email, deps.HmacSecret, deps.AccessTokenTTL, deps.RefreshTokenTTL)},
+		interestService:  NewInterestService(deps.Repos, deps.Cache, deps.InterestRates),
+		statementService: NewStatementService(deps.Repos),
+		importService:    NewImportService(deps.Repos, deps.Cache),
+		paymentService:   NewPaymentService(deps.Repos, deps.Cache, deps.Fees, limitService, riskService, deps.Mfa, deps.PaymentExport),
+		payeeService:     NewPayeeService(deps.Repos, deps.Mfa),
+		limitService:     limitService,
+		riskService:      riskService,
+		privacyService:   NewPrivacyService(deps.Repos, deps.Mfa),
+		auditService:     NewAuditService(deps.Repos),
+		outboxService:    NewOutboxService(deps.Repos, deps.Publisher, deps.Outbox, webhookService),
+		webhookService:   webhookService,
+		streamService:    NewStreamService(deps.Repos, deps.Stream),
 	}
 }
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type StatementService struct {
+	repo struct {
+		account     domain.AccountRepository
+		statement   domain.StatementRepository
+		transaction domain.TransactionRepository
+	}
+}
+
+func NewStatementService(repos Repositories) *StatementService {
+	return &StatementService{
+		repo: struct {
+			account     domain.AccountRepository
+			statement   domain.StatementRepository
+			transaction domain.TransactionRepository
+		}{
+			account:     repos.GetAccountRepository(),
+			statement:   repos.GetStatementRepository(),
+			transaction: repos.GetTransactionRepository(),
+		},
+	}
+}
+
+func (s *StatementService) List(ctx context.Context, accountId int64) ([]domain.StatementSummary, error) {
+	if _, err := s.repo.account.GetById(ctx, accountId); err != nil {
+		return nil, err
+	}
+
+	return s.repo.statement.List(ctx, accountId)
+}
+
+// Get returns statement of user's account for the period. Statements of finished periods
+// are stored on first request, statement of the current month is always built from scratch.
+// There are no statements for months before the account was created.
+func (s *StatementService) Get(ctx context.Context, accountId int64, period string) (*domain.Statement, error) {
+	from, to, err := parsePeriod(period)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.repo.account.GetById(ctx, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if from.After(now) {
+		return nil, domain.ErrInvalidPeriod
+	}
+
+	if !to.After(account.CreatedAt) {
+		return nil, domain.ErrNotExist
+	}
+
+	if to.After(now) {
+		return s.generate(ctx, account, from, to, now)
+	}
+
+	statement, err := s.repo.statement.Get(ctx, accountId, period)
+	if !errors.Is(err, domain.ErrNotExist) {
+		return statement, err
+	}
+
+	statement, err = s.generate(ctx, account, from, to, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return statement, s.repo.statement.Create(ctx, *statement)
+}
+
+// Run generates statements for the previous month for every account which doesn't have one yet.
+func (s *StatementService) Run(ctx context.Context, now time.Time) error {
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -1, 0)
+	period := from.Format(domain.StatementPeriodLayout)
+
+	ids, err := s.repo.statement.ListAccountsWithout(ctx, period, to)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		account, err := s.repo.account.Lookup(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		statement, err := s.generate(ctx, account, from, to, now)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.statement.Create(ctx, *statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// generate computes balances of the period from the ledger, so they don't depend on changes
+// made after the period.
+func (s *StatementService) generate(ctx context.Context, account *domain.Account, from, to, now time.Time) (*domain.Statement, error) {
+	closing, err := s.repo.transaction.SumBefore(ctx, account.Id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.repo.transaction.List(ctx, account.Id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := domain.Statement{
+		AccountId:      account.Id,
+		Type:           account.Type,
+		Currency:       account.Currency,
+		Period:         from.Format(domain.StatementPeriodLayout),
+		From:           from,
+		To:             to,
+		ClosingBalance: closing,
+		Lines:          make([]domain.StatementLine, 0, len(transactions)),
+		GeneratedAt:    now,
+	}
+
+	statement.OpeningBalance = statement.ClosingBalance
+	for _, t := range transactions {
+		statement.OpeningBalance -= t.Amount
+	}
+
+	balance := statement.OpeningBalance
+	for _, t := range transactions {
+		balance += t.Amount
+		statement.Lines = append(statement.Lines, domain.StatementLine{Transaction: t, Balance: balance})
+	}
+
+	return &statement, nil
+}
+
+func parsePeriod(period string) (time.Time, time.Time, error) {
+	from, err := time.Parse(domain.StatementPeriodLayout, period)
+	if err != nil {
+		return time.Time{}, time.Time{}, domain.ErrInvalidPeriod
+	}
+
+	return from, from.AddDate(0, 1, 0), nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// StreamSettings configure live events. Buffer is the number of events kept for a slow
+// client; when it's full, the client is disconnected and has to resume by the last event id.
+// ReplayBatch is the page size of events read on resume. Events created within ReplayWindow
+// before the last received one are sent again on resume, as they could be committed after it;
+// the window must be longer than the longest transaction.
+type StreamSettings struct {
+	Buffer       int
+	ReplayBatch  int
+	ReplayWindow time.Duration
+}
+
+type subscriber struct {
+	events chan domain.Event
+}
+
+// StreamService fans events of accounts out to subscribed clients of their owners.
+// Events reach it from the outbox listener, so events of all replicas are delivered.
+type StreamService struct {
+	repo struct {
+		outbox domain.OutboxRepository
+	}
+	settings StreamSettings
+
+	mu          sync.Mutex
+	subscribers map[int64]map[*subscriber]struct{}
+	closed      bool
+}
+
+func NewStreamService(repos Repositories, settings StreamSettings) *StreamService {
+	return &StreamService{
+		repo: struct {
+			outbox domain.OutboxRepository
+		}{
+			outbox: repos.GetOutboxRepository(),
+		},
+		settings:    settings,
+		subscribers: make(map[int64]map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe returns events of user's accounts: stored ones with id greater than lastEventId
+// or within the replay window before it, if it's set, and then new ones. Clients may get an
+// event twice and should skip ids they have seen. The channel is closed when ctx is done or
+// the client falls behind.
+func (s *StreamService) Subscribe(ctx context.Context, lastEventId int64) (<-chan domain.Event, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	// subscribe before reading stored events, so nothing is lost in between
+	sub := &subscriber{events: make(chan domain.Event, s.settings.Buffer)}
+	s.subscribe(userId, sub)
+
+	var replay []domain.Event
+	if lastEventId > 0 {
+		var err error
+		if replay, err = s.replay(ctx, userId, lastEventId); err != nil {
+			s.unsubscribe(userId, sub)
+			return nil, err
+		}
+	}
+
+	out := make(chan domain.Event)
+	go func() {
+		defer close(out)
+		defer s.unsubscribe(userId, sub)
+
+		replayed := make(map[int64]bool, len(replay))
+		for _, e := range replay {
+			select {
+			case out <- e:
+				replayed[e.Id] = true
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case e, ok := <-sub.events:
+				if !ok {
+					return
+				}
+
+				if replayed[e.Id] {
+					continue
+				}
+
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// replay returns stored events to resume the stream after lastEventId.
+func (s *StreamService) replay(ctx context.Context, userId, lastEventId int64) ([]domain.Event, error) {
+	since := time.Now().UTC()
+	last, err := s.repo.outbox.GetById(ctx, lastEventId)
+	if err == nil {
+		since = last.CreatedAt
+	} else if !errors.Is(err, domain.ErrNotExist) {
+		return nil, err
+	}
+	since = since.Add(-s.settings.ReplayWindow)
+
+	var replay []domain.Event
+	for afterId := int64(0); ; {
+		events, err := s.repo.outbox.ListAccountEvents(ctx, userId, lastEventId, since, afterId, s.settings.ReplayBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			if e.Id != lastEventId {
+				replay = append(replay, e)
+			}
+		}
+
+		if len(events) < s.settings.ReplayBatch {
+			return replay, nil
+		}
+		afterId = events[len(events)-1].Id
+	}
+}
+
+// Dispatch sends the event to subscribers of the owner if it's an event of an account.
+func (s *StreamService) Dispatch(ctx context.Context, eventId int64) {
+	s.mu.Lock()
+	idle := len(s.subscribers) == 0
+	s.mu.Unlock()
+
+	if idle {
+		return
+	}
+
+	e, err := s.repo.outbox.GetById(ctx, eventId)
+	if err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context":  "StreamService.Dispatch()",
+			"problem":  "can't get event",
+			"event_id": eventId,
+		}).Warn(err.Error())
+		return
+	}
+
+	if e.AggregateType != domain.AggregateAccount {
+		return
+	}
+
+	var owner struct {
+		UserId int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(e.Payload, &owner); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers[owner.UserId] {
+		select {
+		case sub.events <- *e:
+		default:
+			// client is too slow, it resumes from the last received event
+			close(sub.events)
+			delete(s.subscribers[owner.UserId], sub)
+		}
+	}
+
+	if len(s.subscribers[owner.UserId]) == 0 {
+		delete(s.subscribers, owner.UserId)
+	}
+}
+
+// Close ends all streams and the ones subscribed later, so clients reconnect to another
+// instance on shutdown.
+func (s *StreamService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for userId, subs := range s.subscribers {
+		for sub := range subs {
+			close(sub.events)
+		}
+		delete(s.subscribers, userId)
+	}
+}
+
+func (s *StreamService) subscribe(userId int64, sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		close(sub.events)
+		return
+	}
+
+	if s.subscribers[userId] == nil {
+		s.subscribers[userId] = make(map[*subscriber]struct{})
+	}
+	s.subscribers[userId][sub] = struct{}{}
+}
+
+func (s *StreamService) unsubscribe(userId int64, sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscribers[userId][sub]; !ok {
+		return
+	}
+
+	delete(s.subscribers[userId], sub)
+	if len(s.subscribers[userId]) == 0 {
+		delete(s.subscribers, userId)
+	}
+}
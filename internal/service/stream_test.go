@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+// fakeOutboxRepository keeps events in memory in order of id. Methods not used by streams panic.
+type fakeOutboxRepository struct {
+	domain.OutboxRepository
+
+	events []domain.Event
+}
+
+func (r *fakeOutboxRepository) add(id, userId int64, createdAt time.Time) {
+	r.events = append(r.events, domain.Event{
+		Id:            id,
+		AggregateType: domain.AggregateAccount,
+		AggregateId:   strconv.FormatInt(id, 10),
+		Type:          domain.EventBalanceChanged,
+		Payload:       json.RawMessage(`{"user_id":` + strconv.FormatInt(userId, 10) + `}`),
+		CreatedAt:     createdAt,
+	})
+}
+
+func (r *fakeOutboxRepository) GetById(ctx context.Context, id int64) (*domain.Event, error) {
+	for _, e := range r.events {
+		if e.Id == id {
+			e := e
+			return &e, nil
+		}
+	}
+
+	return nil, domain.ErrNotExist
+}
+
+func (r *fakeOutboxRepository) ListAccountEvents(ctx context.Context, userId, lastEventId int64, since time.Time, afterId int64, limit int) ([]domain.Event, error) {
+	var events []domain.Event
+	for _, e := range r.events {
+		var owner struct {
+			UserId int64 `json:"user_id"`
+		}
+		if err := json.Unmarshal(e.Payload, &owner); err != nil {
+			return nil, err
+		}
+
+		if owner.UserId != userId || e.Id <= afterId || (e.Id <= lastEventId && e.CreatedAt.Before(since)) {
+			continue
+		}
+		if len(events) == limit {
+			break
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+func newTestStreamService(repo *fakeOutboxRepository) *StreamService {
+	s := &StreamService{
+		settings:    StreamSettings{Buffer: 8, ReplayBatch: 2, ReplayWindow: time.Minute},
+		subscribers: make(map[int64]map[*subscriber]struct{}),
+	}
+	s.repo.outbox = repo
+
+	return s
+}
+
+// receive returns ids of n events from the stream.
+func receive(t *testing.T, events <-chan domain.Event, n int) []int64 {
+	t.Helper()
+
+	var ids []int64
+	for len(ids) < n {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed after %v", ids)
+			}
+			ids = append(ids, e.Id)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v, want %d events", ids, n)
+		}
+	}
+
+	return ids
+}
+
+func equalIds(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestSubscribeReplaysEventsCommittedOutOfOrder(t *testing.T) {
+	last := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	repo := &fakeOutboxRepository{}
+	repo.add(1, 1, last.Add(-time.Hour))
+	repo.add(2, 1, last.Add(-30*time.Second)) // committed after the client got event 4
+	repo.add(3, 2, last.Add(-20*time.Second))
+	repo.add(4, 1, last)
+	repo.add(5, 1, last.Add(time.Second))
+	repo.add(6, 1, last.Add(2*time.Second))
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), domain.UserIdKey, int64(1)))
+	defer cancel()
+
+	events, err := newTestStreamService(repo).Subscribe(ctx, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := receive(t, events, 3), []int64{2, 5, 6}; !equalIds(got, want) {
+		t.Fatalf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeUnknownLastEvent(t *testing.T) {
+	repo := &fakeOutboxRepository{}
+	repo.add(1, 1, time.Now().UTC().Add(-time.Hour))
+	repo.add(8, 1, time.Now().UTC())
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), domain.UserIdKey, int64(1)))
+	defer cancel()
+
+	events, err := newTestStreamService(repo).Subscribe(ctx, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := receive(t, events, 1), []int64{8}; !equalIds(got, want) {
+		t.Fatalf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeSkipsReplayedEvents(t *testing.T) {
+	now := time.Now().UTC()
+
+	repo := &fakeOutboxRepository{}
+	repo.add(1, 1, now)
+	repo.add(2, 1, now)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), domain.UserIdKey, int64(1)))
+	defer cancel()
+
+	s := newTestStreamService(repo)
+	events, err := s.Subscribe(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// event 2 is replayed and also notified, as it was committed while subscribing
+	s.Dispatch(ctx, 2)
+	repo.add(3, 1, now)
+	s.Dispatch(ctx, 3)
+
+	if got, want := receive(t, events, 2), []int64{2, 3}; !equalIds(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	cache "github.com/Viquad/simple-cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Viquad/crud-app/internal/service")
+
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedCache wraps cache with spans, so it takes ctx of the caller.
+type tracedCache struct {
+	cache cache.Cache
+}
+
+func newTracedCache(c cache.Cache) *tracedCache {
+	return &tracedCache{c}
+}
+
+func (c *tracedCache) Get(ctx context.Context, key string) (interface{}, error) {
+	_, span := startSpan(ctx, "cache.Get", attribute.String("cache.key", key))
+	defer span.End()
+
+	value, err := c.cache.Get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", err == nil))
+
+	return value, err
+}
+
+func (c *tracedCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	_, span := startSpan(ctx, "cache.Set", attribute.String("cache.key", key))
+	err := c.cache.Set(key, value, ttl)
+	endSpan(span, err)
+
+	return err
+}
+
+func (c *tracedCache) Delete(ctx context.Context, key string) error {
+	_, span := startSpan(ctx, "cache.Delete", attribute.String("cache.key", key))
+	defer span.End()
+
+	// missed key is not a failure of delete
+	return c.cache.Delete(key)
+}
+
+// tracedAccountService starts a span for each method of AccountService.
+type tracedAccountService struct {
+	next domain.AccountService
+}
+
+func (s *tracedAccountService) Create(ctx context.Context, inp domain.AccountCreateInput) (account *domain.Account, err error) {
+	ctx, span := startSpan(ctx, "AccountService.Create")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Create(ctx, inp)
+}
+
+func (s *tracedAccountService) List(ctx context.Context) (accounts []domain.Account, err error) {
+	ctx, span := startSpan(ctx, "AccountService.List")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.List(ctx)
+}
+
+func (s *tracedAccountService) GetById(ctx context.Context, id int64) (account *domain.Account, err error) {
+	ctx, span := startSpan(ctx, "AccountService.GetById", attribute.Int64("account.id", id))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetById(ctx, id)
+}
+
+func (s *tracedAccountService) ListByIds(ctx context.Context, ids []int64) (accounts []domain.Account, err error) {
+	ctx, span := startSpan(ctx, "AccountService.ListByIds", attribute.Int("account.count", len(ids)))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ListByIds(ctx, ids)
+}
+
+func (s *tracedAccountService) ListRecentTransactions(ctx context.Context, ids []int64, limit int) (transactions []domain.Transaction, err error) {
+	ctx, span := startSpan(ctx, "AccountService.ListRecentTransactions", attribute.Int("account.count", len(ids)))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ListRecentTransactions(ctx, ids, limit)
+}
+
+func (s *tracedAccountService) UpdateById(ctx context.Context, id int64, inp domain.AccountUpdateInput) (account *domain.Account, err error) {
+	ctx, span := startSpan(ctx, "AccountService.UpdateById", attribute.Int64("account.id", id))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.UpdateById(ctx, id, inp)
+}
+
+func (s *tracedAccountService) DeleteById(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "AccountService.DeleteById", attribute.Int64("account.id", id))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.DeleteById(ctx, id)
+}
+
+func (s *tracedAccountService) Transfer(ctx context.Context, id int64, inp domain.TransferInput, dryRun bool) (result *domain.TransactionResult, err error) {
+	ctx, span := startSpan(ctx, "AccountService.Transfer", attribute.Int64("account.id", id), attribute.Bool("dry_run", dryRun))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Transfer(ctx, id, inp, dryRun)
+}
+
+func (s *tracedAccountService) Withdraw(ctx context.Context, id int64, inp domain.WithdrawInput, dryRun bool) (result *domain.TransactionResult, err error) {
+	ctx, span := startSpan(ctx, "AccountService.Withdraw", attribute.Int64("account.id", id), attribute.Bool("dry_run", dryRun))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Withdraw(ctx, id, inp, dryRun)
+}
+
+func (s *tracedAccountService) LookupByIban(ctx context.Context, iban string) (lookup *domain.AccountLookup, err error) {
+	ctx, span := startSpan(ctx, "AccountService.LookupByIban")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.LookupByIban(ctx, iban)
+}
+
+func (s *tracedAccountService) AssignIbans(ctx context.Context) (n int, err error) {
+	ctx, span := startSpan(ctx, "AccountService.AssignIbans")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.AssignIbans(ctx)
+}
+
+// tracedUserService starts a span for each method of UserService.
+type tracedUserService struct {
+	next domain.UserService
+}
+
+func (s *tracedUserService) Create(ctx context.Context, input domain.SignUpInput) (err error) {
+	ctx, span := startSpan(ctx, "UserService.Create")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.Create(ctx, input)
+}
+
+func (s *tracedUserService) GetTokenByCredentials(ctx context.Context, input domain.SignInInput) (access string, refresh string, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetTokenByCredentials")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetTokenByCredentials(ctx, input)
+}
+
+func (s *tracedUserService) ParseToken(ctx context.Context, token string) (claims *domain.AccessClaims, err error) {
+	ctx, span := startSpan(ctx, "UserService.ParseToken")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ParseToken(ctx, token)
+}
+
+func (s *tracedUserService) RefreshTokens(ctx context.Context, token string) (access string, refresh string, err error) {
+	ctx, span := startSpan(ctx, "UserService.RefreshTokens")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.RefreshTokens(ctx, token)
+}
+
+func (s *tracedUserService) GetById(ctx context.Context, id int64) (user *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetById", attribute.Int64("user.id", id))
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetById(ctx, id)
+}
+
+func (s *tracedUserService) EnrollTotp(ctx context.Context) (enrollment *domain.TotpEnrollment, err error) {
+	ctx, span := startSpan(ctx, "UserService.EnrollTotp")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.EnrollTotp(ctx)
+}
+
+func (s *tracedUserService) ConfirmTotp(ctx context.Context, code string) (recoveryCodes []string, err error) {
+	ctx, span := startSpan(ctx, "UserService.ConfirmTotp")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ConfirmTotp(ctx, code)
+}
+
+func (s *tracedUserService) SignInWithMfa(ctx context.Context, input domain.MfaSignInInput) (access string, refresh string, err error) {
+	ctx, span := startSpan(ctx, "UserService.SignInWithMfa")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.SignInWithMfa(ctx, input)
+}
+
+func (s *tracedUserService) StepUp(ctx context.Context, code string) (access string, refresh string, err error) {
+	ctx, span := startSpan(ctx, "UserService.StepUp")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.StepUp(ctx, code)
+}
+
+func (s *tracedUserService) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := startSpan(ctx, "UserService.VerifyEmail")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.VerifyEmail(ctx, token)
+}
+
+func (s *tracedUserService) ResendVerification(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "UserService.ResendVerification")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ResendVerification(ctx)
+}
+
+func (s *tracedUserService) ForgotPassword(ctx context.Context, input domain.ForgotPasswordInput) (err error) {
+	ctx, span := startSpan(ctx, "UserService.ForgotPassword")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ForgotPassword(ctx, input)
+}
+
+// SendPasswordResets runs in background, every reset it sends is traced by the request.
+func (s *tracedUserService) SendPasswordResets(ctx context.Context) error {
+	return s.next.SendPasswordResets(ctx)
+}
+
+func (s *tracedUserService) ResetPassword(ctx context.Context, input domain.ResetPasswordInput) (err error) {
+	ctx, span := startSpan(ctx, "UserService.ResetPassword")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ResetPassword(ctx, input)
+}
+
+func (s *tracedUserService) GetProfile(ctx context.Context) (user *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.GetProfile")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.GetProfile(ctx)
+}
+
+func (s *tracedUserService) UpdateProfile(ctx context.Context, input domain.UserUpdateInput) (user *domain.User, err error) {
+	ctx, span := startSpan(ctx, "UserService.UpdateProfile")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.UpdateProfile(ctx, input)
+}
+
+func (s *tracedUserService) ChangePassword(ctx context.Context, input domain.ChangePasswordInput) (access string, refresh string, err error) {
+	ctx, span := startSpan(ctx, "UserService.ChangePassword")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ChangePassword(ctx, input)
+}
+
+func (s *tracedUserService) ChangeEmail(ctx context.Context, input domain.ChangeEmailInput) (err error) {
+	ctx, span := startSpan(ctx, "UserService.ChangeEmail")
+	defer func() { endSpan(span, err) }()
+
+	return s.next.ChangeEmail(ctx, input)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/fee"
+)
+
+func (s *AccountService) Transfer(ctx context.Context, id int64, inp domain.TransferInput, dryRun bool) (*domain.TransactionResult, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	if err := requireVerifiedEmail(ctx, s.repo.user); err != nil {
+		return nil, err
+	}
+
+	from, err := s.repo.account.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		to   *domain.Account
+		name = inp.PayeeName
+	)
+	switch {
+	case inp.ToPayeeId != 0:
+		var payee *domain.Payee
+		if payee, err = s.repo.payee.GetById(ctx, inp.ToPayeeId); err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = payee.Name
+		}
+		to, err = s.getByIban(ctx, payee.Iban)
+	case inp.ToIban != "":
+		to, err = s.getByIban(ctx, inp.ToIban)
+	default:
+		// ids are sequential, so only own accounts are found by id; others are reached by IBAN
+		to, err = s.repo.account.GetById(ctx, inp.ToAccountId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if from.Id == to.Id {
+		return nil, domain.ErrSameAccount
+	}
+
+	if from.Currency != to.Currency {
+		return nil, domain.ErrCurrencyMismatch
+	}
+
+	if err := s.limits.Check(ctx, from, inp.Amount); err != nil {
+		return nil, err
+	}
+
+	result, err := s.ledger.preview(ctx, userId, fee.OperationTransfer, from.Currency, inp.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != "" {
+		if result.NameCheck, err = confirmPayee(ctx, s.repo.user, to, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := s.requireMfaForAmount(ctx, inp.Amount); err != nil {
+		return nil, err
+	}
+
+	op := domain.RiskOperation{Operation: domain.TransactionTransfer, UserId: userId, Account: from, ToAccount: to, Amount: inp.Amount}
+	lines := []domain.Transaction{
+		{AccountId: from.Id, Amount: -inp.Amount, Type: domain.TransactionTransfer, Description: inp.Description},
+		{AccountId: to.Id, Amount: inp.Amount, Type: domain.TransactionTransfer, Description: inp.Description},
+	}
+
+	result.DryRun = false
+	result.Transactions, err = s.ledger.post(ctx, posting{action: domain.AuditAccountTransfer, op: op, lines: lines, fees: result.Fees})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *AccountService) Withdraw(ctx context.Context, id int64, inp domain.WithdrawInput, dryRun bool) (*domain.TransactionResult, error) {
+	userId, ok := ctx.Value(domain.UserIdKey).(int64)
+	if !ok {
+		return nil, domain.ErrInvalidId
+	}
+
+	if err := requireVerifiedEmail(ctx, s.repo.user); err != nil {
+		return nil, err
+	}
+
+	from, err := s.repo.account.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.limits.Check(ctx, from, inp.Amount); err != nil {
+		return nil, err
+	}
+
+	result, err := s.ledger.preview(ctx, userId, fee.OperationWithdrawal, from.Currency, inp.Amount)
+	if err != nil || dryRun {
+		return result, err
+	}
+
+	if err := s.requireMfaForAmount(ctx, inp.Amount); err != nil {
+		return nil, err
+	}
+
+	op := domain.RiskOperation{Operation: domain.TransactionWithdrawal, UserId: userId, Account: from, Amount: inp.Amount}
+	lines := []domain.Transaction{
+		{AccountId: from.Id, Amount: -inp.Amount, Type: domain.TransactionWithdrawal, Description: inp.Description},
+	}
+
+	result.DryRun = false
+	result.Transactions, err = s.ledger.post(ctx, posting{action: domain.AuditAccountWithdraw, op: op, lines: lines, fees: result.Fees})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// requireMfaForAmount requires recent second factor for money moved out from the large
+// transfer amount.
+func (s *AccountService) requireMfaForAmount(ctx context.Context, amount int64) error {
+	return s.mfa.requireForAmount(ctx, s.repo.user, amount)
+}
+
+func newReference() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", b), nil
+}
@@ -2,40 +2,83 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strconv"
 	"time"
 
 	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/throttle"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
 )
 
+// tokenClaims are claims of access and challenge tokens. Purpose is empty for access tokens.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Purpose string           `json:"purpose,omitempty"`
+	MfaAt   *jwt.NumericDate `json:"mfa_at,omitempty"`
+}
+
 type UserService struct {
 	repo struct {
-		user  domain.UserRepository
-		token domain.TokenRepository
+		user          domain.UserRepository
+		token         domain.TokenRepository
+		mfa           domain.MfaRepository
+		passwordReset domain.PasswordResetRepository
+		audit         domain.AuditRepository
+		outbox        domain.OutboxRepository
+	}
+	transactor domain.Transactor
+	hasher     PasswordHasher
+	mailer     Mailer
+	metrics    MetricsRecorder
+	mfa        MfaSettings
+	email      EmailSettings
+	resets     struct {
+		queue   chan passwordReset
+		byEmail *throttle.Limiter
+		byIp    *throttle.Limiter
 	}
-	hasher          PasswordHasher
 	hmacSecret      []byte
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
 }
 
-func NewUserService(repos Repositories, hasher PasswordHasher, secret []byte, accessttl, refreshttl time.Duration) *UserService {
-	return &UserService{
+func NewUserService(repos Repositories, hasher PasswordHasher, mailer Mailer, metrics MetricsRecorder, mfa MfaSettings, email EmailSettings,
+	secret []byte, accessttl, refreshttl time.Duration) *UserService {
+	s := &UserService{
 		repo: struct {
-			user  domain.UserRepository
-			token domain.TokenRepository
+			user          domain.UserRepository
+			token         domain.TokenRepository
+			mfa           domain.MfaRepository
+			passwordReset domain.PasswordResetRepository
+			audit         domain.AuditRepository
+			outbox        domain.OutboxRepository
 		}{
-			user:  repos.GetUserRepository(),
-			token: repos.GetTokenRepository(),
+			user:          repos.GetUserRepository(),
+			token:         repos.GetTokenRepository(),
+			mfa:           repos.GetMfaRepository(),
+			passwordReset: repos.GetPasswordResetRepository(),
+			audit:         repos.GetAuditRepository(),
+			outbox:        repos.GetOutboxRepository(),
 		},
+		transactor:      repos.GetTransactor(),
 		hasher:          hasher,
+		mailer:          mailer,
+		metrics:         metrics,
+		mfa:             mfa,
+		email:           email,
 		hmacSecret:      secret,
 		accessTokenTTL:  accessttl,
 		refreshTokenTTL: refreshttl,
 	}
+	s.resets.queue = make(chan passwordReset, email.ResetQueue)
+	s.resets.byEmail = throttle.New(email.ResetEmailLimit, email.ResetWindow)
+	s.resets.byIp = throttle.New(email.ResetIpLimit, email.ResetWindow)
+
+	return s
 }
 
 func (s *UserService) Create(ctx context.Context, input domain.SignUpInput) error {
@@ -46,10 +89,47 @@ func (s *UserService) Create(ctx context.Context, input domain.SignUpInput) erro
 
 	input.Password = password
 
-	return s.repo.user.Create(ctx, input)
+	var id int64
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if id, err = s.repo.user.Create(ctx, input); err != nil {
+			return err
+		}
+
+		user, err := s.repo.user.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := emit(ctx, s.repo.outbox, domain.AggregateUser, id, domain.EventUserRegistered, domain.UserEvent{UserId: id}); err != nil {
+			return err
+		}
+
+		return audit(withActor(ctx, id), s.repo.audit, domain.AuditUserCreate, domain.AuditEntityUser, id, nil, user)
+	})
+	if err != nil {
+		return err
+	}
+
+	// user can request the link again, so failed delivery doesn't fail sign up
+	if err := s.sendVerification(ctx, id, input.Email); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context": "UserService.Create()",
+			"problem": "can't send verification email",
+		}).Error(err)
+	}
+
+	return nil
 }
 
 func (s *UserService) GetTokenByCredentials(ctx context.Context, input domain.SignInInput) (string, string, error) {
+	accessToken, refreshToken, err := s.signIn(ctx, input)
+	s.metrics.SignIn("password", signInResult(err))
+
+	return accessToken, refreshToken, err
+}
+
+func (s *UserService) signIn(ctx context.Context, input domain.SignInInput) (string, string, error) {
 	password, err := s.hasher.Hash(input.Password)
 	if err != nil {
 		return "", "", err
@@ -62,7 +142,29 @@ func (s *UserService) GetTokenByCredentials(ctx context.Context, input domain.Si
 		return "", "", err
 	}
 
-	return s.generateTokens(ctx, user.Id)
+	if user.TotpEnabled {
+		challenge, err := s.newChallengeToken(user.Id)
+		if err != nil {
+			return "", "", err
+		}
+
+		return "", "", &domain.MfaChallengeError{ChallengeToken: challenge}
+	}
+
+	return s.generateTokens(ctx, user.Id, nil)
+}
+
+// signInResult names the outcome of sign in for metrics.
+func signInResult(err error) string {
+	var challenge *domain.MfaChallengeError
+	switch {
+	case err == nil:
+		return "success"
+	case errors.As(err, &challenge):
+		return "mfa_required"
+	default:
+		return "failure"
+	}
 }
 
 func (s *UserService) GetByCredentials(ctx context.Context, input domain.SignInInput) (*domain.User, error) {
@@ -76,8 +178,45 @@ func (s *UserService) GetByCredentials(ctx context.Context, input domain.SignInI
 	return s.repo.user.GetByCredentials(ctx, input)
 }
 
-func (s *UserService) ParseToken(ctx context.Context, tokenString string) (int64, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+func (s *UserService) GetById(ctx context.Context, id int64) (*domain.User, error) {
+	return s.repo.user.GetById(ctx, id)
+}
+
+func (s *UserService) ParseToken(ctx context.Context, tokenString string) (*domain.AccessClaims, error) {
+	claims, err := s.parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// challenge tokens only prove the password and can't be used for access
+	if claims.Purpose != "" {
+		return nil, domain.ErrInvalidToken
+	}
+
+	id, err := strconv.ParseInt(claims.ID, 10, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidId
+	}
+
+	// tokens are not stored, so tokens of erased users are refused here until they expire
+	active, err := s.repo.user.IsActive(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, domain.ErrInvalidToken
+	}
+
+	access := domain.AccessClaims{UserId: id, ExpiresAt: claims.ExpiresAt.Time}
+	if claims.MfaAt != nil {
+		access.MfaAt = &claims.MfaAt.Time
+	}
+
+	return &access, nil
+}
+
+func (s *UserService) parseClaims(tokenString string) (*tokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -85,28 +224,23 @@ func (s *UserService) ParseToken(ctx context.Context, tokenString string) (int64
 		return s.hmacSecret, nil
 	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return 0, domain.ErrInvalidToken
+		return nil, domain.ErrInvalidToken
 	}
 
-	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
-		return 0, domain.ErrInvalidClaims
+		return nil, domain.ErrInvalidClaims
 	}
 
 	if claims.ExpiresAt.Before(time.Now()) {
-		return 0, domain.ErrAccessTokenExpired
-	}
-
-	id, err := strconv.ParseInt(claims.ID, 10, 64)
-	if err != nil {
-		return 0, domain.ErrInvalidId
+		return nil, domain.ErrAccessTokenExpired
 	}
 
-	return id, nil
+	return claims, nil
 }
 
 func (s *UserService) RefreshTokens(ctx context.Context, refreshToken string) (string, string, error) {
@@ -119,15 +253,24 @@ func (s *UserService) RefreshTokens(ctx context.Context, refreshToken string) (s
 		return "", "", domain.ErrRefreshTokenExpired
 	}
 
-	return s.generateTokens(ctx, session.UserID)
+	return s.generateTokens(ctx, session.UserID, nil)
 }
 
-func (s *UserService) generateTokens(ctx context.Context, userId int64) (string, string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		ID:        strconv.FormatInt(userId, 10),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTokenTTL)),
-	})
+// generateTokens issues access and refresh tokens. mfaAt is the moment the second factor
+// was passed, it's kept in access token to allow sensitive operations.
+func (s *UserService) generateTokens(ctx context.Context, userId int64, mfaAt *time.Time) (string, string, error) {
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        strconv.FormatInt(userId, 10),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.accessTokenTTL)),
+		},
+	}
+	if mfaAt != nil {
+		claims.MfaAt = jwt.NewNumericDate(*mfaAt)
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	accessToken, err := token.SignedString(s.hmacSecret)
 	if err != nil {
@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/publisher"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	webhookDeliveriesLimit = 100
+	defaultWebhookLease    = 5 * time.Minute
+)
+
+type WebhookSender interface {
+	Send(ctx context.Context, url, secret string, msg publisher.Message) (int, error)
+}
+
+// WebhookSettings configure delivery to user's webhooks. A delivery is retried with exponential
+// backoff up to MaxAttempts times; a webhook failing DisableAfter attempts in a row is disabled.
+// Balance changes of at least LargeTransaction are sent as large transactions as well.
+// Up to Concurrency webhooks are sent to at once; claimed deliveries are not picked by other
+// workers for Lease. AllowInsecure accepts plain HTTP URLs, it is meant for local development.
+type WebhookSettings struct {
+	BatchSize        int
+	Concurrency      int
+	Lease            time.Duration
+	MaxAttempts      int
+	DisableAfter     int
+	MinBackoff       time.Duration
+	MaxBackoff       time.Duration
+	LargeTransaction int64
+	AllowInsecure    bool
+}
+
+type WebhookService struct {
+	repo struct {
+		webhook domain.WebhookRepository
+		account domain.AccountRepository
+		user    domain.UserRepository
+		audit   domain.AuditRepository
+	}
+	transactor domain.Transactor
+	sender     WebhookSender
+	mfa        MfaSettings
+	settings   WebhookSettings
+}
+
+func NewWebhookService(repos Repositories, sender WebhookSender, mfa MfaSettings, settings WebhookSettings) *WebhookService {
+	if settings.Concurrency < 1 {
+		settings.Concurrency = 1
+	}
+	if settings.Lease <= 0 {
+		settings.Lease = defaultWebhookLease
+	}
+
+	return &WebhookService{
+		repo: struct {
+			webhook domain.WebhookRepository
+			account domain.AccountRepository
+			user    domain.UserRepository
+			audit   domain.AuditRepository
+		}{
+			webhook: repos.GetWebhookRepository(),
+			account: repos.GetAccountRepository(),
+			user:    repos.GetUserRepository(),
+			audit:   repos.GetAuditRepository(),
+		},
+		transactor: repos.GetTransactor(),
+		sender:     sender,
+		mfa:        mfa,
+		settings:   settings,
+	}
+}
+
+// Create registers user's webhook with a new signing secret. Webhooks send account data
+// out of the bank, so users with TOTP enabled must pass second factor recently.
+func (s *WebhookService) Create(ctx context.Context, inp domain.WebhookInput) (*domain.Webhook, error) {
+	if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkURL(inp.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := newReference()
+	if err != nil {
+		return nil, err
+	}
+	secret = "whsec_" + secret
+
+	var created *domain.Webhook
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.repo.webhook.Create(ctx, domain.Webhook{URL: inp.URL, EventTypes: inp.EventTypes, Secret: secret})
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditWebhookCreate, domain.AuditEntityWebhook, created.Id, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created.Secret = secret
+
+	return created, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]domain.Webhook, error) {
+	return s.repo.webhook.List(ctx)
+}
+
+func (s *WebhookService) GetById(ctx context.Context, id int64) (*domain.Webhook, error) {
+	return s.repo.webhook.GetById(ctx, id)
+}
+
+// UpdateById changes the webhook. Changing URL requires second factor as creating one does.
+func (s *WebhookService) UpdateById(ctx context.Context, id int64, inp domain.WebhookUpdateInput) (*domain.Webhook, error) {
+	if inp.URL != nil {
+		if err := requireRecentMfa(ctx, s.repo.user, s.mfa.MaxAge); err != nil {
+			return nil, err
+		}
+
+		if err := s.checkURL(*inp.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	var updated *domain.Webhook
+	err := s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.webhook.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if updated, err = s.repo.webhook.UpdateById(ctx, id, inp); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditWebhookUpdate, domain.AuditEntityWebhook, id, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *WebhookService) DeleteById(ctx context.Context, id int64) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.webhook.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.webhook.DeleteById(ctx, id); err != nil {
+			return err
+		}
+
+		return audit(ctx, s.repo.audit, domain.AuditWebhookDelete, domain.AuditEntityWebhook, id, before, nil)
+	})
+}
+
+// ListDeliveries returns the latest deliveries of user's webhook.
+func (s *WebhookService) ListDeliveries(ctx context.Context, id int64) ([]domain.WebhookDelivery, error) {
+	if _, err := s.repo.webhook.GetById(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.webhook.ListDeliveries(ctx, id, webhookDeliveriesLimit)
+}
+
+// Resend queues the delivery again. It is sent with the next run of delivery.
+func (s *WebhookService) Resend(ctx context.Context, id, deliveryId int64) (*domain.WebhookDelivery, error) {
+	return s.repo.webhook.ResetDelivery(ctx, id, deliveryId)
+}
+
+// Handle queues the event for webhooks of its owner subscribed to it.
+func (s *WebhookService) Handle(ctx context.Context, e domain.Event) error {
+	var payload struct {
+		UserId    int64 `json:"user_id"`
+		AccountId int64 `json:"account_id"`
+		Amount    int64 `json:"amount"`
+	}
+
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context":  "WebhookService.Handle()",
+			"problem":  "can't decode event",
+			"event_id": e.Id,
+		}).Warn(err.Error())
+		return nil
+	}
+
+	var types []string
+	switch e.Type {
+	case domain.EventBalanceChanged:
+		amount := payload.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+
+		types = append(types, domain.WebhookBalanceChanged)
+		if s.settings.LargeTransaction > 0 && amount >= s.settings.LargeTransaction {
+			types = append(types, domain.WebhookLargeTransaction)
+		}
+	case domain.EventAccountCreated:
+		types = append(types, domain.WebhookAccountCreated)
+	case domain.EventAccountUpdated:
+		types = append(types, domain.WebhookAccountUpdated)
+	case domain.EventAccountClosed:
+		types = append(types, domain.WebhookAccountClosed)
+	case domain.EventPaymentStatusChanged:
+		types = append(types, domain.WebhookPaymentStatusChanged)
+	default:
+		return nil
+	}
+
+	userId := payload.UserId
+	if userId == 0 && payload.AccountId != 0 {
+		account, err := s.repo.account.Lookup(ctx, payload.AccountId)
+		if errors.Is(err, domain.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		userId = account.UserId
+	}
+
+	for _, t := range types {
+		webhooks, err := s.repo.webhook.ListSubscribed(ctx, userId, t)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range webhooks {
+			err := s.repo.webhook.CreateDelivery(ctx, domain.WebhookDelivery{
+				WebhookId: w.Id,
+				EventId:   e.Id,
+				EventType: t,
+				Payload:   e.Payload,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Deliver sends deliveries due at now until there are no more of them.
+func (s *WebhookService) Deliver(ctx context.Context, now time.Time) error {
+	for {
+		n, err := s.deliverBatch(ctx, now)
+		if err != nil || n < s.settings.BatchSize {
+			return err
+		}
+	}
+}
+
+// deliverBatch claims due deliveries and sends them outside of transaction. Deliveries of
+// a webhook are sent one after another in order, different webhooks are sent concurrently,
+// so a slow endpoint delays only its own deliveries. Sending stops when the claim expires.
+func (s *WebhookService) deliverBatch(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := s.repo.webhook.ClaimDue(ctx, now, s.settings.BatchSize, time.Now().Add(s.settings.Lease))
+	if err != nil {
+		return 0, err
+	}
+
+	var webhooks []int64
+	queues := make(map[int64][]domain.DueDelivery)
+	for _, d := range deliveries {
+		if _, ok := queues[d.WebhookId]; !ok {
+			webhooks = append(webhooks, d.WebhookId)
+		}
+		queues[d.WebhookId] = append(queues[d.WebhookId], d)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, s.settings.Lease)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, s.settings.Concurrency)
+	)
+
+	for _, id := range webhooks {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(queue []domain.DueDelivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := s.sendQueue(ctx, sendCtx, queue); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(queues[id])
+	}
+
+	wg.Wait()
+
+	return len(deliveries), firstErr
+}
+
+// sendQueue sends deliveries of one webhook until it is disabled or the claim expires.
+// Unsent deliveries stay claimed and are sent after the claim.
+func (s *WebhookService) sendQueue(ctx, sendCtx context.Context, queue []domain.DueDelivery) error {
+	for _, d := range queue {
+		if sendCtx.Err() != nil {
+			return nil
+		}
+
+		disabled, err := s.send(ctx, sendCtx, d)
+		if err != nil || disabled {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send makes an attempt of the delivery and reports whether its webhook has been disabled.
+// Request is made with sendCtx, the result is recorded with ctx in a short transaction.
+func (s *WebhookService) send(ctx, sendCtx context.Context, d domain.DueDelivery) (bool, error) {
+	status, err := s.sender.Send(sendCtx, d.URL, d.Secret, publisher.Message{
+		Id:        strconv.FormatInt(d.Id, 10),
+		Type:      d.EventType,
+		Payload:   d.Payload,
+		CreatedAt: d.CreatedAt,
+	})
+	if err == nil {
+		return false, s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := s.repo.webhook.MarkDelivered(ctx, d.Id, status); err != nil {
+				return err
+			}
+
+			return s.repo.webhook.ResetFailures(ctx, d.WebhookId)
+		})
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"context":     "WebhookService.Deliver()",
+		"problem":     "can't deliver webhook",
+		"webhook_id":  d.WebhookId,
+		"delivery_id": d.Id,
+		"attempts":    d.Attempts + 1,
+	}).Warn(err.Error())
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+
+	var next *time.Time
+	if d.Attempts+1 < s.settings.MaxAttempts {
+		t := time.Now().Add(backoff(s.settings.MinBackoff, s.settings.MaxBackoff, d.Attempts))
+		next = &t
+	}
+
+	var disabled bool
+	lastError := err.Error()
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.webhook.MarkAttemptFailed(ctx, d.Id, responseStatus, lastError, next); err != nil {
+			return err
+		}
+
+		var err error
+		disabled, err = s.repo.webhook.AddFailure(ctx, d.WebhookId, s.settings.DisableAfter)
+		return err
+	})
+	if disabled {
+		logrus.WithContext(ctx).WithFields(logrus.Fields{
+			"context":    "WebhookService.Deliver()",
+			"webhook_id": d.WebhookId,
+		}).Info("webhook disabled after repeated failures")
+	}
+
+	return disabled, err
+}
+
+func (s *WebhookService) checkURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return domain.ErrInvalidWebhookURL
+	}
+
+	if u.Scheme == "https" || (u.Scheme == "http" && s.settings.AllowInsecure) {
+		return nil
+	}
+
+	return domain.ErrInvalidWebhookURL
+}
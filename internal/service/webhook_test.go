@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/publisher"
+)
+
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeWebhookRepository keeps deliveries in memory. Methods not used by delivery panic.
+type fakeWebhookRepository struct {
+	domain.WebhookRepository
+
+	mu         sync.Mutex
+	deliveries []*domain.DueDelivery
+	failures   map[int64]int
+	disabled   map[int64]bool
+}
+
+func (r *fakeWebhookRepository) add(id, webhookId int64, url string, attempts int) {
+	r.deliveries = append(r.deliveries, &domain.DueDelivery{
+		WebhookDelivery: domain.WebhookDelivery{
+			Id:        id,
+			WebhookId: webhookId,
+			EventType: domain.WebhookBalanceChanged,
+			Payload:   json.RawMessage(`{"account_id":1}`),
+			Status:    domain.DeliveryPending,
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: "whsec_" + strconv.FormatInt(webhookId, 10),
+	})
+}
+
+func (r *fakeWebhookRepository) get(id int64) *domain.DueDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries {
+		if d.Id == id {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// due makes pending deliveries due again, as if their backoff or lease has passed.
+func (r *fakeWebhookRepository) due() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries {
+		d.NextAttemptAt = nil
+	}
+}
+
+func (r *fakeWebhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]domain.DueDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed []domain.DueDelivery
+	for _, d := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if d.Status != domain.DeliveryPending || r.disabled[d.WebhookId] || (d.NextAttemptAt != nil && d.NextAttemptAt.After(now)) {
+			continue
+		}
+
+		lease := leaseUntil
+		d.NextAttemptAt = &lease
+		claimed = append(claimed, *d)
+	}
+
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepository) MarkDelivered(ctx context.Context, deliveryId int64, responseStatus int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries {
+		if d.Id == deliveryId {
+			d.Status, d.ResponseStatus, d.NextAttemptAt = domain.DeliveryDelivered, &responseStatus, nil
+			d.Attempts++
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeWebhookRepository) MarkAttemptFailed(ctx context.Context, deliveryId int64, responseStatus *int, lastError string, nextAttemptAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries {
+		if d.Id == deliveryId {
+			d.ResponseStatus, d.LastError, d.NextAttemptAt = responseStatus, lastError, nextAttemptAt
+			d.Attempts++
+			if nextAttemptAt == nil {
+				d.Status = domain.DeliveryFailed
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *fakeWebhookRepository) AddFailure(ctx context.Context, id int64, disableAfter int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[id]++
+	if disableAfter > 0 && r.failures[id] >= disableAfter {
+		r.disabled[id] = true
+	}
+
+	return r.disabled[id], nil
+}
+
+func (r *fakeWebhookRepository) ResetFailures(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[id] = 0
+
+	return nil
+}
+
+func newTestWebhookService(repo *fakeWebhookRepository, settings WebhookSettings) *WebhookService {
+	if settings.BatchSize == 0 {
+		settings.BatchSize = 10
+	}
+	if settings.Concurrency == 0 {
+		settings.Concurrency = 4
+	}
+	if settings.Lease == 0 {
+		settings.Lease = 5 * time.Second
+	}
+
+	s := &WebhookService{
+		transactor: fakeTransactor{},
+		sender:     publisher.NewSender(time.Second, true),
+		settings:   settings,
+	}
+	s.repo.webhook = repo
+
+	return s
+}
+
+func newFakeWebhookRepository() *fakeWebhookRepository {
+	return &fakeWebhookRepository{failures: make(map[int64]int), disabled: make(map[int64]bool)}
+}
+
+func TestDeliverSignsRequests(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		bad []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg publisher.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+			return
+		}
+
+		body, _ := json.Marshal(msg)
+		ts, _ := strconv.ParseInt(r.Header.Get(publisher.HeaderTimestamp), 10, 64)
+		if r.Header.Get(publisher.HeaderSignature) != "sha256="+publisher.Sign("whsec_1", time.Unix(ts, 0), body) {
+			mu.Lock()
+			bad = append(bad, msg.Id)
+			mu.Unlock()
+		}
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepository()
+	repo.add(1, 1, srv.URL, 0)
+	repo.add(2, 1, srv.URL, 0)
+
+	if err := newTestWebhookService(repo, WebhookSettings{MaxAttempts: 3}).Deliver(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int64{1, 2} {
+		if d := repo.get(id); d.Status != domain.DeliveryDelivered || *d.ResponseStatus != http.StatusOK {
+			t.Errorf("delivery %d: status %s", id, d.Status)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range bad {
+		t.Errorf("delivery %s: bad signature", id)
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepository()
+	repo.add(1, 1, srv.URL, 0)
+
+	settings := WebhookSettings{MaxAttempts: 5, MinBackoff: time.Minute, MaxBackoff: 90 * time.Second}
+	s := newTestWebhookService(repo, settings)
+
+	wantBackoff := []time.Duration{time.Minute, 90 * time.Second}
+	for i, want := range wantBackoff {
+		start := time.Now()
+		if err := s.Deliver(context.Background(), start); err != nil {
+			t.Fatal(err)
+		}
+
+		d := repo.get(1)
+		if d.Status != domain.DeliveryPending || d.Attempts != i+1 || *d.ResponseStatus != http.StatusInternalServerError {
+			t.Fatalf("attempt %d: status %s, attempts %d", i+1, d.Status, d.Attempts)
+		}
+		if next := d.NextAttemptAt.Sub(start); next < want || next > want+time.Second {
+			t.Fatalf("attempt %d: retried after %s, want %s", i+1, next, want)
+		}
+
+		// not due yet
+		if err := s.Deliver(context.Background(), start); err != nil {
+			t.Fatal(err)
+		}
+		if repo.get(1).Attempts != i+1 {
+			t.Fatalf("attempt %d: retried before backoff", i+1)
+		}
+
+		repo.due()
+	}
+
+	if err := s.Deliver(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := repo.get(1); d.Status != domain.DeliveryDelivered || d.Attempts != 3 {
+		t.Fatalf("status %s, attempts %d", d.Status, d.Attempts)
+	}
+	if repo.failures[1] != 0 {
+		t.Fatalf("failures are not reset: %d", repo.failures[1])
+	}
+}
+
+func TestDeliverGivesUpAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepository()
+	repo.add(1, 1, srv.URL, 2)
+
+	if err := newTestWebhookService(repo, WebhookSettings{MaxAttempts: 3}).Deliver(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := repo.get(1); d.Status != domain.DeliveryFailed || d.NextAttemptAt != nil {
+		t.Fatalf("status %s, next attempt %v", d.Status, d.NextAttemptAt)
+	}
+}
+
+func TestDeliverDisablesWebhook(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepository()
+	repo.add(1, 1, srv.URL+"/broken", 0)
+	repo.add(2, 1, srv.URL+"/broken", 0)
+	repo.add(3, 2, srv.URL+"/ok", 0)
+
+	err := newTestWebhookService(repo, WebhookSettings{MaxAttempts: 5, DisableAfter: 1, MinBackoff: time.Minute, MaxBackoff: time.Hour}).
+		Deliver(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !repo.disabled[1] || calls["/broken"] != 1 {
+		t.Fatalf("disabled %v after %d calls, want disabled after 1", repo.disabled[1], calls["/broken"])
+	}
+	if d := repo.get(2); d.Attempts != 0 {
+		t.Fatalf("delivery of disabled webhook was attempted")
+	}
+	if d := repo.get(3); d.Status != domain.DeliveryDelivered {
+		t.Fatalf("delivery of other webhook: status %s", d.Status)
+	}
+}
+
+func TestDeliverSlowWebhookDoesNotBlockOthers(t *testing.T) {
+	fastDone := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			close(fastDone)
+			return
+		}
+
+		// the slow endpoint answers only after the other webhook got its delivery
+		select {
+		case <-fastDone:
+		case <-time.After(2 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	}))
+	defer srv.Close()
+
+	repo := newFakeWebhookRepository()
+	repo.add(1, 1, srv.URL+"/slow", 0)
+	repo.add(2, 2, srv.URL+"/fast", 0)
+
+	s := newTestWebhookService(repo, WebhookSettings{MaxAttempts: 3, MinBackoff: time.Minute, MaxBackoff: time.Hour})
+	s.sender = publisher.NewSender(5*time.Second, true)
+
+	if err := s.Deliver(context.Background(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int64{1, 2} {
+		if d := repo.get(id); d.Status != domain.DeliveryDelivered {
+			t.Errorf("delivery %d: status %s, last error %q", id, d.Status, d.LastError)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(30*time.Second, time.Hour, tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	codeBadUserInput    = "BAD_USER_INPUT"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	codeNotFound        = "NOT_FOUND"
+	codeLimitExceeded   = "LIMIT_EXCEEDED"
+	codeConflict        = "CONFLICT"
+	codeInternal        = "INTERNAL"
+)
+
+// resolveError is a field error with the code in extensions. Messages of internal errors
+// are not sent to clients.
+type resolveError struct {
+	code string
+	err  error
+}
+
+func (e *resolveError) Error() string {
+	if e.code == codeInternal {
+		return "internal error"
+	}
+
+	return e.err.Error()
+}
+
+func (e *resolveError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func (e *resolveError) Unwrap() error {
+	return e.err
+}
+
+// newResolveError logs the error of the service layer and maps it to the code the way
+// REST handlers map them to HTTP statuses.
+func newResolveError(ctx context.Context, context string, err error) error {
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"context": context,
+		"problem": "service error",
+	}).Error(err)
+
+	return &resolveError{code: serviceCode(err), err: err}
+}
+
+func serviceCode(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrNotExist),
+		errors.Is(err, domain.ErrUserNotFound),
+		errors.Is(err, domain.ErrDeleteFailed):
+		return codeNotFound
+	case errors.Is(err, domain.ErrInvalidToken),
+		errors.Is(err, domain.ErrInvalidClaims),
+		errors.Is(err, domain.ErrAccessTokenExpired):
+		return codeUnauthenticated
+	case errors.Is(err, domain.ErrMfaRequired),
+		errors.Is(err, domain.ErrEmailNotVerified),
+		errors.Is(err, domain.ErrRiskChallenged),
+		errors.Is(err, domain.ErrRiskBlocked),
+		errors.Is(err, domain.ErrForbidden):
+		return codeForbidden
+	case errors.Is(err, domain.ErrLimitExceeded),
+		errors.Is(err, domain.ErrTooManyRequests):
+		return codeLimitExceeded
+	case errors.Is(err, domain.ErrAccountInUse):
+		return codeConflict
+	case errors.Is(err, domain.ErrInvalidId),
+		errors.Is(err, domain.ErrUpdateFailed),
+		errors.Is(err, domain.ErrIbanAlreadyExists):
+		return codeBadUserInput
+	default:
+		return codeInternal
+	}
+}
@@ -0,0 +1,92 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type Services interface {
+	GetAccountService() domain.AccountService
+	GetUserService() domain.UserService
+}
+
+// Limits bound the cost of a query. Depth is the nesting of fields; complexity is the number
+// of fields, where fields under a list with limit argument are counted limit times.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+type Handler struct {
+	services Services
+	limits   Limits
+	schema   graphql.Schema
+}
+
+func NewHandler(s Services, limits Limits) (*Handler, error) {
+	h := &Handler{
+		services: s,
+		limits:   limits,
+	}
+
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+
+	return h, nil
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP executes a query sent as JSON body. The user must be already authorized,
+// all resolvers act on behalf of the user from the request context.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	if v := graphql.ValidateDocument(&h.schema, doc, nil); !v.IsValid {
+		writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: v.Errors})
+		return
+	}
+
+	if err := h.checkLimits(doc, req.OperationName, req.Variables); err != nil {
+		writeResult(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(r.Context(), h.services.GetAccountService()),
+	})
+
+	writeResult(w, http.StatusOK, result)
+}
+
+func writeResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,151 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// checkLimits rejects the operation exceeding depth or complexity limits before it's executed.
+// The document must be valid. Introspection fields are not counted.
+func (h *Handler) checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}) error {
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var operation *ast.OperationDefinition
+
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operation == nil && (operationName == "" || def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+
+	if operation == nil {
+		return nil
+	}
+
+	root := h.schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = h.schema.MutationType()
+	}
+
+	w := walker{schema: h.schema, fragments: fragments, variables: variables}
+	complexity, depth := w.walk(operation.SelectionSet, root, 1)
+
+	if h.limits.MaxDepth > 0 && depth > h.limits.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds limit %d", depth, h.limits.MaxDepth)
+	}
+
+	if h.limits.MaxComplexity > 0 && complexity > h.limits.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds limit %d", complexity, h.limits.MaxComplexity)
+	}
+
+	return nil
+}
+
+type walker struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// walk returns complexity and depth of fields of the selection set on the given depth.
+func (w walker) walk(set *ast.SelectionSet, parent *graphql.Object, depth int) (int, int) {
+	var complexity, maxDepth int
+	if set == nil || parent == nil {
+		return 0, 0
+	}
+
+	add := func(c, d int) {
+		complexity += c
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			name := s.Name.Value
+			def, ok := parent.Fields()[name]
+			if !ok || len(name) > 1 && name[:2] == "__" {
+				continue
+			}
+
+			child, _ := graphql.GetNamed(def.Type).(*graphql.Object)
+			c, d := w.walk(s.SelectionSet, child, depth+1)
+			if d < depth {
+				d = depth
+			}
+			add(1+c*w.multiplier(def, s), d)
+		case *ast.InlineFragment:
+			add(w.walk(s.SelectionSet, w.object(s.TypeCondition, parent), depth))
+		case *ast.FragmentSpread:
+			if f, ok := w.fragments[s.Name.Value]; ok {
+				add(w.walk(f.SelectionSet, w.object(f.TypeCondition, parent), depth))
+			}
+		}
+	}
+
+	return complexity, maxDepth
+}
+
+// multiplier is the number of items a list field may return, it's known for fields with limit.
+func (w walker) multiplier(def *graphql.FieldDefinition, field *ast.Field) int {
+	var limit interface{}
+	for _, arg := range def.Args {
+		if arg.Name() == "limit" {
+			limit = arg.DefaultValue
+		}
+	}
+
+	if limit == nil {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			limit, _ = strconv.Atoi(v.Value)
+		case *ast.Variable:
+			if value, ok := w.variables[v.Name.Value]; ok {
+				limit = value
+			}
+		}
+	}
+
+	var n int
+	switch v := limit.(type) {
+	case int:
+		n = v
+	case float64:
+		n = int(v)
+	}
+
+	if n < 1 {
+		return 1
+	}
+
+	return n
+}
+
+func (w walker) object(condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+
+	if object, ok := w.schema.Type(condition.Name.Value).(*graphql.Object); ok {
+		return object
+	}
+
+	return parent
+}
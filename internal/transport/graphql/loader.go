@@ -0,0 +1,148 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Viquad/crud-app/internal/domain"
+)
+
+type loadersKey struct{}
+
+// loader batches loads of one request. Keys requested by resolvers of one level are collected
+// while the executor walks it, the first thunk called fetches all of them with one query.
+type loader struct {
+	fetch func(ctx context.Context, keys []int64) (map[int64]interface{}, error)
+
+	mu      sync.Mutex
+	pending []int64
+	queued  map[int64]bool
+	results map[int64]interface{}
+	errs    map[int64]error
+}
+
+func newLoader(fetch func(ctx context.Context, keys []int64) (map[int64]interface{}, error)) *loader {
+	return &loader{
+		fetch:   fetch,
+		queued:  make(map[int64]bool),
+		results: make(map[int64]interface{}),
+		errs:    make(map[int64]error),
+	}
+}
+
+// load queues the key and returns thunk resolving the value, nil if it's not found.
+func (l *loader) load(ctx context.Context, key int64) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok && !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.queued[key] {
+			l.flush(ctx)
+		}
+
+		return l.results[key], l.errs[key]
+	}
+}
+
+// prime stores the value already loaded by other resolver.
+func (l *loader) prime(key int64, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.queued[key] {
+		l.results[key] = value
+	}
+}
+
+func (l *loader) flush(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	results, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		delete(l.queued, key)
+		l.results[key] = results[key]
+		if err != nil {
+			l.errs[key] = err
+		}
+	}
+}
+
+type loaders struct {
+	accountService domain.AccountService
+
+	accounts *loader
+
+	mu           sync.Mutex
+	transactions map[int]*loader
+}
+
+func withLoaders(ctx context.Context, accountService domain.AccountService) context.Context {
+	l := &loaders{
+		accountService: accountService,
+		transactions:   make(map[int]*loader),
+	}
+
+	l.accounts = newLoader(func(ctx context.Context, ids []int64) (map[int64]interface{}, error) {
+		accounts, err := accountService.ListByIds(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make(map[int64]interface{}, len(accounts))
+		for i := range accounts {
+			results[accounts[i].Id] = &accounts[i]
+		}
+
+		return results, nil
+	})
+
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func getLoaders(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// transactionsOf returns loader of the latest transactions of accounts. Loads with
+// different limits are batched separately.
+func (l *loaders) transactionsOf(limit int) *loader {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if tl, ok := l.transactions[limit]; ok {
+		return tl
+	}
+
+	tl := newLoader(func(ctx context.Context, ids []int64) (map[int64]interface{}, error) {
+		transactions, err := l.accountService.ListRecentTransactions(ctx, ids, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		byAccount := make(map[int64][]domain.Transaction, len(ids))
+		for _, t := range transactions {
+			byAccount[t.AccountId] = append(byAccount[t.AccountId], t)
+		}
+
+		results := make(map[int64]interface{}, len(ids))
+		for _, id := range ids {
+			if byAccount[id] == nil {
+				byAccount[id] = []domain.Transaction{}
+			}
+			results[id] = byAccount[id]
+		}
+
+		return results, nil
+	})
+	l.transactions[limit] = tl
+
+	return tl
+}
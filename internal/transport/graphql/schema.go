@@ -0,0 +1,316 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	defaultTransactionsLimit = 20
+	maxTransactionsLimit     = 100
+)
+
+// int64Type carries amounts in minor units, which don't fit into 32-bit GraphQL Int.
+var int64Type = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Int64",
+	Description: "64-bit integer, amounts are in minor units of the currency",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case int64:
+			return v
+		case *int64:
+			if v == nil {
+				return nil
+			}
+			return *v
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case float64:
+			if v == float64(int64(v)) {
+				return int64(v)
+			}
+		case int:
+			return int64(v)
+		case int64:
+			return v
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i
+			}
+		}
+		return nil
+	},
+	ParseLiteral: func(value ast.Value) interface{} {
+		if v, ok := value.(*ast.IntValue); ok {
+			if i, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				return i
+			}
+		}
+		return nil
+	},
+})
+
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	account := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Account",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"type":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"iban":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"balance":    &graphql.Field{Type: graphql.NewNonNull(int64Type)},
+			"currency":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lastUpdate": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	transaction := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Transaction",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"amount":      &graphql.Field{Type: graphql.NewNonNull(int64Type)},
+			"currency":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"type":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"reference":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"externalRef": &graphql.Field{Type: graphql.String, Resolve: resolveExternalRef},
+			"date":        &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"account":     &graphql.Field{Type: graphql.NewNonNull(account), Resolve: resolveTransactionAccount},
+		},
+	})
+
+	account.AddFieldConfig("transactions", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transaction))),
+		Description: "The latest transactions, newest first",
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultTransactionsLimit},
+		},
+		Resolve: resolveAccountTransactions,
+	})
+
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"firstName":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"lastName":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"phone":        &graphql.Field{Type: graphql.String},
+			"tier":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"registeredAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"accounts":     &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(account))), Resolve: h.resolveAccounts},
+		},
+	})
+
+	accountCreateInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "AccountCreateInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"type":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"balance":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(int64Type)},
+			"currency": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:    graphql.NewNonNull(user),
+				Resolve: h.resolveMe,
+			},
+			"accounts": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(account))),
+				Resolve: h.resolveAccounts,
+			},
+			"account": &graphql.Field{
+				Type: account,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolveAccount,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createAccount": &graphql.Field{
+				Type: graphql.NewNonNull(account),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(accountCreateInput)},
+				},
+				Resolve: h.createAccount,
+			},
+			"updateAccount": &graphql.Field{
+				Type: graphql.NewNonNull(account),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"balance": &graphql.ArgumentConfig{Type: int64Type},
+				},
+				Resolve: h.updateAccount,
+			},
+			"deleteAccount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: h.deleteAccount,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+func (h *Handler) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	user, err := h.services.GetUserService().GetProfile(p.Context)
+	if err != nil {
+		return nil, newResolveError(p.Context, "resolveMe()", err)
+	}
+
+	return user, nil
+}
+
+func (h *Handler) resolveAccounts(p graphql.ResolveParams) (interface{}, error) {
+	accounts, err := h.services.GetAccountService().List(p.Context)
+	if err != nil {
+		return nil, newResolveError(p.Context, "resolveAccounts()", err)
+	}
+
+	// sources of account fields are pointers whether accounts are listed or loaded by id
+	l := getLoaders(p.Context)
+	result := make([]*domain.Account, 0, len(accounts))
+	for i := range accounts {
+		l.accounts.prime(accounts[i].Id, &accounts[i])
+		result = append(result, &accounts[i])
+	}
+
+	return result, nil
+}
+
+func resolveAccount(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseId(p.Args["id"])
+	if err != nil {
+		return nil, newResolveError(p.Context, "resolveAccount()", err)
+	}
+
+	return thunk(p.Context, "resolveAccount()", getLoaders(p.Context).accounts.load(p.Context, id)), nil
+}
+
+func resolveAccountTransactions(p graphql.ResolveParams) (interface{}, error) {
+	account, ok := p.Source.(*domain.Account)
+	if !ok {
+		return nil, nil
+	}
+
+	limit, _ := p.Args["limit"].(int)
+	if limit < 1 || limit > maxTransactionsLimit {
+		return nil, &resolveError{code: codeBadUserInput, err: fmt.Errorf("limit must be from 1 to %d", maxTransactionsLimit)}
+	}
+
+	return thunk(p.Context, "resolveAccountTransactions()", getLoaders(p.Context).transactionsOf(limit).load(p.Context, account.Id)), nil
+}
+
+func resolveTransactionAccount(p graphql.ResolveParams) (interface{}, error) {
+	t, ok := p.Source.(domain.Transaction)
+	if !ok {
+		return nil, nil
+	}
+
+	return thunk(p.Context, "resolveTransactionAccount()", getLoaders(p.Context).accounts.load(p.Context, t.AccountId)), nil
+}
+
+func resolveExternalRef(p graphql.ResolveParams) (interface{}, error) {
+	if t, ok := p.Source.(domain.Transaction); ok && t.ExternalRef != "" {
+		return t.ExternalRef, nil
+	}
+
+	return nil, nil
+}
+
+func (h *Handler) createAccount(p graphql.ResolveParams) (interface{}, error) {
+	fields, _ := p.Args["input"].(map[string]interface{})
+
+	var input domain.AccountCreateInput
+	input.Type, _ = fields["type"].(string)
+	input.Balance, _ = fields["balance"].(int64)
+	input.Currency, _ = fields["currency"].(string)
+
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, &resolveError{code: codeBadUserInput, err: err}
+	}
+
+	account, err := h.services.GetAccountService().Create(p.Context, input)
+	if err != nil {
+		return nil, newResolveError(p.Context, "createAccount()", err)
+	}
+
+	return account, nil
+}
+
+func (h *Handler) updateAccount(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseId(p.Args["id"])
+	if err != nil {
+		return nil, newResolveError(p.Context, "updateAccount()", err)
+	}
+
+	var input domain.AccountUpdateInput
+	if balance, ok := p.Args["balance"].(int64); ok {
+		input.Balance = &balance
+	}
+
+	account, err := h.services.GetAccountService().UpdateById(p.Context, id, input)
+	if err != nil {
+		return nil, newResolveError(p.Context, "updateAccount()", err)
+	}
+
+	return account, nil
+}
+
+func (h *Handler) deleteAccount(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseId(p.Args["id"])
+	if err != nil {
+		return nil, newResolveError(p.Context, "deleteAccount()", err)
+	}
+
+	if err := h.services.GetAccountService().DeleteById(p.Context, id); err != nil {
+		return nil, newResolveError(p.Context, "deleteAccount()", err)
+	}
+
+	return true, nil
+}
+
+// thunk wraps loader result, so errors of the batch are reported as errors of the service.
+func thunk(ctx context.Context, context string, load func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		v, err := load()
+		if err != nil {
+			return nil, newResolveError(ctx, context, err)
+		}
+
+		return v, nil
+	}
+}
+
+func parseId(arg interface{}) (int64, error) {
+	s, _ := arg.(string)
+
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, domain.ErrInvalidId
+	}
+
+	return id, nil
+}
@@ -0,0 +1,158 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/api/crudv1"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type accountServer struct {
+	crudv1.UnimplementedAccountServiceServer
+	services Services
+}
+
+func (s *accountServer) CreateAccount(ctx context.Context, req *crudv1.CreateAccountRequest) (*crudv1.Account, error) {
+	input := domain.AccountCreateInput{Type: req.Type, Balance: req.Balance, Currency: req.Currency}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "CreateAccount()", "binding error", err)
+	}
+
+	account, err := s.services.GetAccountService().Create(ctx, input)
+	if err != nil {
+		return nil, newServiceError(ctx, "CreateAccount()", err)
+	}
+
+	return toAccount(account), nil
+}
+
+func (s *accountServer) ListAccounts(ctx context.Context, req *crudv1.ListAccountsRequest) (*crudv1.ListAccountsResponse, error) {
+	accounts, err := s.services.GetAccountService().List(ctx)
+	if err != nil {
+		return nil, newServiceError(ctx, "ListAccounts()", err)
+	}
+
+	resp := &crudv1.ListAccountsResponse{Accounts: make([]*crudv1.Account, 0, len(accounts))}
+	for i := range accounts {
+		resp.Accounts = append(resp.Accounts, toAccount(&accounts[i]))
+	}
+
+	return resp, nil
+}
+
+func (s *accountServer) GetAccount(ctx context.Context, req *crudv1.GetAccountRequest) (*crudv1.Account, error) {
+	account, err := s.services.GetAccountService().GetById(ctx, req.Id)
+	if err != nil {
+		return nil, newServiceError(ctx, "GetAccount()", err)
+	}
+
+	return toAccount(account), nil
+}
+
+func (s *accountServer) UpdateAccount(ctx context.Context, req *crudv1.UpdateAccountRequest) (*crudv1.Account, error) {
+	account, err := s.services.GetAccountService().UpdateById(ctx, req.Id, domain.AccountUpdateInput{Balance: req.Balance})
+	if err != nil {
+		return nil, newServiceError(ctx, "UpdateAccount()", err)
+	}
+
+	return toAccount(account), nil
+}
+
+func (s *accountServer) DeleteAccount(ctx context.Context, req *crudv1.DeleteAccountRequest) (*crudv1.DeleteAccountResponse, error) {
+	if err := s.services.GetAccountService().DeleteById(ctx, req.Id); err != nil {
+		return nil, newServiceError(ctx, "DeleteAccount()", err)
+	}
+
+	return &crudv1.DeleteAccountResponse{}, nil
+}
+
+func (s *accountServer) Transfer(ctx context.Context, req *crudv1.TransferRequest) (*crudv1.TransactionResult, error) {
+	input := domain.TransferInput{
+		ToAccountId: req.ToAccountId,
+		ToIban:      req.ToIban,
+		ToPayeeId:   req.ToPayeeId,
+		PayeeName:   req.PayeeName,
+		Amount:      req.Amount,
+		Description: req.Description,
+	}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "Transfer()", "binding error", err)
+	}
+
+	result, err := s.services.GetAccountService().Transfer(ctx, req.Id, input, req.DryRun)
+	if err != nil {
+		return nil, newServiceError(ctx, "Transfer()", err)
+	}
+
+	return toTransactionResult(result), nil
+}
+
+func (s *accountServer) Withdraw(ctx context.Context, req *crudv1.WithdrawRequest) (*crudv1.TransactionResult, error) {
+	input := domain.WithdrawInput{Amount: req.Amount, Description: req.Description}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "Withdraw()", "binding error", err)
+	}
+
+	result, err := s.services.GetAccountService().Withdraw(ctx, req.Id, input, req.DryRun)
+	if err != nil {
+		return nil, newServiceError(ctx, "Withdraw()", err)
+	}
+
+	return toTransactionResult(result), nil
+}
+
+func (s *accountServer) LookupByIban(ctx context.Context, req *crudv1.LookupByIbanRequest) (*crudv1.AccountLookup, error) {
+	account, err := s.services.GetAccountService().LookupByIban(ctx, req.Iban)
+	if err != nil {
+		return nil, newServiceError(ctx, "LookupByIban()", err)
+	}
+
+	return &crudv1.AccountLookup{Iban: account.Iban, Currency: account.Currency}, nil
+}
+
+func toAccount(a *domain.Account) *crudv1.Account {
+	return &crudv1.Account{
+		Id:         a.Id,
+		UserId:     a.UserId,
+		Type:       a.Type,
+		Iban:       a.Iban,
+		Balance:    a.Balance,
+		Currency:   a.Currency,
+		LastUpdate: timestamppb.New(a.LastUpdate),
+	}
+}
+
+func toTransactionResult(r *domain.TransactionResult) *crudv1.TransactionResult {
+	result := &crudv1.TransactionResult{
+		DryRun: r.DryRun,
+		Amount: r.Amount,
+		Total:  r.Total,
+	}
+
+	for _, f := range r.Fees {
+		result.Fees = append(result.Fees, &crudv1.Fee{Name: f.Name, Amount: f.Amount, Currency: f.Currency})
+	}
+
+	if r.NameCheck != nil {
+		result.NameCheck = &crudv1.NameCheck{Result: r.NameCheck.Result, Name: r.NameCheck.Name}
+	}
+
+	for _, t := range r.Transactions {
+		result.Transactions = append(result.Transactions, &crudv1.Transaction{
+			Id:          t.Id,
+			AccountId:   t.AccountId,
+			Amount:      t.Amount,
+			Currency:    t.Currency,
+			Type:        t.Type,
+			Reference:   t.Reference,
+			Description: t.Description,
+			ExternalRef: t.ExternalRef,
+			Date:        timestamppb.New(t.Date),
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/api/crudv1"
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+)
+
+type authServer struct {
+	crudv1.UnimplementedAuthServiceServer
+	services Services
+}
+
+func (s *authServer) SignUp(ctx context.Context, req *crudv1.SignUpRequest) (*crudv1.SignUpResponse, error) {
+	input := domain.SignUpInput{FirstName: req.FirstName, LastName: req.LastName, Email: req.Email, Password: req.Password}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "SignUp()", "binding error", err)
+	}
+
+	if err := s.services.GetUserService().Create(ctx, input); err != nil {
+		return nil, newServiceError(ctx, "SignUp()", err)
+	}
+
+	return &crudv1.SignUpResponse{}, nil
+}
+
+func (s *authServer) SignIn(ctx context.Context, req *crudv1.SignInRequest) (*crudv1.SignInResponse, error) {
+	input := domain.SignInInput{Email: req.Email, Password: req.Password}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "SignIn()", "binding error", err)
+	}
+
+	accessToken, refreshToken, err := s.services.GetUserService().GetTokenByCredentials(ctx, input)
+	var challenge *domain.MfaChallengeError
+	switch {
+	case errors.As(err, &challenge):
+		return &crudv1.SignInResponse{Result: &crudv1.SignInResponse_ChallengeToken{ChallengeToken: challenge.ChallengeToken}}, nil
+	case err != nil:
+		return nil, newServiceError(ctx, "SignIn()", err)
+	}
+
+	return &crudv1.SignInResponse{Result: &crudv1.SignInResponse_Tokens{Tokens: toTokens(accessToken, refreshToken)}}, nil
+}
+
+func (s *authServer) SignInWithMfa(ctx context.Context, req *crudv1.SignInWithMfaRequest) (*crudv1.Tokens, error) {
+	input := domain.MfaSignInInput{ChallengeToken: req.ChallengeToken, Code: req.Code}
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "SignInWithMfa()", "binding error", err)
+	}
+
+	accessToken, refreshToken, err := s.services.GetUserService().SignInWithMfa(ctx, input)
+	if err != nil {
+		return nil, newServiceError(ctx, "SignInWithMfa()", err)
+	}
+
+	return toTokens(accessToken, refreshToken), nil
+}
+
+func (s *authServer) RefreshTokens(ctx context.Context, req *crudv1.RefreshTokensRequest) (*crudv1.Tokens, error) {
+	if req.RefreshToken == "" {
+		return nil, newStatusError(ctx, codes.InvalidArgument, "RefreshTokens()", "binding error", errors.New("refresh token is empty"))
+	}
+
+	accessToken, refreshToken, err := s.services.GetUserService().RefreshTokens(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, newServiceError(ctx, "RefreshTokens()", err)
+	}
+
+	return toTokens(accessToken, refreshToken), nil
+}
+
+func toTokens(accessToken, refreshToken string) *crudv1.Tokens {
+	return &crudv1.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}
+}
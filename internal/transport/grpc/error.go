@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newStatusError logs the error and converts it to status. Messages of internal errors
+// are not sent to clients.
+func newStatusError(ctx context.Context, code codes.Code, context, problem string, err error) error {
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"context": context,
+		"problem": problem,
+	}).Error(err)
+
+	if code == codes.Internal {
+		return status.Error(code, "internal error")
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// newServiceError maps errors of the service layer to status codes the way REST handlers
+// map them to HTTP statuses.
+func newServiceError(ctx context.Context, context string, err error) error {
+	return newStatusError(ctx, serviceCode(err), context, "service error", err)
+}
+
+func serviceCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, domain.ErrNotExist),
+		errors.Is(err, domain.ErrUserNotFound),
+		errors.Is(err, domain.ErrDeleteFailed):
+		return codes.NotFound
+	case errors.Is(err, domain.ErrUserAlreadyExists):
+		return codes.AlreadyExists
+	case errors.Is(err, domain.ErrAccountInUse):
+		return codes.FailedPrecondition
+	case errors.Is(err, domain.ErrInvalidToken),
+		errors.Is(err, domain.ErrInvalidClaims),
+		errors.Is(err, domain.ErrAccessTokenExpired),
+		errors.Is(err, domain.ErrRefreshTokenExpired),
+		errors.Is(err, domain.ErrInvalidMfaCode):
+		return codes.Unauthenticated
+	case errors.Is(err, domain.ErrMfaRequired),
+		errors.Is(err, domain.ErrEmailNotVerified),
+		errors.Is(err, domain.ErrRiskChallenged),
+		errors.Is(err, domain.ErrRiskBlocked),
+		errors.Is(err, domain.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, domain.ErrLimitExceeded),
+		errors.Is(err, domain.ErrMfaLocked),
+		errors.Is(err, domain.ErrTooManyRequests):
+		return codes.ResourceExhausted
+	case errors.Is(err, domain.ErrInsufficientFunds),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrSameAccount),
+		errors.Is(err, domain.ErrInvalidIban),
+		errors.Is(err, domain.ErrInvalidId),
+		errors.Is(err, domain.ErrUpdateFailed):
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/api/crudv1"
+	"github.com/Viquad/crud-app/pkg/requestid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const requestIdKey = "x-request-id"
+
+// clientInterceptor puts client IP, request ID and method into context for the audit trail
+// and logs. Request ID is taken from x-request-id metadata or generated and sent back in
+// the header. Every call is logged with its code and latency when it's done.
+func (h *Handler) clientInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var requestId string
+	if values := md.Get(requestIdKey); len(values) > 0 && requestid.Valid(values[0]) {
+		requestId = values[0]
+	} else {
+		requestId = requestid.New()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIdKey, requestId))
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			ctx = context.WithValue(ctx, domain.ClientIpKey, host)
+		}
+	}
+	ctx = context.WithValue(ctx, domain.RequestIdKey, requestId)
+	ctx = context.WithValue(ctx, domain.RouteKey, info.FullMethod)
+
+	t := time.Now()
+	resp, err := handler(ctx, req)
+
+	var userAgent string
+	if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+
+	logrus.WithContext(ctx).WithFields(logrus.Fields{
+		"code":       status.Code(err).String(),
+		"latency":    time.Since(t).String(),
+		"user_agent": userAgent,
+	}).Info("Call served")
+
+	return resp, err
+}
+
+// authInterceptor checks access token in authorization metadata the same way authMiddleware
+// checks the header. Calls of AuthService pass without token.
+func (h *Handler) authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, "/"+crudv1.AuthService_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	token, err := getTokenFromMetadata(ctx)
+	if err != nil {
+		return nil, newStatusError(ctx, codes.Unauthenticated, "authInterceptor", "get token error", err)
+	}
+
+	claims, err := h.services.GetUserService().ParseToken(ctx, token)
+	if err != nil {
+		return nil, newStatusError(ctx, codes.Unauthenticated, "authInterceptor", "service error", err)
+	}
+
+	ctx = context.WithValue(ctx, domain.UserIdKey, claims.UserId)
+	ctx = context.WithValue(ctx, domain.TokenExpiresAtKey, claims.ExpiresAt)
+	if claims.MfaAt != nil {
+		ctx = context.WithValue(ctx, domain.MfaAtKey, *claims.MfaAt)
+	}
+
+	return handler(ctx, req)
+}
+
+func getTokenFromMetadata(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return "", errors.New("empty authorization metadata")
+	}
+
+	parts := strings.Split(values[0], " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", errors.New("invalid authorization metadata")
+	}
+
+	if len(parts[1]) == 0 {
+		return "", errors.New("token is empty")
+	}
+
+	return parts[1], nil
+}
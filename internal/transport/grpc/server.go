@@ -0,0 +1,31 @@
+package grpc
+
+import (
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/api/crudv1"
+	"google.golang.org/grpc"
+)
+
+type Services interface {
+	GetAccountService() domain.AccountService
+	GetUserService() domain.UserService
+}
+
+type Handler struct {
+	services Services
+}
+
+func NewHandler(s Services) *Handler {
+	return &Handler{s}
+}
+
+// InitServer returns gRPC server with account and auth services. Calls of AuthService are
+// public, the others need access token as REST handlers do.
+func (h *Handler) InitServer() *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(h.clientInterceptor, h.authInterceptor))
+
+	crudv1.RegisterAuthServiceServer(server, &authServer{services: h.services})
+	crudv1.RegisterAccountServiceServer(server, &accountServer{services: h.services})
+
+	return server
+}
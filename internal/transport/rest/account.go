@@ -21,6 +21,14 @@ func (h *Handler) initAccount(router *gin.RouterGroup) {
 		account.POST("/:id", h.UpdateAccount)
 		account.PUT("/:id", h.UpdateAccount)
 		account.DELETE("/:id", h.DeleteAccount)
+		account.POST("/:id/transfer", h.Transfer)
+		account.POST("/:id/withdraw", h.Withdraw)
+		account.GET("/:id/statements", h.GetStatements)
+		account.GET("/:id/statements/:period", h.GetStatement)
+		account.POST("/:id/payments", h.CreatePayment)
+		account.GET("/:id/payments", h.GetPayments)
+		account.GET("/:id/limits", h.GetLimits)
+		account.PATCH("/:id/limits", h.LowerLimits)
 	}
 }
 
@@ -151,7 +159,7 @@ func (h *Handler) UpdateAccount(c *gin.Context) {
 // @Produce     json
 // @Param       id              path     string true "account id"
 // @Success     200             {object} rest.statusResponse
-// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Failure     400,401,404,409,500 {object} rest.errorResponse
 // @Router      /account/{id} [delete]
 func (h *Handler) DeleteAccount(c *gin.Context) {
 	id, err := parseId(c)
@@ -164,6 +172,8 @@ func (h *Handler) DeleteAccount(c *gin.Context) {
 		switch {
 		case errors.Is(err, domain.ErrDeleteFailed):
 			newErrorResponse(c, http.StatusNotFound, "DeleteAccount()", "service error", err)
+		case errors.Is(err, domain.ErrAccountInUse):
+			newErrorResponse(c, http.StatusConflict, "DeleteAccount()", "service error", err)
 		default:
 			newErrorResponse(c, http.StatusInternalServerError, "DeleteAccount()", "service error", err)
 		}
@@ -173,6 +183,122 @@ func (h *Handler) DeleteAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, statusResponse{"OK"})
 }
 
+// Transfer godoc
+// @Summary     Transfer money
+// @Description Transfer money from user's account to another account, by to_account_id only between own accounts. Use dry_run to preview fees.
+// @Security    ApiKeyAuth
+// @Tags        account
+// @Accept      json
+// @Produce     json
+// @Param       id                      path     string               true  "account id"
+// @Param       dry_run                 query    bool                 false "preview fees without moving money"
+// @Param       input                   body     domain.TransferInput true  "transfer info"
+// @Success     200,201                 {object} domain.TransactionResult
+// @Failure     400,401,403,404,422,500 {object} rest.errorResponse
+// @Router      /account/{id}/transfer [post]
+func (h *Handler) Transfer(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Transfer()", "parsing id error", err)
+		return
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Transfer()", "parsing dry_run error", err)
+		return
+	}
+
+	var input domain.TransferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Transfer()", "binding error", err)
+		return
+	}
+
+	result, err := h.services.GetAccountService().Transfer(c.Request.Context(), id, input, dryRun)
+	if err != nil {
+		newTransactionErrorResponse(c, "Transfer()", err)
+		return
+	}
+
+	c.JSON(transactionStatus(result), result)
+}
+
+// Withdraw godoc
+// @Summary     Withdraw money
+// @Description Withdraw money from user's account. Use dry_run to preview fees.
+// @Security    ApiKeyAuth
+// @Tags        account
+// @Accept      json
+// @Produce     json
+// @Param       id                      path     string               true  "account id"
+// @Param       dry_run                 query    bool                 false "preview fees without moving money"
+// @Param       input                   body     domain.WithdrawInput true  "withdrawal info"
+// @Success     200,201                 {object} domain.TransactionResult
+// @Failure     400,401,403,404,422,500 {object} rest.errorResponse
+// @Router      /account/{id}/withdraw [post]
+func (h *Handler) Withdraw(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Withdraw()", "parsing id error", err)
+		return
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Withdraw()", "parsing dry_run error", err)
+		return
+	}
+
+	var input domain.WithdrawInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "Withdraw()", "binding error", err)
+		return
+	}
+
+	result, err := h.services.GetAccountService().Withdraw(c.Request.Context(), id, input, dryRun)
+	if err != nil {
+		newTransactionErrorResponse(c, "Withdraw()", err)
+		return
+	}
+
+	c.JSON(transactionStatus(result), result)
+}
+
+func newTransactionErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrLimitExceeded):
+		newLimitExceededResponse(c, context, err)
+	case errors.Is(err, domain.ErrRiskChallenged), errors.Is(err, domain.ErrRiskBlocked):
+		newRiskErrorResponse(c, context, err)
+	case errors.Is(err, domain.ErrMfaRequired), errors.Is(err, domain.ErrEmailNotVerified):
+		newErrorResponse(c, http.StatusForbidden, context, problem, err)
+	case errors.Is(err, domain.ErrNotExist):
+		newErrorResponse(c, http.StatusNotFound, context, problem, err)
+	case errors.Is(err, domain.ErrInsufficientFunds),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrSameAccount),
+		errors.Is(err, domain.ErrInvalidIban),
+		errors.Is(err, domain.ErrInvalidDate):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
+
+func transactionStatus(result *domain.TransactionResult) int {
+	if result.DryRun {
+		return http.StatusOK
+	}
+
+	return http.StatusCreated
+}
+
+func parseDryRun(c *gin.Context) (bool, error) {
+	return strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+}
+
 func parseId(c *gin.Context) (int64, error) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
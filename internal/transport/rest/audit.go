@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditEvents godoc
+// @Summary     Get audit events
+// @Description Get audit trail newest first. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Produce     json
+// @Param       actor_id        query    int    false "user who made the change"
+// @Param       action          query    string false "action, e.g. account.update"
+// @Param       entity_type     query    string false "entity type, e.g. account"
+// @Param       entity_id       query    string false "entity id"
+// @Param       from            query    string false "created at or after, RFC 3339"
+// @Param       to              query    string false "created before, RFC 3339"
+// @Param       limit           query    int    false "max events, 100 by default, up to 1000"
+// @Param       offset          query    int    false "events to skip"
+// @Success     200             {object} []domain.AuditEvent
+// @Failure     400,401,403,500 {object} rest.errorResponse
+// @Router      /admin/audit-events [get]
+func (h *Handler) GetAuditEvents(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetAuditEvents()", "parsing filter error", err)
+		return
+	}
+
+	events, err := h.services.GetAuditService().List(c.Request.Context(), filter)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "GetAuditEvents()", "service error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// VerifyAuditEvents godoc
+// @Summary     Verify audit trail
+// @Description Check hash chain of the audit trail to detect tampering. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Produce     json
+// @Success     200         {object} domain.AuditVerification
+// @Failure     401,403,500 {object} rest.errorResponse
+// @Router      /admin/audit-events/verify [get]
+func (h *Handler) VerifyAuditEvents(c *gin.Context) {
+	result, err := h.services.GetAuditService().Verify(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "VerifyAuditEvents()", "service error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func parseAuditFilter(c *gin.Context) (domain.AuditFilter, error) {
+	filter := domain.AuditFilter{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityId:   c.Query("entity_id"),
+	}
+
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, err
+		}
+		filter.ActorId = &id
+	}
+
+	for _, t := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		v := c.Query(t.param)
+		if v == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, err
+		}
+		parsed = parsed.UTC()
+		*t.dst = &parsed
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, err
+		}
+	}
+
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
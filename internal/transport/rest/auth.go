@@ -7,7 +7,6 @@ import (
 
 	"github.com/Viquad/crud-app/internal/domain"
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 func (h *Handler) initAuth(router *gin.RouterGroup) {
@@ -16,6 +15,14 @@ func (h *Handler) initAuth(router *gin.RouterGroup) {
 		auth.POST("/sign-up", h.signUp)
 		auth.POST("/sign-in", h.signIn)
 		auth.GET("/refresh", h.refresh)
+		auth.POST("/mfa", h.signInMfa)
+		auth.POST("/step-up", h.authMiddleware, h.stepUp)
+		auth.POST("/totp/enroll", h.authMiddleware, h.enrollTotp)
+		auth.POST("/totp/confirm", h.authMiddleware, h.confirmTotp)
+		auth.GET("/verify", h.verifyEmail)
+		auth.POST("/verify/resend", h.authMiddleware, h.resendVerification)
+		auth.POST("/password/forgot", h.forgotPassword)
+		auth.POST("/password/reset", h.resetPassword)
 	}
 }
 
@@ -56,7 +63,8 @@ func (h *Handler) signUp(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       input   body     domain.SignInInput true "user credentials to Sign-In"
-// @Success     200     {object} rest.authResponse
+// @Success     201     {object} rest.authResponse
+// @Success     202     {object} rest.mfaChallengeResponse
 // @Failure     400,500 {object} rest.errorResponse
 // @Router      /auth/sign-in [post]
 func (h *Handler) signIn(c *gin.Context) {
@@ -67,7 +75,11 @@ func (h *Handler) signIn(c *gin.Context) {
 	}
 
 	accessToken, refreshToken, err := h.services.GetUserService().GetTokenByCredentials(c.Request.Context(), input)
+	var challenge *domain.MfaChallengeError
 	switch {
+	case errors.As(err, &challenge):
+		c.JSON(http.StatusAccepted, mfaChallengeResponse{Status: "mfa_required", ChallengeToken: challenge.ChallengeToken})
+		return
 	case errors.Is(err, domain.ErrUserNotFound):
 		newErrorResponse(c, http.StatusNotFound, "SignIn()", "user not found error", err)
 		return
@@ -85,7 +97,7 @@ func (h *Handler) signIn(c *gin.Context) {
 // @Tags        auth
 // @Produce     json
 // @Success     201     {object} rest.authResponse
-// @Failure     400,500 {object} rest.errorResponse
+// @Failure     400,401,500 {object} rest.errorResponse
 // @Router      /auth/refresh [get]
 func (h *Handler) refresh(c *gin.Context) {
 	cookie, err := c.Request.Cookie("refresh-token")
@@ -94,16 +106,242 @@ func (h *Handler) refresh(c *gin.Context) {
 		return
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"context": "refresh()",
-	}).Debugf("%s", cookie.Value)
-
 	accessToken, refreshToken, err := h.services.GetUserService().RefreshTokens(c.Request.Context(), cookie.Value)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, "refresh()", "service error", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrRefreshTokenExpired) {
+			status = http.StatusUnauthorized
+		}
+		newErrorResponse(c, status, "refresh()", "service error", err)
+		return
+	}
+
+	c.Header("Set-Cookie", fmt.Sprintf("refresh-token=%s; HttpOnly", refreshToken))
+	c.JSON(http.StatusCreated, authResponse{Token: accessToken})
+}
+
+// SignInMfa godoc
+// @Summary     SignIn with second factor
+// @Description Exchange challenge token from SignIn and TOTP or recovery code for tokens
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       input           body     domain.MfaSignInInput true "challenge token and code"
+// @Success     201             {object} rest.authResponse
+// @Failure     400,401,429,500 {object} rest.errorResponse
+// @Router      /auth/mfa [post]
+func (h *Handler) signInMfa(c *gin.Context) {
+	var input domain.MfaSignInInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "signInMfa()", "binding error", err)
+		return
+	}
+
+	accessToken, refreshToken, err := h.services.GetUserService().SignInWithMfa(c.Request.Context(), input)
+	if err != nil {
+		newMfaErrorResponse(c, "signInMfa()", err)
 		return
 	}
 
 	c.Header("Set-Cookie", fmt.Sprintf("refresh-token=%s; HttpOnly", refreshToken))
 	c.JSON(http.StatusCreated, authResponse{Token: accessToken})
 }
+
+// StepUp godoc
+// @Summary     Step-up authentication
+// @Description Pass second factor again to get access token allowing sensitive operations
+// @Security    ApiKeyAuth
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       input           body     domain.MfaCodeInput true "TOTP or recovery code"
+// @Success     201             {object} rest.authResponse
+// @Failure     400,401,429,500 {object} rest.errorResponse
+// @Router      /auth/step-up [post]
+func (h *Handler) stepUp(c *gin.Context) {
+	var input domain.MfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "stepUp()", "binding error", err)
+		return
+	}
+
+	accessToken, refreshToken, err := h.services.GetUserService().StepUp(c.Request.Context(), input.Code)
+	if err != nil {
+		newMfaErrorResponse(c, "stepUp()", err)
+		return
+	}
+
+	c.Header("Set-Cookie", fmt.Sprintf("refresh-token=%s; HttpOnly", refreshToken))
+	c.JSON(http.StatusCreated, authResponse{Token: accessToken})
+}
+
+// EnrollTotp godoc
+// @Summary     Enroll TOTP
+// @Description Generate TOTP secret and provisioning URI to show as QR code
+// @Security    ApiKeyAuth
+// @Tags        auth
+// @Produce     json
+// @Success     200             {object} domain.TotpEnrollment
+// @Failure     400,401,409,500 {object} rest.errorResponse
+// @Router      /auth/totp/enroll [post]
+func (h *Handler) enrollTotp(c *gin.Context) {
+	enrollment, err := h.services.GetUserService().EnrollTotp(c.Request.Context())
+	if err != nil {
+		newMfaErrorResponse(c, "enrollTotp()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTotp godoc
+// @Summary     Confirm TOTP
+// @Description Enable enrolled TOTP with a code from authenticator app. Recovery codes are returned only once.
+// @Security    ApiKeyAuth
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       input           body     domain.MfaCodeInput true "TOTP code"
+// @Success     200             {object} rest.recoveryCodesResponse
+// @Failure     400,401,409,500 {object} rest.errorResponse
+// @Router      /auth/totp/confirm [post]
+func (h *Handler) confirmTotp(c *gin.Context) {
+	var input domain.MfaCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "confirmTotp()", "binding error", err)
+		return
+	}
+
+	codes, err := h.services.GetUserService().ConfirmTotp(c.Request.Context(), input.Code)
+	if err != nil {
+		newMfaErrorResponse(c, "confirmTotp()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func newMfaErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrInvalidMfaCode),
+		errors.Is(err, domain.ErrInvalidToken),
+		errors.Is(err, domain.ErrAccessTokenExpired):
+		newErrorResponse(c, http.StatusUnauthorized, context, problem, err)
+	case errors.Is(err, domain.ErrMfaAlreadyEnabled):
+		newErrorResponse(c, http.StatusConflict, context, problem, err)
+	case errors.Is(err, domain.ErrMfaNotEnrolled):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	case errors.Is(err, domain.ErrMfaLocked):
+		newErrorResponse(c, http.StatusTooManyRequests, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
+
+// VerifyEmail godoc
+// @Summary     Verify email
+// @Description Confirm email with the signed link sent after SignUp
+// @Tags        auth
+// @Produce     json
+// @Param       token           query    string true "verification token"
+// @Success     200             {object} rest.statusResponse
+// @Failure     400,401,409,500 {object} rest.errorResponse
+// @Router      /auth/verify [get]
+func (h *Handler) verifyEmail(c *gin.Context) {
+	if err := h.services.GetUserService().VerifyEmail(c.Request.Context(), c.Query("token")); err != nil {
+		newEmailErrorResponse(c, "verifyEmail()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// ResendVerification godoc
+// @Summary     Resend verification email
+// @Description Send email verification link again
+// @Security    ApiKeyAuth
+// @Tags        auth
+// @Produce     json
+// @Success     200         {object} rest.statusResponse
+// @Failure     401,409,500 {object} rest.errorResponse
+// @Router      /auth/verify/resend [post]
+func (h *Handler) resendVerification(c *gin.Context) {
+	if err := h.services.GetUserService().ResendVerification(c.Request.Context()); err != nil {
+		newEmailErrorResponse(c, "resendVerification()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// ForgotPassword godoc
+// @Summary     Forgot password
+// @Description Send single-use password reset token. Responds ok for unknown emails as well.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       input   body     domain.ForgotPasswordInput true "user email"
+// @Success     200         {object} rest.statusResponse
+// @Failure     400,429,500 {object} rest.errorResponse
+// @Router      /auth/password/forgot [post]
+func (h *Handler) forgotPassword(c *gin.Context) {
+	var input domain.ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "forgotPassword()", "binding error", err)
+		return
+	}
+
+	if err := h.services.GetUserService().ForgotPassword(c.Request.Context(), input); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrTooManyRequests) {
+			status = http.StatusTooManyRequests
+		}
+		newErrorResponse(c, status, "forgotPassword()", "service error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// ResetPassword godoc
+// @Summary     Reset password
+// @Description Set new password with reset token. All sessions of the user are revoked.
+// @Tags        auth
+// @Accept      json
+// @Produce     json
+// @Param       input       body     domain.ResetPasswordInput true "reset token and new password"
+// @Success     200         {object} rest.statusResponse
+// @Failure     400,401,500 {object} rest.errorResponse
+// @Router      /auth/password/reset [post]
+func (h *Handler) resetPassword(c *gin.Context) {
+	var input domain.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "resetPassword()", "binding error", err)
+		return
+	}
+
+	if err := h.services.GetUserService().ResetPassword(c.Request.Context(), input); err != nil {
+		newEmailErrorResponse(c, "resetPassword()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+func newEmailErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrInvalidToken),
+		errors.Is(err, domain.ErrAccessTokenExpired):
+		newErrorResponse(c, http.StatusUnauthorized, context, problem, err)
+	case errors.Is(err, domain.ErrEmailVerified):
+		newErrorResponse(c, http.StatusConflict, context, problem, err)
+	case errors.Is(err, domain.ErrUserNotFound),
+		errors.Is(err, domain.ErrInvalidId),
+		errors.Is(err, domain.ErrUpdateFailed):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
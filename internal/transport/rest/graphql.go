@@ -0,0 +1,24 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initGraphQL(router *gin.RouterGroup) {
+	router.POST("/graphql", h.authMiddleware, h.GraphQL)
+}
+
+// GraphQL godoc
+// @Summary     GraphQL query
+// @Description Execute GraphQL query or mutation over user, accounts and transactions. Errors of fields are returned in errors with code in extensions.
+// @Security    ApiKeyAuth
+// @Tags        graphql
+// @Accept      json
+// @Produce     json
+// @Param       input   body     object true "query, operationName and variables"
+// @Success     200     {object} object
+// @Failure     400,401 {object} object
+// @Router      /graphql [post]
+func (h *Handler) GraphQL(c *gin.Context) {
+	h.graphql.ServeHTTP(c.Writer, c.Request)
+}
@@ -1,31 +1,64 @@
 package rest
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/Viquad/crud-app/internal/domain"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// serverName is reported in spans of requests.
+const serverName = "crud-app"
+
 type Services interface {
 	GetAccountService() domain.AccountService
 	GetUserService() domain.UserService
+	GetStatementService() domain.StatementService
+	GetImportService() domain.ImportService
+	GetPaymentService() domain.PaymentService
+	GetPayeeService() domain.PayeeService
+	GetLimitService() domain.LimitService
+	GetRiskService() domain.RiskService
+	GetPrivacyService() domain.PrivacyService
+	GetAuditService() domain.AuditService
+	GetWebhookService() domain.WebhookService
+	GetStreamService() domain.StreamService
+}
+
+// RequestObserver records served requests for monitoring.
+type RequestObserver interface {
+	ObserveRequest(method, route string, status int, elapsed time.Duration)
 }
 
 type Handler struct {
 	services Services
+	graphql  http.Handler
+	metrics  RequestObserver
 }
 
-func NewHandler(s Services) *Handler {
-	return &Handler{s}
+func NewHandler(s Services, graphql http.Handler, metrics RequestObserver) *Handler {
+	registerJSONNames()
+
+	return &Handler{s, graphql, metrics}
 }
 
 func (h *Handler) InitRouter() *gin.Engine {
 	router := gin.New()
 
-	router.Use(h.Logger, gin.Recovery())
+	router.Use(otelgin.Middleware(serverName), h.Logger, h.metricsMiddleware, h.clientMiddleware, gin.Recovery())
 
 	h.initSwagger(&router.RouterGroup)
 	h.initAuth(&router.RouterGroup)
 	h.initAccount(&router.RouterGroup)
+	h.initIban(&router.RouterGroup)
+	h.initMe(&router.RouterGroup)
+	h.initPayee(&router.RouterGroup)
+	h.initWebhook(&router.RouterGroup)
+	h.initStream(&router.RouterGroup)
+	h.initGraphQL(&router.RouterGroup)
+	h.initAdmin(&router.RouterGroup)
 
 	return router
 }
@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initIban(router *gin.RouterGroup) {
+	iban := router.Group("/iban")
+	{
+		iban.Use(h.authMiddleware)
+
+		iban.GET("/:iban", h.LookupIban)
+	}
+}
+
+// LookupIban godoc
+// @Summary     Lookup IBAN
+// @Description Resolve IBAN to an account to transfer money to
+// @Security    ApiKeyAuth
+// @Tags        account
+// @Produce     json
+// @Param       iban            path     string true "IBAN"
+// @Success     200             {object} domain.AccountLookup
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /iban/{iban} [get]
+func (h *Handler) LookupIban(c *gin.Context) {
+	account, err := h.services.GetAccountService().LookupByIban(c.Request.Context(), c.Param("iban"))
+	if err != nil {
+		context, problem := "LookupIban()", "service error"
+		switch {
+		case errors.Is(err, domain.ErrInvalidIban):
+			newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+		case errors.Is(err, domain.ErrNotExist):
+			newErrorResponse(c, http.StatusNotFound, context, problem, err)
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/bankimport"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportTransactions godoc
+// @Summary     Import bank file
+// @Description Import transactions from CSV, OFX or camt.053 bank file into any account. Only for admins. Use dry_run to preview.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Accept      multipart/form-data
+// @Produce     json
+// @Param       id                  path     string true  "account id"
+// @Param       dry_run             query    bool   false "preview import without posting transactions"
+// @Param       file                formData file   true  "bank file"
+// @Param       format              formData string true  "csv, ofx or camt053"
+// @Param       date_column         formData string false "CSV date column name or index (default: date)"
+// @Param       amount_column       formData string false "CSV amount column name or index (default: amount)"
+// @Param       description_column  formData string false "CSV description column name or index"
+// @Param       reference_column    formData string false "CSV external reference column name or index"
+// @Param       currency_column     formData string false "CSV currency column name or index"
+// @Param       date_layout         formData string false "CSV date layout in Go format (default: 2006-01-02)"
+// @Param       delimiter           formData string false "CSV delimiter (default: ,)"
+// @Param       no_header           formData bool   false "CSV file has no header"
+// @Success     200,201             {object} domain.ImportResult
+// @Failure     400,401,403,404,500 {object} rest.errorResponse
+// @Router      /admin/accounts/{id}/imports [post]
+func (h *Handler) ImportTransactions(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "parsing id error", err)
+		return
+	}
+
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "parsing dry_run error", err)
+		return
+	}
+
+	var mapping bankimport.CSVMapping
+	if err := c.ShouldBind(&mapping); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "binding error", err)
+		return
+	}
+
+	parser, err := bankimport.NewParser(c.PostForm("format"), mapping)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "parser error", err)
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "file error", err)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "file error", err)
+		return
+	}
+	defer file.Close()
+
+	entries, err := parser.Parse(file)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ImportTransactions()", "parsing file error", err)
+		return
+	}
+
+	result, err := h.services.GetImportService().Import(c.Request.Context(), id, importEntries(entries), dryRun)
+	if err != nil {
+		context, problem := "ImportTransactions()", "service error"
+		switch {
+		case errors.Is(err, domain.ErrNotExist):
+			newErrorResponse(c, http.StatusNotFound, context, problem, err)
+		case errors.Is(err, domain.ErrCurrencyMismatch), errors.Is(err, domain.ErrInsufficientFunds):
+			newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, result)
+}
+
+// importEntries converts parsed bank file entries to domain entries.
+func importEntries(entries []bankimport.Entry) []domain.ImportEntry {
+	result := make([]domain.ImportEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, domain.ImportEntry{
+			ExternalRef: e.ExternalRef,
+			Date:        e.Date,
+			Amount:      e.Amount,
+			Currency:    e.Currency,
+			Description: e.Description,
+		})
+	}
+
+	return result
+}
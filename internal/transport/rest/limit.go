@@ -0,0 +1,189 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initAdmin(router *gin.RouterGroup) {
+	admin := router.Group("/admin")
+	{
+		admin.Use(h.authMiddleware, h.adminMiddleware)
+
+		admin.PATCH("/accounts/:id/limits", h.OverrideLimits)
+		admin.PATCH("/users/:id/limits", h.OverrideUserLimits)
+		admin.POST("/accounts/:id/imports", h.ImportTransactions)
+		admin.GET("/risk-reviews", h.GetRiskReviews)
+		admin.POST("/risk-reviews/:id/approve", h.ApproveRiskReview)
+		admin.POST("/risk-reviews/:id/reject", h.RejectRiskReview)
+		admin.GET("/audit-events", h.GetAuditEvents)
+		admin.GET("/audit-events/verify", h.VerifyAuditEvents)
+	}
+}
+
+// GetLimits godoc
+// @Summary     Get account limits
+// @Description Get effective spending limits of user's account and money spent in rolling windows
+// @Security    ApiKeyAuth
+// @Tags        account
+// @Produce     json
+// @Param       id              path     string true "account id"
+// @Success     200             {object} domain.AccountLimits
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /account/{id}/limits [get]
+func (h *Handler) GetLimits(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetLimits()", "parsing id error", err)
+		return
+	}
+
+	limits, err := h.services.GetLimitService().Get(c.Request.Context(), id)
+	if err != nil {
+		newLimitErrorResponse(c, "GetLimits()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// LowerLimits godoc
+// @Summary     Lower account limits
+// @Description Lower spending limits of user's account. Limits can't be raised above effective ones.
+// @Security    ApiKeyAuth
+// @Tags        account
+// @Accept      json
+// @Produce     json
+// @Param       id              path     string        true "account id"
+// @Param       input           body     domain.Limits true "new limits, omitted are unchanged"
+// @Success     200             {object} domain.AccountLimits
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /account/{id}/limits [patch]
+func (h *Handler) LowerLimits(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "LowerLimits()", "parsing id error", err)
+		return
+	}
+
+	var input domain.Limits
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "LowerLimits()", "binding error", err)
+		return
+	}
+
+	limits, err := h.services.GetLimitService().Lower(c.Request.Context(), id, input)
+	if err != nil {
+		newLimitErrorResponse(c, "LowerLimits()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// OverrideLimits godoc
+// @Summary     Override account limits
+// @Description Set spending limits of any account instead of defaults. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       id                  path     string        true "account id"
+// @Param       input               body     domain.Limits true "new limits, omitted are unchanged"
+// @Success     200                 {object} domain.AccountLimits
+// @Failure     400,401,403,404,500 {object} rest.errorResponse
+// @Router      /admin/accounts/{id}/limits [patch]
+func (h *Handler) OverrideLimits(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "OverrideLimits()", "parsing id error", err)
+		return
+	}
+
+	var input domain.Limits
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "OverrideLimits()", "binding error", err)
+		return
+	}
+
+	limits, err := h.services.GetLimitService().Override(c.Request.Context(), id, input)
+	if err != nil {
+		newLimitErrorResponse(c, "OverrideLimits()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// GetUserLimits godoc
+// @Summary     Get user limits
+// @Description Get effective spending limits of all user's accounts by currency and money spent in rolling windows
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Produce     json
+// @Success     200     {array}  domain.UserLimits
+// @Failure     401,500 {object} rest.errorResponse
+// @Router      /me/limits [get]
+func (h *Handler) GetUserLimits(c *gin.Context) {
+	limits, err := h.services.GetLimitService().GetUser(c.Request.Context())
+	if err != nil {
+		newLimitErrorResponse(c, "GetUserLimits()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// OverrideUserLimits godoc
+// @Summary     Override user limits
+// @Description Set spending limits of all accounts of any user in the currency instead of defaults. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Accept      json
+// @Produce     json
+// @Param       id                  path     string                 true "user id"
+// @Param       input               body     domain.UserLimitsInput true "currency and new limits, omitted are unchanged"
+// @Success     200                 {object} domain.UserLimits
+// @Failure     400,401,403,404,500 {object} rest.errorResponse
+// @Router      /admin/users/{id}/limits [patch]
+func (h *Handler) OverrideUserLimits(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "OverrideUserLimits()", "parsing id error", err)
+		return
+	}
+
+	var input domain.UserLimitsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "OverrideUserLimits()", "binding error", err)
+		return
+	}
+
+	limits, err := h.services.GetLimitService().OverrideUser(c.Request.Context(), id, input)
+	if err != nil {
+		newLimitErrorResponse(c, "OverrideUserLimits()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+func newLimitErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrNotExist):
+		newErrorResponse(c, http.StatusNotFound, context, problem, err)
+	case errors.Is(err, domain.ErrLimitRaise):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
+
+// newLimitExceededResponse responds with the exceeded limit and remaining allowance.
+func newLimitExceededResponse(c *gin.Context, context string, err error) {
+	newErrorResponse(c, http.StatusUnprocessableEntity, context, "limit exceeded", err)
+}
@@ -8,24 +8,69 @@ import (
 	"time"
 
 	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/requestid"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+const requestIdHeader = "X-Request-ID"
+
+// Logger writes a single access log line when the response is sent. Request ID, user ID and
+// route are added from the request context filled by the following middlewares.
 func (h *Handler) Logger(c *gin.Context) {
-	logrus.WithFields(logrus.Fields{
-		"method": c.Request.Method,
-		"url":    c.Request.URL.String(),
-	}).Info("Accept request")
+	t := time.Now()
 
+	c.Next()
+
+	bytes := c.Writer.Size()
+	if bytes < 0 {
+		bytes = 0
+	}
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"method":     c.Request.Method,
+		"path":       c.Request.URL.Path,
+		"status":     c.Writer.Status(),
+		"latency":    time.Since(t).String(),
+		"bytes":      bytes,
+		"user_agent": c.Request.UserAgent(),
+		"client_ip":  c.ClientIP(),
+	}).Info("Request served")
+}
+
+// metricsMiddleware observes latency and status of the request by its route. Requests to
+// unknown routes are observed together, so scanners don't add new series.
+func (h *Handler) metricsMiddleware(c *gin.Context) {
 	t := time.Now()
 
 	c.Next()
 
-	logrus.WithFields(logrus.Fields{
-		"status":  c.Writer.Status(),
-		"elapsed": time.Since(t).String(),
-	}).Info("Send response")
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	h.metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(t))
+}
+
+// clientMiddleware puts client IP, request ID and route into request context for the audit
+// trail and logs. Request ID is taken from X-Request-ID header or generated and sent back
+// in the response.
+func (h *Handler) clientMiddleware(c *gin.Context) {
+	requestId := c.GetHeader(requestIdHeader)
+	if !requestid.Valid(requestId) {
+		requestId = requestid.New()
+	}
+	c.Header(requestIdHeader, requestId)
+
+	ctx := context.WithValue(c.Request.Context(), domain.ClientIpKey, c.ClientIP())
+	ctx = context.WithValue(ctx, domain.RequestIdKey, requestId)
+	if route := c.FullPath(); route != "" {
+		ctx = context.WithValue(ctx, domain.RouteKey, route)
+	}
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
 }
 
 func (h *Handler) authMiddleware(c *gin.Context) {
@@ -35,17 +80,49 @@ func (h *Handler) authMiddleware(c *gin.Context) {
 		return
 	}
 
-	userId, err := h.services.GetUserService().ParseToken(c.Request.Context(), token)
+	claims, err := h.services.GetUserService().ParseToken(c.Request.Context(), token)
 	if err != nil {
 		newErrorResponse(c, http.StatusUnauthorized, "authMiddleware", "service error", err)
+		return
 	}
 
-	ctx := context.WithValue(c.Request.Context(), domain.UserIdKey, userId)
+	ctx := context.WithValue(c.Request.Context(), domain.UserIdKey, claims.UserId)
+	ctx = context.WithValue(ctx, domain.TokenExpiresAtKey, claims.ExpiresAt)
+	if claims.MfaAt != nil {
+		ctx = context.WithValue(ctx, domain.MfaAtKey, *claims.MfaAt)
+	}
 	c.Request = c.Request.WithContext(ctx)
 
 	c.Next()
 }
 
+// adminMiddleware allows request only to admins. It must follow authMiddleware.
+func (h *Handler) adminMiddleware(c *gin.Context) {
+	userId, ok := c.Request.Context().Value(domain.UserIdKey).(int64)
+	if !ok {
+		newErrorResponse(c, http.StatusUnauthorized, "adminMiddleware", "get user id error", domain.ErrInvalidId)
+		return
+	}
+
+	// fail closed: a user who can't be looked up is never treated as admin
+	user, err := h.services.GetUserService().GetById(c.Request.Context(), userId)
+	if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrNotExist) {
+		newErrorResponse(c, http.StatusUnauthorized, "adminMiddleware", "user not found", err)
+		return
+	}
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "adminMiddleware", "can't check role", err)
+		return
+	}
+
+	if user.Role != domain.RoleAdmin {
+		newErrorResponse(c, http.StatusForbidden, "adminMiddleware", "not an admin", domain.ErrForbidden)
+		return
+	}
+
+	c.Next()
+}
+
 func getTokenFromRequest(c *gin.Context) (string, error) {
 	header := c.Request.Header.Get("Authorization")
 	if header == "" {
@@ -0,0 +1,178 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initPayee(router *gin.RouterGroup) {
+	payee := router.Group("/payees")
+	{
+		payee.Use(h.authMiddleware)
+
+		payee.POST("/", h.CreatePayee)
+		payee.PUT("/", h.CreatePayee)
+		payee.GET("/", h.GetPayees)
+		payee.GET("/:id", h.GetPayeeById)
+		payee.POST("/:id", h.UpdatePayee)
+		payee.PUT("/:id", h.UpdatePayee)
+		payee.DELETE("/:id", h.DeletePayee)
+	}
+}
+
+// CreatePayee godoc
+// @Summary     Create payee
+// @Description Save a payee and confirm its name against the holder of the account
+// @Security    ApiKeyAuth
+// @Tags        payee
+// @Accept      json
+// @Produce     json
+// @Param       input               body     domain.PayeeInput true "payee info"
+// @Success     201                 {object} domain.Payee
+// @Failure     400,401,403,409,500 {object} rest.errorResponse
+// @Router      /payees [post]
+func (h *Handler) CreatePayee(c *gin.Context) {
+	var input domain.PayeeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "CreatePayee()", "binding error", err)
+		return
+	}
+
+	payee, err := h.services.GetPayeeService().Create(c.Request.Context(), input)
+	if err != nil {
+		newPayeeErrorResponse(c, "CreatePayee()", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, payee)
+}
+
+// GetPayees godoc
+// @Summary     Get payees
+// @Description Get all user's payees list
+// @Security    ApiKeyAuth
+// @Tags        payee
+// @Produce     json
+// @Success     200     {object} []domain.Payee
+// @Failure     401,500 {object} rest.errorResponse
+// @Router      /payees [get]
+func (h *Handler) GetPayees(c *gin.Context) {
+	payees, err := h.services.GetPayeeService().List(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "GetPayees()", "service error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payees)
+}
+
+// GetPayeeById godoc
+// @Summary     Get payee
+// @Description Get user's payee by id
+// @Security    ApiKeyAuth
+// @Tags        payee
+// @Produce     json
+// @Param       id              path     string true "payee id"
+// @Success     200             {object} domain.Payee
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /payees/{id} [get]
+func (h *Handler) GetPayeeById(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetPayeeById()", "parsing id error", err)
+		return
+	}
+
+	payee, err := h.services.GetPayeeService().GetById(c.Request.Context(), id)
+	if err != nil {
+		newPayeeErrorResponse(c, "GetPayeeById()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payee)
+}
+
+// UpdatePayee godoc
+// @Summary     Update payee
+// @Description Update user's payee by id. Name is confirmed again if name or IBAN changed.
+// @Security    ApiKeyAuth
+// @Tags        payee
+// @Accept      json
+// @Produce     json
+// @Param       id                  path     string                  true "payee id"
+// @Param       input               body     domain.PayeeUpdateInput true "payee update info"
+// @Success     200                 {object} domain.Payee
+// @Failure     400,401,404,409,500 {object} rest.errorResponse
+// @Router      /payees/{id} [post]
+func (h *Handler) UpdatePayee(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "UpdatePayee()", "parsing id error", err)
+		return
+	}
+
+	var input domain.PayeeUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "UpdatePayee()", "binding error", err)
+		return
+	}
+
+	payee, err := h.services.GetPayeeService().UpdateById(c.Request.Context(), id, input)
+	if err != nil {
+		newPayeeErrorResponse(c, "UpdatePayee()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payee)
+}
+
+// DeletePayee godoc
+// @Summary     Delete payee
+// @Description Delete user's payee by id
+// @Security    ApiKeyAuth
+// @Tags        payee
+// @Produce     json
+// @Param       id              path     string true "payee id"
+// @Success     200             {object} rest.statusResponse
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /payees/{id} [delete]
+func (h *Handler) DeletePayee(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "DeletePayee()", "parsing id error", err)
+		return
+	}
+
+	if err := h.services.GetPayeeService().DeleteById(c.Request.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrDeleteFailed):
+			newErrorResponse(c, http.StatusNotFound, "DeletePayee()", "service error", err)
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, "DeletePayee()", "service error", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"OK"})
+}
+
+func newPayeeErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrNotExist):
+		newErrorResponse(c, http.StatusNotFound, context, problem, err)
+	case errors.Is(err, domain.ErrPayeeAlreadyExists):
+		newErrorResponse(c, http.StatusConflict, context, problem, err)
+	case errors.Is(err, domain.ErrMfaRequired):
+		newErrorResponse(c, http.StatusForbidden, context, problem, err)
+	case errors.Is(err, domain.ErrInvalidIban),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrUpdateFailed):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
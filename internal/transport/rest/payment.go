@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePayment godoc
+// @Summary     Create payment order
+// @Description Debit user's account and queue transfer to another bank
+// @Security    ApiKeyAuth
+// @Tags        payment
+// @Accept      json
+// @Produce     json
+// @Param       id                      path     string                   true "account id"
+// @Param       input                   body     domain.PaymentOrderInput true "payment info"
+// @Success     201                     {object} domain.PaymentOrder
+// @Failure     400,401,403,404,422,500 {object} rest.errorResponse
+// @Router      /account/{id}/payments [post]
+func (h *Handler) CreatePayment(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "CreatePayment()", "parsing id error", err)
+		return
+	}
+
+	var input domain.PaymentOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "CreatePayment()", "binding error", err)
+		return
+	}
+
+	order, err := h.services.GetPaymentService().Create(c.Request.Context(), id, input)
+	if err != nil {
+		newTransactionErrorResponse(c, "CreatePayment()", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// GetPayments godoc
+// @Summary     Get payment orders
+// @Description Get outgoing payment orders of user's account with their statuses
+// @Security    ApiKeyAuth
+// @Tags        payment
+// @Produce     json
+// @Param       id              path     string true "account id"
+// @Success     200             {object} []domain.PaymentOrder
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /account/{id}/payments [get]
+func (h *Handler) GetPayments(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetPayments()", "parsing id error", err)
+		return
+	}
+
+	orders, err := h.services.GetPaymentService().List(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotExist):
+			newErrorResponse(c, http.StatusNotFound, "GetPayments()", "service error", err)
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, "GetPayments()", "service error", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// problemTypePrefix is prepended to codes to build type URIs of problems, relative to the API.
+const problemTypePrefix = "/problems/"
+
+const (
+	codeValidationFailed = "validation_failed"
+	codeInternal         = "internal_error"
+)
+
+type problemType struct {
+	err   error
+	code  string
+	title string
+}
+
+// problemTypes map domain errors to stable codes. Errors are matched in order with errors.Is,
+// so wrapping errors go before the ones they wrap.
+var problemTypes = []problemType{
+	{domain.ErrNotExist, "not_found", "Resource not found"},
+	{domain.ErrUpdateFailed, "update_failed", "Update failed"},
+	{domain.ErrDeleteFailed, "delete_failed", "Delete failed"},
+	{domain.ErrInvalidId, "invalid_id", "Invalid id"},
+	{domain.ErrUserNotFound, "user_not_found", "User not found"},
+	{domain.ErrInvalidClaims, "invalid_claims", "Invalid token claims"},
+	{domain.ErrInvalidToken, "invalid_token", "Invalid token"},
+	{domain.ErrUserAlreadyExists, "user_already_exists", "User already exists"},
+	{domain.ErrAccessTokenExpired, "access_token_expired", "Access token expired"},
+	{domain.ErrRefreshTokenExpired, "refresh_token_expired", "Refresh token expired"},
+	{domain.ErrInsufficientFunds, "insufficient_funds", "Insufficient funds"},
+	{domain.ErrCurrencyMismatch, "currency_mismatch", "Currency mismatch"},
+	{domain.ErrSameAccount, "same_account", "Same source and destination account"},
+	{domain.ErrInvalidPeriod, "invalid_period", "Invalid period"},
+	{domain.ErrInvalidDate, "invalid_date", "Invalid date"},
+	{domain.ErrInvalidIban, "invalid_iban", "Invalid IBAN"},
+	{domain.ErrIbanAlreadyExists, "iban_already_exists", "IBAN already exists"},
+	{domain.ErrPayeeAlreadyExists, "payee_already_exists", "Payee already exists"},
+	{domain.ErrLimitExceeded, "limit_exceeded", "Limit exceeded"},
+	{domain.ErrLimitRaise, "limit_raise_forbidden", "Limits can only be lowered"},
+	{domain.ErrForbidden, "forbidden", "Forbidden"},
+	{domain.ErrRiskChallenged, "risk_challenged", "Operation held for review"},
+	{domain.ErrRiskBlocked, "risk_blocked", "Operation blocked"},
+	{domain.ErrMfaRequired, "mfa_required", "Second factor required"},
+	{domain.ErrInvalidMfaCode, "invalid_mfa_code", "Invalid second factor code"},
+	{domain.ErrMfaNotEnrolled, "mfa_not_enrolled", "TOTP not enrolled"},
+	{domain.ErrMfaAlreadyEnabled, "mfa_already_enabled", "TOTP already enabled"},
+	{domain.ErrMfaLocked, "mfa_locked", "Too many invalid second factor codes"},
+	{domain.ErrEmailNotVerified, "email_not_verified", "Email not verified"},
+	{domain.ErrEmailVerified, "email_already_verified", "Email already verified"},
+	{domain.ErrWrongPassword, "wrong_password", "Wrong password"},
+	{domain.ErrBalanceNotZero, "balance_not_zero", "Account balance is not zero"},
+	{domain.ErrAccountInUse, "account_in_use", "Account has history"},
+	{domain.ErrInvalidWebhookURL, "invalid_webhook_url", "Invalid webhook URL"},
+	{domain.ErrTooManyRequests, "too_many_requests", "Too many requests"},
+}
+
+// statusCodes are codes of errors unknown to problemTypes, by response status.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "request_too_large",
+	http.StatusUnprocessableEntity:   "unprocessable_entity",
+	http.StatusTooManyRequests:       "too_many_requests",
+}
+
+type invalidParam struct {
+	Name   string `json:"name" example:"amount"`
+	Reason string `json:"reason" example:"must be greater than 0"`
+}
+
+// newProblem describes the error. Known domain errors get their own code and type, validation
+// errors list invalid params. Details of other errors are never sent, as they may contain
+// internals such as database errors.
+func newProblem(status int, err error) errorResponse {
+	p := errorResponse{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+
+	if params, ok := invalidParams(err); ok {
+		p.Type = problemTypePrefix + codeValidationFailed
+		p.Title = "Request validation failed"
+		p.Code = codeValidationFailed
+		p.Detail = "request has invalid params"
+		p.InvalidParams = params
+		return p
+	}
+
+	for _, t := range problemTypes {
+		if errors.Is(err, t.err) {
+			p.Type = problemTypePrefix + t.code
+			p.Title = t.title
+			p.Code = t.code
+			p.Detail = err.Error()
+			break
+		}
+	}
+
+	var limitErr *domain.LimitExceededError
+	if errors.As(err, &limitErr) {
+		p.Limit = limitErr.Limit
+		p.Remaining = &limitErr.Remaining
+	}
+
+	var riskErr *domain.RiskError
+	if errors.As(err, &riskErr) {
+		p.Decision = riskErr.Decision
+		p.Rules = riskErr.Rules
+		p.ReviewId = riskErr.ReviewId
+	}
+
+	switch {
+	case p.Code != "":
+	case status >= http.StatusInternalServerError:
+		p.Code = codeInternal
+		p.Detail = "internal error"
+	default:
+		p.Code = statusCodes[status]
+		if p.Code == "" {
+			p.Code = "client_error"
+		}
+		p.Detail = clientDetail(status, err)
+	}
+
+	return p
+}
+
+// clientDetail describes a client error unknown to problemTypes without its message.
+func clientDetail(status int, err error) string {
+	var (
+		syntaxErr *json.SyntaxError
+		numErr    *strconv.NumError
+	)
+	if errors.As(err, &syntaxErr) || errors.As(err, &numErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return "malformed request"
+	}
+
+	return strings.ToLower(http.StatusText(status))
+}
+
+// invalidParams lists fields failed validation or decoding of JSON body.
+func invalidParams(err error) ([]invalidParam, bool) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		params := make([]invalidParam, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			params = append(params, invalidParam{Name: fe.Field(), Reason: validationReason(fe)})
+		}
+		return params, true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []invalidParam{{Name: typeErr.Field, Reason: "must be " + typeErr.Type.String()}}, true
+	}
+
+	return nil, false
+}
+
+func validationReason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_without_all":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte", "min":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte", "max":
+		return "must be at most " + fe.Param()
+	case "nefield":
+		return "must differ from " + fe.Param()
+	default:
+		return fmt.Sprintf("failed on %q", fe.Tag())
+	}
+}
+
+// registerJSONNames makes validation errors name fields as they are named in JSON body.
+func registerJSONNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return f.Name
+		}
+		return name
+	})
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRiskReviews godoc
+// @Summary     Get risk reviews
+// @Description Get challenged and blocked operations by review status. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Produce     json
+// @Param       status      query    string false "pending (default), approved, rejected or used"
+// @Success     200         {object} []domain.RiskReview
+// @Failure     401,403,500 {object} rest.errorResponse
+// @Router      /admin/risk-reviews [get]
+func (h *Handler) GetRiskReviews(c *gin.Context) {
+	reviews, err := h.services.GetRiskService().List(c.Request.Context(), c.DefaultQuery("status", domain.RiskReviewPending))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "GetRiskReviews()", "service error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// ApproveRiskReview godoc
+// @Summary     Approve risk review
+// @Description Approve pending operation, so the user can retry it once. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Produce     json
+// @Param       id                  path     string true "review id"
+// @Success     200                 {object} domain.RiskReview
+// @Failure     400,401,403,404,500 {object} rest.errorResponse
+// @Router      /admin/risk-reviews/{id}/approve [post]
+func (h *Handler) ApproveRiskReview(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ApproveRiskReview()", "parsing id error", err)
+		return
+	}
+
+	review, err := h.services.GetRiskService().Approve(c.Request.Context(), id)
+	if err != nil {
+		newRiskReviewErrorResponse(c, "ApproveRiskReview()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// RejectRiskReview godoc
+// @Summary     Reject risk review
+// @Description Reject pending operation. Admins only.
+// @Security    ApiKeyAuth
+// @Tags        admin
+// @Produce     json
+// @Param       id                  path     string true "review id"
+// @Success     200                 {object} domain.RiskReview
+// @Failure     400,401,403,404,500 {object} rest.errorResponse
+// @Router      /admin/risk-reviews/{id}/reject [post]
+func (h *Handler) RejectRiskReview(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "RejectRiskReview()", "parsing id error", err)
+		return
+	}
+
+	review, err := h.services.GetRiskService().Reject(c.Request.Context(), id)
+	if err != nil {
+		newRiskReviewErrorResponse(c, "RejectRiskReview()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+func newRiskReviewErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrNotExist):
+		newErrorResponse(c, http.StatusNotFound, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
+
+// newRiskErrorResponse responds with the decision, triggered rules and review id.
+func newRiskErrorResponse(c *gin.Context, context string, err error) {
+	newErrorResponse(c, http.StatusForbidden, context, "risk screening", err)
+}
@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/Viquad/crud-app/pkg/pdf"
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02 15:04"
+
+// GetStatements godoc
+// @Summary     Get statements
+// @Description Get list of generated statements of user's account
+// @Security    ApiKeyAuth
+// @Tags        statement
+// @Produce     json
+// @Param       id              path     string true "account id"
+// @Success     200             {object} []domain.StatementSummary
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /account/{id}/statements [get]
+func (h *Handler) GetStatements(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetStatements()", "parsing id error", err)
+		return
+	}
+
+	statements, err := h.services.GetStatementService().List(c.Request.Context(), id)
+	if err != nil {
+		newStatementErrorResponse(c, "GetStatements()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statements)
+}
+
+// GetStatement godoc
+// @Summary     Get statement
+// @Description Get statement of user's account for a month in JSON, CSV, HTML or PDF
+// @Security    ApiKeyAuth
+// @Tags        statement
+// @Produce     json,text/csv,text/html,application/pdf
+// @Param       id              path     string true  "account id"
+// @Param       period          path     string true  "month, e.g. 2022-08"
+// @Param       format          query    string false "json (default), csv, html or pdf"
+// @Success     200             {object} domain.Statement
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /account/{id}/statements/{period} [get]
+func (h *Handler) GetStatement(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetStatement()", "parsing id error", err)
+		return
+	}
+
+	statement, err := h.services.GetStatementService().Get(c.Request.Context(), id, c.Param("period"))
+	if err != nil {
+		newStatementErrorResponse(c, "GetStatement()", err)
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s", statement.AccountId, statement.Period)
+
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		c.JSON(http.StatusOK, statement)
+	case "csv":
+		data, err := renderStatementCSV(statement)
+		if err != nil {
+			newErrorResponse(c, http.StatusInternalServerError, "GetStatement()", "render error", err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+		c.Data(http.StatusOK, "text/csv", data)
+	case "html":
+		data, err := renderStatementHTML(statement)
+		if err != nil {
+			newErrorResponse(c, http.StatusInternalServerError, "GetStatement()", "render error", err)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", data)
+	case "pdf":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", filename))
+		c.Data(http.StatusOK, "application/pdf", renderStatementPDF(statement))
+	default:
+		newErrorResponse(c, http.StatusBadRequest, "GetStatement()", "parsing format error", fmt.Errorf("unknown format %q", format))
+	}
+}
+
+func newStatementErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrNotExist):
+		newErrorResponse(c, http.StatusNotFound, context, problem, err)
+	case errors.Is(err, domain.ErrInvalidPeriod):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
+
+// formatAmount formats amount of minor units as decimal with two fraction digits.
+func formatAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+func renderStatementCSV(s *domain.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := csv.NewWriter(&buf)
+	records := [][]string{
+		{"date", "type", "reference", "description", "amount", "balance"},
+		{s.From.Format(dateLayout), "opening", "", "Opening balance", "", formatAmount(s.OpeningBalance)},
+	}
+
+	for _, l := range s.Lines {
+		records = append(records, []string{
+			l.Date.Format(dateLayout), l.Type, l.Reference, l.Description, formatAmount(l.Amount), formatAmount(l.Balance),
+		})
+	}
+
+	records = append(records, []string{s.To.Format(dateLayout), "closing", "", "Closing balance", "", formatAmount(s.ClosingBalance)})
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var statementTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"amount": formatAmount,
+	"date":   func(t interface{ Format(string) string }) string { return t.Format(dateLayout) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Statement {{.Period}} - account #{{.AccountId}}</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+td.amount, th.amount { text-align: right; }
+@media print { body { margin: 0; } @page { size: A4; margin: 15mm; } }
+</style>
+</head>
+<body>
+<h1>Account statement</h1>
+<p>
+Account: #{{.AccountId}} ({{.Type}}, {{.Currency}})<br>
+Period: {{date .From}} &ndash; {{date .To}}<br>
+Generated: {{date .GeneratedAt}}
+</p>
+<table>
+<tr><th>Date</th><th>Type</th><th>Description</th><th>Reference</th><th class="amount">Amount</th><th class="amount">Balance</th></tr>
+<tr><td>{{date .From}}</td><td colspan="4">Opening balance</td><td class="amount">{{amount .OpeningBalance}}</td></tr>
+{{range .Lines}}<tr><td>{{date .Date}}</td><td>{{.Type}}</td><td>{{.Description}}</td><td>{{.Reference}}</td><td class="amount">{{amount .Amount}}</td><td class="amount">{{amount .Balance}}</td></tr>
+{{end}}<tr><td>{{date .To}}</td><td colspan="4">Closing balance</td><td class="amount">{{amount .ClosingBalance}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+func renderStatementHTML(s *domain.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := statementTemplate.Execute(&buf, s); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func renderStatementPDF(s *domain.Statement) []byte {
+	var (
+		buf bytes.Buffer
+		doc = pdf.New()
+		row = func(date, kind, description, amount, balance string) string {
+			if r := []rune(description); len(r) > 32 {
+				description = string(r[:29]) + "..."
+			}
+			return fmt.Sprintf("%-16s  %-10s  %-32s  %14s  %14s", date, kind, description, amount, balance)
+		}
+	)
+
+	doc.Heading("Account statement")
+	doc.Line("")
+	doc.Line("Account:   #" + strconv.FormatInt(s.AccountId, 10) + " (" + s.Type + ", " + s.Currency + ")")
+	doc.Line("Period:    " + s.From.Format(dateLayout) + " - " + s.To.Format(dateLayout))
+	doc.Line("Generated: " + s.GeneratedAt.Format(dateLayout))
+	doc.Line("")
+	doc.Heading(row("Date", "Type", "Description", "Amount", "Balance"))
+	doc.Line(row(s.From.Format(dateLayout), "", "Opening balance", "", formatAmount(s.OpeningBalance)))
+	for _, l := range s.Lines {
+		doc.Line(row(l.Date.Format(dateLayout), l.Type, l.Description, formatAmount(l.Amount), formatAmount(l.Balance)))
+	}
+	doc.Line(row(s.To.Format(dateLayout), "", "Closing balance", "", formatAmount(s.ClosingBalance)))
+
+	// writing to bytes.Buffer never fails
+	doc.WriteTo(&buf)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,84 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat is the interval of comments sent to keep idle connections open through proxies.
+const streamHeartbeat = 15 * time.Second
+
+func (h *Handler) initStream(router *gin.RouterGroup) {
+	router.GET("/stream", h.authMiddleware, h.Stream)
+}
+
+// Stream godoc
+// @Summary     Stream account events
+// @Description Push events of user's accounts as Server-Sent Events: BalanceChanged, AccountCreated, AccountUpdated, AccountClosed.
+// @Description Reconnect with Last-Event-ID header to receive events missed meanwhile. The stream ends when the access token expires.
+// @Security    ApiKeyAuth
+// @Tags        stream
+// @Produce     text/event-stream
+// @Param       Last-Event-ID   header   string false "id of the last received event"
+// @Success     200             {string} string "event stream"
+// @Failure     400,401,500     {object} rest.errorResponse
+// @Router      /stream [get]
+func (h *Handler) Stream(c *gin.Context) {
+	var lastEventId int64
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, "Stream()", "parsing Last-Event-ID error", err)
+			return
+		}
+		lastEventId = id
+	}
+
+	ctx := c.Request.Context()
+	events, err := h.services.GetStreamService().Subscribe(ctx, lastEventId)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "Stream()", "service error", err)
+		return
+	}
+
+	expired := make(<-chan time.Time)
+	if expiresAt, ok := ctx.Value(domain.TokenExpiresAtKey).(time.Time); ok {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, e.Payload)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		case <-expired:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		c.Writer.Flush()
+	}
+}
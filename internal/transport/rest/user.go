@@ -0,0 +1,223 @@
+package rest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initMe(router *gin.RouterGroup) {
+	me := router.Group("/me")
+	{
+		me.Use(h.authMiddleware)
+
+		me.GET("", h.GetProfile)
+		me.PATCH("", h.UpdateProfile)
+		me.POST("/password", h.ChangePassword)
+		me.POST("/email", h.ChangeEmail)
+		me.GET("/export", h.ExportData)
+		me.GET("/limits", h.GetUserLimits)
+		me.DELETE("", h.EraseUser)
+	}
+}
+
+// GetProfile godoc
+// @Summary     Get profile
+// @Description Get signed in user's profile
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Produce     json
+// @Success     200     {object} domain.User
+// @Failure     401,500 {object} rest.errorResponse
+// @Router      /me [get]
+func (h *Handler) GetProfile(c *gin.Context) {
+	user, err := h.services.GetUserService().GetProfile(c.Request.Context())
+	if err != nil {
+		newProfileErrorResponse(c, "GetProfile()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary     Update profile
+// @Description Update name and phone of signed in user. Omitted fields are left unchanged.
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Accept      json
+// @Produce     json
+// @Param       input       body     domain.UserUpdateInput true "profile update info"
+// @Success     200         {object} domain.User
+// @Failure     400,401,500 {object} rest.errorResponse
+// @Router      /me [patch]
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	var input domain.UserUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "UpdateProfile()", "binding error", err)
+		return
+	}
+
+	user, err := h.services.GetUserService().UpdateProfile(c.Request.Context(), input)
+	if err != nil {
+		newProfileErrorResponse(c, "UpdateProfile()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword godoc
+// @Summary     Change password
+// @Description Change password of signed in user. All other sessions are revoked, new tokens are returned.
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Accept      json
+// @Produce     json
+// @Param       input           body     domain.ChangePasswordInput true "old and new password"
+// @Success     201             {object} rest.authResponse
+// @Failure     400,401,403,500 {object} rest.errorResponse
+// @Router      /me/password [post]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var input domain.ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ChangePassword()", "binding error", err)
+		return
+	}
+
+	accessToken, refreshToken, err := h.services.GetUserService().ChangePassword(c.Request.Context(), input)
+	if err != nil {
+		newProfileErrorResponse(c, "ChangePassword()", err)
+		return
+	}
+
+	c.Header("Set-Cookie", fmt.Sprintf("refresh-token=%s; HttpOnly", refreshToken))
+	c.JSON(http.StatusCreated, authResponse{Token: accessToken})
+}
+
+// ChangeEmail godoc
+// @Summary     Change email
+// @Description Change email of signed in user. Verification link is sent to the new address.
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Accept      json
+// @Produce     json
+// @Param       input               body     domain.ChangeEmailInput true "new email and password"
+// @Success     200                 {object} rest.statusResponse
+// @Failure     400,401,403,409,500 {object} rest.errorResponse
+// @Router      /me/email [post]
+func (h *Handler) ChangeEmail(c *gin.Context) {
+	var input domain.ChangeEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ChangeEmail()", "binding error", err)
+		return
+	}
+
+	if err := h.services.GetUserService().ChangeEmail(c.Request.Context(), input); err != nil {
+		newProfileErrorResponse(c, "ChangeEmail()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+// ExportData godoc
+// @Summary     Export personal data
+// @Description Download ZIP with profile, accounts, transactions, payees and sessions of signed in user as JSON files
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Produce     application/zip
+// @Success     200         {file}   file
+// @Failure     401,403,500 {object} rest.errorResponse
+// @Router      /me/export [get]
+func (h *Handler) ExportData(c *gin.Context) {
+	export, err := h.services.GetPrivacyService().Export(c.Request.Context())
+	if err != nil {
+		newProfileErrorResponse(c, "ExportData()", err)
+		return
+	}
+
+	data, err := renderExportZip(export)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "ExportData()", "render error", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user-%d-export.zip", export.Profile.Id))
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// EraseUser godoc
+// @Summary     Erase user
+// @Description Pseudonymize personal data of signed in user and sign out everywhere. Ledger is kept. All accounts must have zero balance.
+// @Security    ApiKeyAuth
+// @Tags        me
+// @Produce     json
+// @Success     200             {object} rest.statusResponse
+// @Failure     401,403,409,500 {object} rest.errorResponse
+// @Router      /me [delete]
+func (h *Handler) EraseUser(c *gin.Context) {
+	if err := h.services.GetPrivacyService().Erase(c.Request.Context()); err != nil {
+		newProfileErrorResponse(c, "EraseUser()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"ok"})
+}
+
+func renderExportZip(export *domain.UserExport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"accounts.json", export.Accounts},
+		{"transactions.json", export.Transactions},
+		{"payees.json", export.Payees},
+		{"sessions.json", export.Sessions},
+	}
+
+	for _, f := range files {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(f.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newProfileErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrWrongPassword), errors.Is(err, domain.ErrMfaRequired):
+		newErrorResponse(c, http.StatusForbidden, context, problem, err)
+	case errors.Is(err, domain.ErrUserAlreadyExists), errors.Is(err, domain.ErrBalanceNotZero):
+		newErrorResponse(c, http.StatusConflict, context, problem, err)
+	case errors.Is(err, domain.ErrUpdateFailed):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	case errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrInvalidId):
+		newErrorResponse(c, http.StatusUnauthorized, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
@@ -0,0 +1,231 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Viquad/crud-app/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) initWebhook(router *gin.RouterGroup) {
+	webhook := router.Group("/webhooks")
+	{
+		webhook.Use(h.authMiddleware)
+
+		webhook.POST("/", h.CreateWebhook)
+		webhook.PUT("/", h.CreateWebhook)
+		webhook.GET("/", h.GetWebhooks)
+		webhook.GET("/:id", h.GetWebhookById)
+		webhook.POST("/:id", h.UpdateWebhook)
+		webhook.PUT("/:id", h.UpdateWebhook)
+		webhook.DELETE("/:id", h.DeleteWebhook)
+		webhook.GET("/:id/deliveries", h.GetWebhookDeliveries)
+		webhook.POST("/:id/deliveries/:delivery_id/resend", h.ResendWebhookDelivery)
+	}
+}
+
+// CreateWebhook godoc
+// @Summary     Create webhook
+// @Description Register URL receiving signed events of the given types. The signing secret is returned only once.
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Accept      json
+// @Produce     json
+// @Param       input           body     domain.WebhookInput true "webhook info"
+// @Success     201             {object} domain.Webhook
+// @Failure     400,401,403,500 {object} rest.errorResponse
+// @Router      /webhooks [post]
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var input domain.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "CreateWebhook()", "binding error", err)
+		return
+	}
+
+	webhook, err := h.services.GetWebhookService().Create(c.Request.Context(), input)
+	if err != nil {
+		newWebhookErrorResponse(c, "CreateWebhook()", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhooks godoc
+// @Summary     Get webhooks
+// @Description Get all user's webhooks list
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Produce     json
+// @Success     200     {object} []domain.Webhook
+// @Failure     401,500 {object} rest.errorResponse
+// @Router      /webhooks [get]
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.services.GetWebhookService().List(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, "GetWebhooks()", "service error", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhookById godoc
+// @Summary     Get webhook
+// @Description Get user's webhook by id
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Produce     json
+// @Param       id              path     string true "webhook id"
+// @Success     200             {object} domain.Webhook
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /webhooks/{id} [get]
+func (h *Handler) GetWebhookById(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetWebhookById()", "parsing id error", err)
+		return
+	}
+
+	webhook, err := h.services.GetWebhookService().GetById(c.Request.Context(), id)
+	if err != nil {
+		newWebhookErrorResponse(c, "GetWebhookById()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary     Update webhook
+// @Description Change URL or event types of user's webhook, disable it or enable it again
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Accept      json
+// @Produce     json
+// @Param       id                  path     string                    true "webhook id"
+// @Param       input               body     domain.WebhookUpdateInput true "webhook update info"
+// @Success     200                 {object} domain.Webhook
+// @Failure     400,401,403,404,500 {object} rest.errorResponse
+// @Router      /webhooks/{id} [post]
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "UpdateWebhook()", "parsing id error", err)
+		return
+	}
+
+	var input domain.WebhookUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "UpdateWebhook()", "binding error", err)
+		return
+	}
+
+	webhook, err := h.services.GetWebhookService().UpdateById(c.Request.Context(), id, input)
+	if err != nil {
+		newWebhookErrorResponse(c, "UpdateWebhook()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary     Delete webhook
+// @Description Delete user's webhook by id with its deliveries
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Produce     json
+// @Param       id              path     string true "webhook id"
+// @Success     200             {object} rest.statusResponse
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "DeleteWebhook()", "parsing id error", err)
+		return
+	}
+
+	if err := h.services.GetWebhookService().DeleteById(c.Request.Context(), id); err != nil {
+		newWebhookErrorResponse(c, "DeleteWebhook()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{"OK"})
+}
+
+// GetWebhookDeliveries godoc
+// @Summary     Get webhook deliveries
+// @Description Get the latest deliveries of user's webhook with results of the last attempts
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Produce     json
+// @Param       id              path     string true "webhook id"
+// @Success     200             {object} []domain.WebhookDelivery
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /webhooks/{id}/deliveries [get]
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "GetWebhookDeliveries()", "parsing id error", err)
+		return
+	}
+
+	deliveries, err := h.services.GetWebhookService().ListDeliveries(c.Request.Context(), id)
+	if err != nil {
+		newWebhookErrorResponse(c, "GetWebhookDeliveries()", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ResendWebhookDelivery godoc
+// @Summary     Resend webhook delivery
+// @Description Queue the delivery again with attempts counted from zero
+// @Security    ApiKeyAuth
+// @Tags        webhook
+// @Produce     json
+// @Param       id              path     string true "webhook id"
+// @Param       delivery_id     path     string true "delivery id"
+// @Success     202             {object} domain.WebhookDelivery
+// @Failure     400,401,404,500 {object} rest.errorResponse
+// @Router      /webhooks/{id}/deliveries/{delivery_id}/resend [post]
+func (h *Handler) ResendWebhookDelivery(c *gin.Context) {
+	id, err := parseId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ResendWebhookDelivery()", "parsing id error", err)
+		return
+	}
+
+	deliveryId, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "ResendWebhookDelivery()", "parsing delivery id error", err)
+		return
+	}
+
+	delivery, err := h.services.GetWebhookService().Resend(c.Request.Context(), id, deliveryId)
+	if err != nil {
+		newWebhookErrorResponse(c, "ResendWebhookDelivery()", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func newWebhookErrorResponse(c *gin.Context, context string, err error) {
+	problem := "service error"
+	switch {
+	case errors.Is(err, domain.ErrNotExist), errors.Is(err, domain.ErrDeleteFailed):
+		newErrorResponse(c, http.StatusNotFound, context, problem, err)
+	case errors.Is(err, domain.ErrMfaRequired):
+		newErrorResponse(c, http.StatusForbidden, context, problem, err)
+	case errors.Is(err, domain.ErrInvalidWebhookURL), errors.Is(err, domain.ErrUpdateFailed):
+		newErrorResponse(c, http.StatusBadRequest, context, problem, err)
+	default:
+		newErrorResponse(c, http.StatusInternalServerError, context, problem, err)
+	}
+}
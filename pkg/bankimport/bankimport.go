@@ -0,0 +1,108 @@
+package bankimport
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Entry is a booked entry of an external bank statement. Amount is signed and in minor units.
+type Entry struct {
+	ExternalRef string
+	Date        time.Time
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+type Parser interface {
+	Parse(r io.Reader) ([]Entry, error)
+}
+
+// NewParser returns parser of the format. Mapping is used for CSV only.
+func NewParser(format string, mapping CSVMapping) (Parser, error) {
+	switch format {
+	case FormatCSV:
+		return &CSVParser{Mapping: mapping}, nil
+	case FormatOFX:
+		return &OFXParser{}, nil
+	case FormatCAMT053:
+		return &CAMT053Parser{}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ParseAmount parses decimal amount like "-1234.5" or "1234,50" into minor units without rounding.
+func ParseAmount(s string) (int64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	units, fraction := s, ""
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		// the last separator is decimal, others group thousands
+		units = strings.NewReplacer(".", "", ",", "").Replace(s[:i])
+		fraction = s[i+1:]
+	}
+
+	if units == "" || len(fraction) > 2 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	value, err := strconv.ParseInt(units+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return value, nil
+}
+
+// fingerprint builds reference for entries which have none, so reimport of the same file is detected.
+// n distinguishes equal entries within one file.
+func fingerprint(e Entry, n int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%d|%s|%s|%d", e.Date.Format("2006-01-02"), e.Amount, e.Currency, e.Description, n)))
+	return fmt.Sprintf("sha1:%x", sum)
+}
+
+// fillReferences sets fingerprints as references of entries without one.
+func fillReferences(entries []Entry) {
+	seen := make(map[string]int)
+	for i := range entries {
+		if entries[i].ExternalRef != "" {
+			continue
+		}
+
+		key := fingerprint(entries[i], 0)
+		entries[i].ExternalRef = fingerprint(entries[i], seen[key])
+		seen[key]++
+	}
+}
@@ -0,0 +1,122 @@
+package bankimport
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CAMT053Parser parses booked entries of ISO 20022 camt.053 bank-to-customer statement.
+type CAMT053Parser struct{}
+
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Ref    string `xml:"NtryRef"`
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	Status      struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate    string `xml:"BookgDt>Dt"`
+	BookingTime    string `xml:"BookgDt>DtTm"`
+	ServicerRef    string `xml:"AcctSvcrRef"`
+	AdditionalInfo string `xml:"AddtlNtryInf"`
+	Details        []struct {
+		EndToEndId   string   `xml:"Refs>EndToEndId"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+func (p *CAMT053Parser) Parse(r io.Reader) ([]Entry, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("camt.053: %w", err)
+	}
+
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("camt.053: statement not found")
+	}
+
+	var entries []Entry
+	for _, stmt := range doc.Statements {
+		for _, e := range stmt.Entries {
+			// pending and informational entries are not on the account yet
+			if status := strings.TrimSpace(e.Status.Value + e.Status.Code); status != "" && status != "BOOK" {
+				continue
+			}
+
+			entry, err := e.entry()
+			if err != nil {
+				return nil, err
+			}
+
+			entries = append(entries, entry)
+		}
+	}
+
+	fillReferences(entries)
+
+	return entries, nil
+}
+
+func (e *camtEntry) entry() (Entry, error) {
+	amount, err := ParseAmount(e.Amount.Value)
+	if err != nil {
+		return Entry{}, fmt.Errorf("camt.053: %w", err)
+	}
+
+	switch strings.TrimSpace(e.CreditDebit) {
+	case "CRDT":
+	case "DBIT":
+		amount = -amount
+	default:
+		return Entry{}, fmt.Errorf("camt.053: invalid credit debit indicator %q", e.CreditDebit)
+	}
+
+	var date time.Time
+	switch {
+	case e.BookingDate != "":
+		date, err = time.Parse("2006-01-02", strings.TrimSpace(e.BookingDate))
+	case e.BookingTime != "":
+		date, err = time.Parse(time.RFC3339, strings.TrimSpace(e.BookingTime))
+	default:
+		err = errors.New("booking date is missing")
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("camt.053: %w", err)
+	}
+
+	ref := strings.TrimSpace(e.ServicerRef)
+	description := strings.TrimSpace(e.AdditionalInfo)
+	for _, d := range e.Details {
+		if ref == "" && d.EndToEndId != "" && d.EndToEndId != "NOTPROVIDED" {
+			ref = strings.TrimSpace(d.EndToEndId)
+		}
+		if description == "" {
+			description = strings.TrimSpace(strings.Join(d.Unstructured, " "))
+		}
+	}
+	if ref == "" {
+		ref = strings.TrimSpace(e.Ref)
+	}
+
+	return Entry{
+		ExternalRef: ref,
+		Date:        date,
+		Amount:      amount,
+		Currency:    e.Amount.Currency,
+		Description: description,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package bankimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CSVMapping describes columns of CSV file. Column is either a header name or zero-based index.
+// Empty reference or currency column means the file has no such column.
+type CSVMapping struct {
+	Date        string `form:"date_column"`
+	Amount      string `form:"amount_column"`
+	Description string `form:"description_column"`
+	Reference   string `form:"reference_column"`
+	Currency    string `form:"currency_column"`
+	DateLayout  string `form:"date_layout"`
+	Delimiter   string `form:"delimiter"`
+	NoHeader    bool   `form:"no_header"`
+}
+
+type CSVParser struct {
+	Mapping CSVMapping
+}
+
+func (p *CSVParser) Parse(r io.Reader) ([]Entry, error) {
+	m := p.Mapping.withDefaults()
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	if delimiter, _ := utf8.DecodeRuneInString(m.Delimiter); delimiter != utf8.RuneError {
+		reader.Comma = delimiter
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var header []string
+	if !m.NoHeader && len(records) > 0 {
+		header, records = records[0], records[1:]
+	}
+
+	columns := make(map[string]int)
+	for name, column := range map[string]string{
+		"date": m.Date, "amount": m.Amount, "description": m.Description, "reference": m.Reference, "currency": m.Currency,
+	} {
+		if column == "" {
+			continue
+		}
+
+		index, err := columnIndex(header, column)
+		if err != nil {
+			return nil, err
+		}
+		columns[name] = index
+	}
+
+	if _, ok := columns["date"]; !ok {
+		return nil, errors.New("csv: date column is required")
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, errors.New("csv: amount column is required")
+	}
+
+	entries := make([]Entry, 0, len(records))
+	for line, record := range records {
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		date, err := time.Parse(m.DateLayout, value("date"))
+		if err != nil {
+			return nil, fmt.Errorf("csv: record %d: %w", line+1, err)
+		}
+
+		amount, err := ParseAmount(value("amount"))
+		if err != nil {
+			return nil, fmt.Errorf("csv: record %d: %w", line+1, err)
+		}
+
+		entries = append(entries, Entry{
+			ExternalRef: value("reference"),
+			Date:        date,
+			Amount:      amount,
+			Currency:    value("currency"),
+			Description: value("description"),
+		})
+	}
+
+	fillReferences(entries)
+
+	return entries, nil
+}
+
+func (m CSVMapping) withDefaults() CSVMapping {
+	if m.Date == "" {
+		m.Date = "date"
+	}
+	if m.Amount == "" {
+		m.Amount = "amount"
+	}
+	if m.DateLayout == "" {
+		m.DateLayout = "2006-01-02"
+	}
+
+	return m
+}
+
+func columnIndex(header []string, column string) (int, error) {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			return i, nil
+		}
+	}
+
+	if i, err := strconv.Atoi(column); err == nil && i >= 0 {
+		return i, nil
+	}
+
+	return 0, fmt.Errorf("csv: column %q not found", column)
+}
@@ -0,0 +1,120 @@
+package bankimport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var ofxTag = regexp.MustCompile(`<([A-Za-z0-9.]+)>([^<\r\n]*)`)
+
+// OFXParser parses bank transactions of OFX 1.x (SGML) and 2.x (XML) files.
+type OFXParser struct{}
+
+func (p *OFXParser) Parse(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(data)
+	upper := strings.ToUpper(text)
+	if !strings.Contains(upper, "<OFX>") {
+		return nil, errors.New("ofx: OFX element not found")
+	}
+
+	var (
+		entries  []Entry
+		currency string
+	)
+
+	if i := strings.Index(upper, "<CURDEF>"); i >= 0 {
+		currency = ofxFields(text[i:])["CURDEF"]
+	}
+
+	// SGML files may omit closing tags, so a transaction ends at the first of its closing tag,
+	// the next transaction or the end of transactions list
+	for i := strings.Index(upper, "<STMTTRN>"); i >= 0; {
+		start := i + len("<STMTTRN>")
+		end, next := len(text), -1
+		for _, marker := range []string{"</STMTTRN>", "<STMTTRN>", "</BANKTRANLIST>"} {
+			if j := strings.Index(upper[start:], marker); j >= 0 && start+j < end {
+				end = start + j
+			}
+		}
+		if j := strings.Index(upper[start:], "<STMTTRN>"); j >= 0 {
+			next = start + j
+		}
+
+		entry, err := ofxEntry(ofxFields(text[start:end]), currency)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+		i = next
+	}
+
+	fillReferences(entries)
+
+	return entries, nil
+}
+
+func ofxFields(text string) map[string]string {
+	fields := make(map[string]string)
+	for _, match := range ofxTag.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToUpper(match[1])
+		if _, ok := fields[tag]; !ok {
+			fields[tag] = strings.TrimSpace(match[2])
+		}
+	}
+
+	return fields
+}
+
+func ofxEntry(fields map[string]string, currency string) (Entry, error) {
+	date, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return Entry{}, err
+	}
+
+	amount, err := ParseAmount(fields["TRNAMT"])
+	if err != nil {
+		return Entry{}, fmt.Errorf("ofx: %w", err)
+	}
+
+	description := fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" {
+		description = strings.TrimSpace(description + " " + memo)
+	}
+
+	if c := fields["CURRENCY"]; c != "" {
+		currency = c
+	}
+
+	return Entry{
+		ExternalRef: fields["FITID"],
+		Date:        date,
+		Amount:      amount,
+		Currency:    currency,
+		Description: description,
+	}, nil
+}
+
+// parseOFXDate parses dates like 20220825, 20220825120000 or 20220825120000.000[-5:EST].
+func parseOFXDate(s string) (time.Time, error) {
+	if i := strings.IndexAny(s, ".["); i >= 0 {
+		s = s[:i]
+	}
+
+	for _, layout := range []string{"20060102150405", "200601021504", "20060102"} {
+		if len(s) == len(layout) {
+			return time.Parse(layout, s)
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("ofx: invalid date %q", s)
+}
@@ -1,22 +1,129 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/Viquad/crud-app/pkg/database"
+	"github.com/Viquad/crud-app/pkg/fee"
+	"github.com/Viquad/crud-app/pkg/interest"
+	"github.com/Viquad/crud-app/pkg/mailer"
+	"github.com/Viquad/crud-app/pkg/publisher"
+	"github.com/Viquad/crud-app/pkg/risk"
+	"github.com/Viquad/crud-app/pkg/tracing"
 	"github.com/spf13/viper"
 )
 
+// Limit is a default spending limit in the currency. Zero means unlimited.
+type Limit struct {
+	Currency       string `mapstructure:"currency"`
+	PerTransaction int64  `mapstructure:"per_transaction"`
+	Daily          int64  `mapstructure:"daily"`
+	Monthly        int64  `mapstructure:"monthly"`
+}
+
 type Config struct {
-	DB    database.ConnectionInfo `mapstructure:"db"`
-	Cache struct {
+	BaseURL string                  `mapstructure:"base_url"`
+	DB      database.ConnectionInfo `mapstructure:"db"`
+	Cache   struct {
 		TTL time.Duration `mapstructure:"ttl"`
 	} `mapstructure:"cache"`
 	Auth struct {
 		AccessTokenTTL  time.Duration `mapstructure:"access_ttl"`
 		RefreshTokenTTL time.Duration `mapstructure:"refresh_ttl"`
+		VerificationTTL time.Duration `mapstructure:"verification_ttl"`
+		ResetTTL        time.Duration `mapstructure:"reset_ttl"`
+		Secret          string        `mapstructure:"-"`
+		PasswordSalt    string        `mapstructure:"-"`
+		Reset           struct {
+			Queue      int           `mapstructure:"queue"`
+			Window     time.Duration `mapstructure:"window"`
+			EmailLimit int           `mapstructure:"email_limit"`
+			IpLimit    int           `mapstructure:"ip_limit"`
+		} `mapstructure:"reset"`
+		Mfa struct {
+			Issuer        string        `mapstructure:"issuer"`
+			ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`
+			MaxAge        time.Duration `mapstructure:"max_age"`
+			LargeTransfer int64         `mapstructure:"large_transfer"`
+			MaxAttempts   int           `mapstructure:"max_attempts"`
+			Lockout       time.Duration `mapstructure:"lockout"`
+		} `mapstructure:"mfa"`
 	} `mapstructure:"auth"`
+	Fees     fee.Schedule `mapstructure:"fees"`
+	Interest struct {
+		RunAt time.Duration  `mapstructure:"run_at"`
+		Rates interest.Rates `mapstructure:"rates"`
+	} `mapstructure:"interest"`
+	Statements struct {
+		RunAt time.Duration `mapstructure:"run_at"`
+	} `mapstructure:"statements"`
+	Payments struct {
+		RunAt     time.Duration `mapstructure:"run_at"`
+		OutputDir string        `mapstructure:"output_dir"`
+		Initiator string        `mapstructure:"initiator"`
+		Debtor    struct {
+			Name string `mapstructure:"name"`
+			IBAN string `mapstructure:"iban"`
+			BIC  string `mapstructure:"bic"`
+		} `mapstructure:"debtor"`
+	} `mapstructure:"payments"`
+	Iban struct {
+		CountryCode string `mapstructure:"country_code"`
+		BankCode    string `mapstructure:"bank_code"`
+	} `mapstructure:"iban"`
+	Limits     []Limit       `mapstructure:"limits"`
+	UserLimits []Limit       `mapstructure:"user_limits"`
+	Risk       risk.Rules    `mapstructure:"risk"`
+	Mail       mailer.Config `mapstructure:"mail"`
+	Outbox     struct {
+		PollInterval time.Duration    `mapstructure:"poll_interval"`
+		BatchSize    int              `mapstructure:"batch_size"`
+		MinBackoff   time.Duration    `mapstructure:"min_backoff"`
+		MaxBackoff   time.Duration    `mapstructure:"max_backoff"`
+		Lease        time.Duration    `mapstructure:"lease"`
+		Publisher    publisher.Config `mapstructure:"publisher"`
+	} `mapstructure:"outbox"`
+	Webhooks struct {
+		PollInterval     time.Duration `mapstructure:"poll_interval"`
+		BatchSize        int           `mapstructure:"batch_size"`
+		Concurrency      int           `mapstructure:"concurrency"`
+		Timeout          time.Duration `mapstructure:"timeout"`
+		Lease            time.Duration `mapstructure:"lease"`
+		MaxAttempts      int           `mapstructure:"max_attempts"`
+		DisableAfter     int           `mapstructure:"disable_after"`
+		MinBackoff       time.Duration `mapstructure:"min_backoff"`
+		MaxBackoff       time.Duration `mapstructure:"max_backoff"`
+		LargeTransaction int64         `mapstructure:"large_transaction"`
+		AllowInsecure    bool          `mapstructure:"allow_insecure"`
+	} `mapstructure:"webhooks"`
+	Stream struct {
+		Buffer       int           `mapstructure:"buffer"`
+		ReplayBatch  int           `mapstructure:"replay_batch"`
+		ReplayWindow time.Duration `mapstructure:"replay_window"`
+	} `mapstructure:"stream"`
+	GRPC struct {
+		Addr string `mapstructure:"addr"`
+	} `mapstructure:"grpc"`
+	GraphQL struct {
+		MaxDepth      int `mapstructure:"max_depth"`
+		MaxComplexity int `mapstructure:"max_complexity"`
+	} `mapstructure:"graphql"`
+	Metrics struct {
+		Addr             string        `mapstructure:"addr"`
+		BalancesInterval time.Duration `mapstructure:"balances_interval"`
+	} `mapstructure:"metrics"`
+	Tracing tracing.Config `mapstructure:"tracing"`
+	Health  struct {
+		Timeout         time.Duration `mapstructure:"timeout"`
+		DrainDelay      time.Duration `mapstructure:"drain_delay"`
+		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	} `mapstructure:"health"`
+	Migrations struct {
+		Auto bool `mapstructure:"auto"`
+	} `mapstructure:"migrations"`
 }
 
 func New(path, name string) (*Config, error) {
@@ -35,6 +142,32 @@ func New(path, name string) (*Config, error) {
 	}
 
 	cfg.DB.Password = os.Getenv("POSTGRES_PASSWORD")
+	cfg.Mail.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	cfg.Outbox.Publisher.Webhook.Secret = os.Getenv("OUTBOX_WEBHOOK_SECRET")
+	cfg.Auth.Secret = os.Getenv("AUTH_SECRET")
+	cfg.Auth.PasswordSalt = os.Getenv("PASSWORD_SALT")
+
+	// tokens signed with a known secret or passwords hashed with a known salt could be forged
+	if cfg.Auth.Secret == "" {
+		return nil, errors.New("AUTH_SECRET is not set")
+	}
+	if cfg.Auth.PasswordSalt == "" {
+		return nil, errors.New("PASSWORD_SALT is not set")
+	}
+
+	// periodic jobs can't run without a positive interval
+	for _, interval := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"metrics.balances_interval", cfg.Metrics.BalancesInterval},
+		{"outbox.poll_interval", cfg.Outbox.PollInterval},
+		{"webhooks.poll_interval", cfg.Webhooks.PollInterval},
+	} {
+		if interval.value <= 0 {
+			return nil, fmt.Errorf("%s must be positive, got %s", interval.name, interval.value)
+		}
+	}
 
 	return &cfg, nil
 }
@@ -22,13 +22,18 @@ type ConnectionInfo struct {
 	} `mapstructure:"connection"`
 }
 
+// DSN returns connection string of lib/pq.
+func (info ConnectionInfo) DSN() string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s password=%s",
+		info.Host, info.Port, info.Username, info.DBName, info.SSLMode, info.Password)
+}
+
 var ErrZeroAttempts = errors.New("zero attempts to connection")
 
 func NewPostgresConnection(info ConnectionInfo) (db *sql.DB, err error) {
 	err = ErrZeroAttempts
 	for i := 1; i <= info.Connection.Attempts; i++ {
-		db, err = sql.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s password=%s",
-			info.Host, info.Port, info.Username, info.DBName, info.SSLMode, info.Password))
+		db, err = sql.Open("postgres", info.DSN())
 		if err != nil {
 			logrus.Infof("Attempt #%d Postgres is unavailable - sleeping", i)
 			time.Sleep(info.Connection.Wait)
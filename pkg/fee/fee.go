@@ -0,0 +1,158 @@
+package fee
+
+// Operations a fee rule can be attached to.
+const (
+	OperationTransfer   = "transfer"
+	OperationWithdrawal = "withdrawal"
+	OperationPayment    = "payment"
+)
+
+const basisPointsDivisor = 10000
+
+// Rule describes a single fee. Empty Currency or Tier matches any value.
+// Percentage part is set in basis points (1 bps = 0.01%).
+type Rule struct {
+	Name        string `mapstructure:"name"`
+	Operation   string `mapstructure:"operation"`
+	Currency    string `mapstructure:"currency"`
+	Tier        string `mapstructure:"tier"`
+	Flat        int64  `mapstructure:"flat"`
+	BasisPoints int64  `mapstructure:"bps"`
+	Min         int64  `mapstructure:"min"`
+	Max         int64  `mapstructure:"max"`
+}
+
+// IncomeAccount is an account which receives collected fees in given currency.
+type IncomeAccount struct {
+	Currency  string `mapstructure:"currency"`
+	AccountId int64  `mapstructure:"account_id"`
+}
+
+// Charge is a fee calculated for an operation.
+type Charge struct {
+	Name     string
+	Amount   int64
+	Currency string
+}
+
+// Schedule is a set of fee rules loaded from config.
+type Schedule struct {
+	IncomeAccounts []IncomeAccount `mapstructure:"income_accounts"`
+	Rules          []Rule          `mapstructure:"rules"`
+}
+
+// Calculate returns all non-zero fees for the operation. Rules are grouped by name
+// and the most specific matching rule of every group is applied, so tier and
+// currency rules override generic ones.
+func (s *Schedule) Calculate(operation, currency, tier string, amount int64) []Charge {
+	var (
+		charges []Charge
+		names   []string
+		best    = make(map[string]*Rule)
+	)
+
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		if !rule.matches(operation, currency, tier) {
+			continue
+		}
+
+		current, ok := best[rule.Name]
+		if !ok {
+			names = append(names, rule.Name)
+		}
+
+		if !ok || rule.specificity() > current.specificity() {
+			best[rule.Name] = rule
+		}
+	}
+
+	for _, name := range names {
+		if value := best[name].apply(amount); value > 0 {
+			charges = append(charges, Charge{Name: name, Amount: value, Currency: currency})
+		}
+	}
+
+	return charges
+}
+
+// IncomeAccount returns id of the account which collects fees in given currency.
+func (s *Schedule) IncomeAccount(currency string) (int64, bool) {
+	for _, a := range s.IncomeAccounts {
+		if a.Currency == currency {
+			return a.AccountId, true
+		}
+	}
+
+	return 0, false
+}
+
+// Unfunded returns currencies of rules and given currencies which have a fee rule but no
+// income account. Rules without currency apply to every given currency.
+func (s *Schedule) Unfunded(currencies []string) []string {
+	var (
+		unfunded []string
+		seen     = make(map[string]bool)
+	)
+
+	check := func(currency string) {
+		if seen[currency] {
+			return
+		}
+		seen[currency] = true
+
+		if _, ok := s.IncomeAccount(currency); !ok {
+			unfunded = append(unfunded, currency)
+		}
+	}
+
+	for _, rule := range s.Rules {
+		if rule.Currency != "" {
+			check(rule.Currency)
+			continue
+		}
+
+		for _, currency := range currencies {
+			check(currency)
+		}
+	}
+
+	return unfunded
+}
+
+func (r *Rule) matches(operation, currency, tier string) bool {
+	return r.Operation == operation &&
+		(r.Currency == "" || r.Currency == currency) &&
+		(r.Tier == "" || r.Tier == tier)
+}
+
+// specificity ranks tier overrides above per-currency rules.
+func (r *Rule) specificity() int {
+	var score int
+	if r.Tier != "" {
+		score += 2
+	}
+	if r.Currency != "" {
+		score++
+	}
+
+	return score
+}
+
+func (r *Rule) apply(amount int64) int64 {
+	value := r.Flat
+	if r.BasisPoints != 0 {
+		// round half up to the minor unit
+		value += (amount*r.BasisPoints + basisPointsDivisor/2) / basisPointsDivisor
+	}
+
+	if r.Min > 0 && value < r.Min {
+		value = r.Min
+	}
+
+	if r.Max > 0 && value > r.Max {
+		value = r.Max
+	}
+
+	return value
+}
@@ -0,0 +1,102 @@
+package fee
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalculate(t *testing.T) {
+	s := Schedule{Rules: []Rule{
+		{Name: "transfer", Operation: OperationTransfer, Flat: 5},
+		{Name: "transfer", Operation: OperationTransfer, Currency: "USD", BasisPoints: 50, Min: 10},
+		{Name: "transfer", Operation: OperationTransfer, Tier: "premium"},
+		{Name: "transfer", Operation: OperationTransfer, Currency: "USD", Tier: "business", Flat: 1},
+		{Name: "fx", Operation: OperationTransfer, Currency: "EUR", BasisPoints: 25, Max: 100},
+		{Name: "withdrawal", Operation: OperationWithdrawal, Flat: 200, BasisPoints: 100},
+	}}
+
+	tests := []struct {
+		name      string
+		operation string
+		currency  string
+		tier      string
+		amount    int64
+		want      []Charge
+	}{
+		{"generic", OperationTransfer, "UAH", "", 1000, []Charge{{"transfer", 5, "UAH"}}},
+		{"currency over generic", OperationTransfer, "USD", "", 10000, []Charge{{"transfer", 50, "USD"}}},
+		{"min", OperationTransfer, "USD", "", 100, []Charge{{"transfer", 10, "USD"}}},
+		{"tier over currency, zero dropped", OperationTransfer, "USD", "premium", 10000, nil},
+		{"tier and currency", OperationTransfer, "USD", "business", 10000, []Charge{{"transfer", 1, "USD"}}},
+		{"several names", OperationTransfer, "EUR", "", 1000, []Charge{{"transfer", 5, "EUR"}, {"fx", 3, "EUR"}}},
+		{"max", OperationTransfer, "EUR", "", 1000000, []Charge{{"transfer", 5, "EUR"}, {"fx", 100, "EUR"}}},
+		{"flat and bps", OperationWithdrawal, "UAH", "", 1000, []Charge{{"withdrawal", 210, "UAH"}}},
+		{"no rules", OperationPayment, "UAH", "", 1000, nil},
+	}
+
+	for _, tt := range tests {
+		if got := s.Calculate(tt.operation, tt.currency, tt.tier, tt.amount); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Calculate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRounding(t *testing.T) {
+	r := Rule{BasisPoints: 25}
+
+	tests := []struct {
+		amount int64
+		want   int64
+	}{
+		{100, 0},
+		{199, 0},
+		{200, 1},
+		{599, 1},
+		{600, 2},
+		{1000, 3},
+	}
+
+	for _, tt := range tests {
+		if got := r.apply(tt.amount); got != tt.want {
+			t.Errorf("apply(%d) = %d, want %d", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestUnfunded(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedule   Schedule
+		currencies []string
+		want       []string
+	}{
+		{"no rules", Schedule{}, []string{"UAH"}, nil},
+		{
+			"generic rule",
+			Schedule{
+				IncomeAccounts: []IncomeAccount{{Currency: "UAH", AccountId: 1}},
+				Rules:          []Rule{{Name: "transfer", Operation: OperationTransfer, Flat: 5}},
+			},
+			[]string{"UAH", "USD"},
+			[]string{"USD"},
+		},
+		{
+			"currency rule",
+			Schedule{
+				IncomeAccounts: []IncomeAccount{{Currency: "UAH", AccountId: 1}},
+				Rules: []Rule{
+					{Name: "transfer", Operation: OperationTransfer, Currency: "EUR", Flat: 5},
+					{Name: "fx", Operation: OperationTransfer, Currency: "EUR", BasisPoints: 25},
+				},
+			},
+			[]string{"UAH"},
+			[]string{"EUR"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.schedule.Unfunded(tt.currencies); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Unfunded() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package iban
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidLength     = errors.New("iban: invalid length")
+	ErrInvalidCharacters = errors.New("iban: invalid characters")
+	ErrInvalidChecksum   = errors.New("iban: invalid check digits")
+	ErrUnknownCountry    = errors.New("iban: unknown country")
+)
+
+// lengths of IBAN by country code.
+var lengths = map[string]int{
+	"AD": 24, "AE": 23, "AL": 28, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BR": 29,
+	"BY": 28, "CH": 21, "CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DK": 18, "DO": 28, "EE": 20, "EG": 29,
+	"ES": 24, "FI": 18, "FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28,
+	"HR": 21, "HU": 28, "IE": 22, "IL": 23, "IQ": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20,
+	"LB": 28, "LC": 32, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MD": 24, "ME": 22, "MK": 19,
+	"MR": 27, "MT": 31, "MU": 30, "NL": 18, "NO": 15, "PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29,
+	"RO": 24, "RS": 22, "SA": 24, "SC": 31, "SE": 24, "SI": 19, "SK": 24, "SM": 27, "ST": 25, "SV": 28,
+	"TL": 23, "TN": 24, "TR": 26, "UA": 29, "VA": 22, "VG": 24, "XK": 20,
+}
+
+// Normalize removes spaces and converts IBAN to upper case.
+func Normalize(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// Validate checks length, characters and mod-97 check digits of normalized IBAN.
+func Validate(s string) error {
+	if len(s) < 15 || len(s) > 34 {
+		return ErrInvalidLength
+	}
+
+	for i, r := range s {
+		switch {
+		case i < 2 && (r < 'A' || r > 'Z'):
+			return ErrInvalidCharacters
+		case i >= 2 && i < 4 && (r < '0' || r > '9'):
+			return ErrInvalidCharacters
+		case (r < 'A' || r > 'Z') && (r < '0' || r > '9'):
+			return ErrInvalidCharacters
+		}
+	}
+
+	if length, ok := lengths[s[:2]]; ok && length != len(s) {
+		return ErrInvalidLength
+	}
+
+	if mod97(s[4:]+s[:4]) != 1 {
+		return ErrInvalidChecksum
+	}
+
+	return nil
+}
+
+// Format splits IBAN into groups of four characters for printing.
+func Format(s string) string {
+	var b strings.Builder
+	for i, r := range Normalize(s) {
+		if i > 0 && i%4 == 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// New builds IBAN from country code and BBAN computing its check digits.
+func New(country, bban string) (string, error) {
+	country, bban = Normalize(country), Normalize(bban)
+
+	length, ok := lengths[country]
+	if !ok {
+		return "", ErrUnknownCountry
+	}
+
+	if len(bban) != length-4 {
+		return "", ErrInvalidLength
+	}
+
+	s := fmt.Sprintf("%s%02d%s", country, 98-mod97(bban+country+"00"), bban)
+
+	return s, Validate(s)
+}
+
+// Generate builds IBAN for the bank with random account number filling the rest of BBAN.
+func Generate(country, bankCode string) (string, error) {
+	length, ok := lengths[Normalize(country)]
+	if !ok {
+		return "", ErrUnknownCountry
+	}
+
+	digits := length - 4 - len(bankCode)
+	if digits <= 0 {
+		return "", ErrInvalidLength
+	}
+
+	number := make([]byte, digits)
+	for i := range number {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		number[i] = byte('0' + n.Int64())
+	}
+
+	return New(country, bankCode+string(number))
+}
+
+// mod97 returns remainder of division by 97 of the number built by replacing letters with 10..35.
+func mod97(s string) int {
+	var remainder int
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		}
+	}
+
+	return remainder
+}
@@ -0,0 +1,119 @@
+package iban
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		iban string
+		want error
+	}{
+		{"GB82WEST12345698765432", nil},
+		{"DE89370400440532013000", nil},
+		{"UA733223133686148276048075246", nil},
+		{"NO9386011117947", nil},
+		{"GB82WEST12345698765433", ErrInvalidChecksum},
+		{"GB28WEST12345698765432", ErrInvalidChecksum},
+		{"GB82WEST1234569876543", ErrInvalidLength},
+		{"DE8937040044053201300", ErrInvalidLength},
+		{"GB82", ErrInvalidLength},
+		{"GB82WEST12345698765432000000000000", ErrInvalidLength},
+		{"gb82WEST12345698765432", ErrInvalidCharacters},
+		{"GBX2WEST12345698765432", ErrInvalidCharacters},
+		{"GB82WEST-2345698765432", ErrInvalidCharacters},
+		{"GB82 WEST 1234 5698 7654 32", ErrInvalidCharacters},
+	}
+
+	for _, tt := range tests {
+		if got := Validate(tt.iban); !errors.Is(got, tt.want) {
+			t.Errorf("Validate(%q) = %v, want %v", tt.iban, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeFormat(t *testing.T) {
+	if got := Normalize(" gb82 west 1234\t5698 7654 32 "); got != "GB82WEST12345698765432" {
+		t.Errorf("Normalize() = %q", got)
+	}
+
+	if got := Format("gb82west12345698765432"); got != "GB82 WEST 1234 5698 7654 32" {
+		t.Errorf("Format() = %q", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		country, bban string
+		want          string
+		err           error
+	}{
+		{"GB", "WEST12345698765432", "GB82WEST12345698765432", nil},
+		{"de", "370400440532013000", "DE89370400440532013000", nil},
+		{"UA", "3223133686148276048075246", "UA733223133686148276048075246", nil},
+		{"GB", "WEST1234569876543", "", ErrInvalidLength},
+		{"ZZ", "WEST12345698765432", "", ErrUnknownCountry},
+	}
+
+	for _, tt := range tests {
+		got, err := New(tt.country, tt.bban)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("New(%q, %q) = %q, %v, want %q, %v", tt.country, tt.bban, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		s, err := Generate("UA", "322313")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != 29 || s[:2] != "UA" || s[4:10] != "322313" {
+			t.Fatalf("Generate() = %q", s)
+		}
+		if err := Validate(s); err != nil {
+			t.Fatalf("Validate(%q) = %v", s, err)
+		}
+	}
+
+	tests := []struct {
+		country, bank string
+		want          error
+	}{
+		{"ZZ", "322313", ErrUnknownCountry},
+		{"NO", "12345678901", ErrInvalidLength},
+	}
+
+	for _, tt := range tests {
+		if _, err := Generate(tt.country, tt.bank); !errors.Is(err, tt.want) {
+			t.Errorf("Generate(%q, %q) = %v, want %v", tt.country, tt.bank, err, tt.want)
+		}
+	}
+}
+
+func TestLengths(t *testing.T) {
+	tests := map[string]int{
+		"DE": 22, "FR": 27, "GB": 22, "LC": 32, "MT": 31, "NO": 15, "PL": 28, "UA": 29,
+	}
+
+	for country, want := range tests {
+		if got := lengths[country]; got != want {
+			t.Errorf("lengths[%s] = %d, want %d", country, got, want)
+		}
+	}
+
+	// every country must fit the IBAN bounds and give valid IBANs of its length
+	for country, length := range lengths {
+		if length < 15 || length > 34 {
+			t.Errorf("lengths[%s] = %d, out of IBAN bounds", country, length)
+			continue
+		}
+
+		s, err := Generate(country, "")
+		if err != nil || len(s) != length {
+			t.Errorf("Generate(%q) = %q, %v, want length %d", country, s, err, length)
+		}
+	}
+}
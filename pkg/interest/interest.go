@@ -0,0 +1,60 @@
+package interest
+
+import (
+	"errors"
+	"math/big"
+	"time"
+)
+
+var ErrInvalidRate = errors.New("invalid interest rate")
+
+// Rate is an annual interest rate for savings accounts in given currency.
+// AnnualRate is a decimal fraction, e.g. "0.035" for 3.5%.
+type Rate struct {
+	Currency   string `mapstructure:"currency"`
+	AnnualRate string `mapstructure:"annual_rate"`
+}
+
+type Rates []Rate
+
+// Rate returns parsed annual rate for currency.
+func (rs Rates) Rate(currency string) (*big.Rat, bool, error) {
+	for _, r := range rs {
+		if r.Currency != currency {
+			continue
+		}
+
+		rate, ok := new(big.Rat).SetString(r.AnnualRate)
+		if !ok || rate.Sign() < 0 {
+			return nil, false, ErrInvalidRate
+		}
+
+		return rate, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// Daily returns exact interest accrued on balance for the date using actual/actual day count.
+func Daily(balance int64, annualRate *big.Rat, date time.Time) *big.Rat {
+	amount := new(big.Rat).SetInt64(balance)
+	amount.Mul(amount, annualRate)
+
+	return amount.Quo(amount, new(big.Rat).SetInt64(int64(DaysInYear(date.Year()))))
+}
+
+func DaysInYear(year int) int {
+	return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+// Round rounds amount half up to the minor currency unit.
+func Round(amount *big.Rat) int64 {
+	num := new(big.Int).Set(amount.Num())
+	den := amount.Denom()
+
+	// (2*num + den) / (2*den) rounds half away from zero for positive amounts
+	num.Mul(num, big.NewInt(2)).Add(num, den)
+	num.Quo(num, new(big.Int).Mul(den, big.NewInt(2)))
+
+	return num.Int64()
+}
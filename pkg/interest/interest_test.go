@@ -0,0 +1,106 @@
+package interest
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   int64
+	}{
+		{"0", 0},
+		{"0.49999999", 0},
+		{"0.5", 1},
+		{"1.49", 1},
+		{"2.5", 3},
+		{"1/3", 0},
+		{"2/3", 1},
+		{"123456.50000001", 123457},
+	}
+
+	for _, tt := range tests {
+		amount, _ := new(big.Rat).SetString(tt.amount)
+		if got := Round(amount); got != tt.want {
+			t.Errorf("Round(%s) = %d, want %d", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	rates := Rates{{Currency: "UAH", AnnualRate: "0.035"}, {Currency: "USD", AnnualRate: "-0.01"}, {Currency: "EUR", AnnualRate: "x"}}
+
+	rate, ok, err := rates.Rate("UAH")
+	if err != nil || !ok || rate.Cmp(big.NewRat(35, 1000)) != 0 {
+		t.Errorf("Rate(UAH) = %v, %t, %v", rate, ok, err)
+	}
+
+	if _, ok, err := rates.Rate("GBP"); ok || err != nil {
+		t.Errorf("Rate(GBP) = %t, %v, want no rate", ok, err)
+	}
+
+	for _, currency := range []string{"USD", "EUR"} {
+		if _, _, err := rates.Rate(currency); !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("Rate(%s) = %v, want %v", currency, err, ErrInvalidRate)
+		}
+	}
+}
+
+func TestDaily(t *testing.T) {
+	rate := big.NewRat(365, 10000)
+
+	tests := []struct {
+		date time.Time
+		want *big.Rat
+	}{
+		{time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), big.NewRat(1, 1)},
+		{time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), big.NewRat(365, 366)},
+	}
+
+	for _, tt := range tests {
+		if got := Daily(10000, rate, tt.date); got.Cmp(tt.want) != 0 {
+			t.Errorf("Daily() on %s = %s, want %s", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+// TestCarry capitalizes a year of daily accruals monthly, carrying the rounding difference
+// to the next month, and checks nothing is lost or overpaid over the year.
+func TestCarry(t *testing.T) {
+	const balance = 123457
+	rate := big.NewRat(35, 1000)
+
+	var (
+		paid  int64
+		carry = new(big.Rat)
+		exact = new(big.Rat)
+	)
+
+	for date := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC); date.Year() == 2025; {
+		total := new(big.Rat).Set(carry)
+		for month := date.Month(); date.Month() == month; date = date.AddDate(0, 0, 1) {
+			daily := Daily(balance, rate, date)
+			total.Add(total, daily)
+			exact.Add(exact, daily)
+		}
+
+		amount := Round(total)
+		carry.Sub(total, new(big.Rat).SetInt64(amount))
+		paid += amount
+
+		if carry.Cmp(big.NewRat(1, 2)) >= 0 || carry.Cmp(big.NewRat(-1, 2)) < 0 {
+			t.Fatalf("carry %s before %s is out of [-1/2, 1/2)", carry.FloatString(8), date.Format("2006-01-02"))
+		}
+	}
+
+	if got := new(big.Rat).Add(new(big.Rat).SetInt64(paid), carry); got.Cmp(exact) != 0 {
+		t.Fatalf("paid %d and carry %s = %s, want %s", paid, carry.FloatString(8), got.FloatString(8), exact.FloatString(8))
+	}
+
+	if want := Round(exact); paid != want {
+		t.Fatalf("paid %d, want %d", paid, want)
+	}
+}
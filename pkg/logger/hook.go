@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// ContextHook adds values of the entry context to its fields, so entries logged with
+// logrus.WithContext carry request scoped data without passing it to every call.
+type ContextHook struct {
+	fields map[string]interface{}
+}
+
+// NewContextHook returns hook setting field to the context value by its key, for every
+// field and key of fields. Values missing in the context are skipped, fields already set
+// are not overwritten.
+func NewContextHook(fields map[string]interface{}) *ContextHook {
+	return &ContextHook{
+		fields: fields,
+	}
+}
+
+func (h *ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *ContextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	for field, key := range h.fields {
+		if _, ok := entry.Data[field]; ok {
+			continue
+		}
+
+		if v := entry.Context.Value(key); v != nil {
+			entry.Data[field] = v
+		}
+	}
+
+	return nil
+}
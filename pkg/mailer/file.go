@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FileMailer logs recipients and subjects of messages, as bodies may carry tokens, and writes
+// messages as .eml files to dir, if it's set.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{from: from, dir: dir}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+
+	data, err := build(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"context": "FileMailer.Send()",
+		"to":      msg.To,
+		"subject": msg.Subject,
+	}).Info("message sent")
+
+	if m.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%x.eml", now.UTC().Format("20060102T150405.000000000"), suffix)
+
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o640)
+}
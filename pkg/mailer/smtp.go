@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends messages through SMTP server. PLAIN auth is used when username is set.
+type SMTPMailer struct {
+	from string
+	cfg  SMTPConfig
+}
+
+func NewSMTPMailer(from string, cfg SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{from: from, cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := build(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.cfg.Host, m.cfg.Port), auth, m.from, []string{msg.To}, data)
+}
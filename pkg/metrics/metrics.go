@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crud"
+
+// Metrics holds collectors of the app in its own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	cacheLookups *prometheus.CounterVec
+	signIns      *prometheus.CounterVec
+	balances     *prometheus.GaugeVec
+	accounts     *prometheus.GaugeVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Number of cache lookups by cache and result: hit or miss.",
+		}, []string{"cache", "result"}),
+		signIns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sign_ins_total",
+			Help:      "Number of sign in attempts by method and result.",
+		}, []string{"method", "result"}),
+		balances: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance_total",
+			Help:      "Sum of account balances in minor units by currency.",
+		}, []string{"currency"}),
+		accounts: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "accounts",
+			Help:      "Number of accounts by currency.",
+		}, []string{"currency"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.latency,
+		m.cacheLookups,
+		m.signIns,
+		m.balances,
+		m.accounts,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in Prometheus format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest counts the request. Route is the route pattern, not the path, to keep
+// number of series bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.latency.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+func (m *Metrics) SignIn(method, result string) {
+	m.signIns.WithLabelValues(method, result).Inc()
+}
+
+// RegisterDB exposes connection pool stats of the database.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+type Balance struct {
+	Total    float64
+	Accounts float64
+}
+
+// SetBalances updates balances by currency. Balances are queried periodically by the app,
+// not on scrape, so scrapes don't load the database.
+func (m *Metrics) SetBalances(balances map[string]Balance) {
+	for currency, b := range balances {
+		m.balances.WithLabelValues(currency).Set(b.Total)
+		m.accounts.WithLabelValues(currency).Set(b.Accounts)
+	}
+}
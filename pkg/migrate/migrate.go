@@ -0,0 +1,435 @@
+// Package migrate applies SQL migrations to Postgres. Applied version is kept in
+// schema_migrations table in the format of golang-migrate, so databases migrated by it
+// keep their version. Versions of all applied migrations are kept in
+// schema_migrations_history, so a migration added with a version lower than the applied
+// one is detected instead of silently skipped.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// lockKey of the advisory lock which serializes migrations of app instances started at once.
+const lockKey int64 = 7357812260
+
+const undefinedTable = "42P01"
+
+var (
+	ErrDirty       = errors.New("schema is dirty: last migration failed half-way, fix it manually")
+	ErrSchemaNewer = errors.New("schema is newer than migrations of the app")
+	ErrOutOfOrder  = errors.New("migration is older than the applied version but isn't applied, give it a newer version")
+	ErrNoSteps     = errors.New("nothing to roll back")
+)
+
+var (
+	fileName      = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	migrationName = regexp.MustCompile(`^\w+$`)
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// State is the state of the schema. Applied is nil if the history isn't kept yet; then all
+// migrations up to Version are considered applied.
+type State struct {
+	Version int64
+	Dirty   bool
+	Applied map[int64]bool
+}
+
+func (s State) applied(version int64) bool {
+	if s.Applied == nil {
+		return version <= s.Version
+	}
+
+	return s.Applied[version]
+}
+
+type Migrator struct {
+	db         *sql.DB
+	fsys       fs.FS
+	migrations []Migration
+}
+
+// New reads migrations of fsys root. Every migration must have both up and down files.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if match[3] == "up" {
+			m.up = e.Name()
+		} else {
+			m.down = e.Name()
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s: up and down files are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return &Migrator{
+		db:         db,
+		fsys:       fsys,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns version of the last known migration, 0 if there are none.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns version of the last applied migration, 0 if none is applied, and
+// whether it failed half-way.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	return version(ctx, m.db)
+}
+
+// Check returns the state of the schema and fails if it's dirty, newer than the migrations,
+// i.e. it was migrated by a newer release of the app, or misses a migration older than the
+// applied version.
+func (m *Migrator) Check(ctx context.Context) (State, error) {
+	s, err := state(ctx, m.db)
+	if err != nil {
+		return s, err
+	}
+
+	return s, m.check(s)
+}
+
+func (m *Migrator) check(s State) error {
+	if s.Dirty {
+		return fmt.Errorf("%w (version %d)", ErrDirty, s.Version)
+	}
+
+	if s.Version > m.Latest() {
+		return fmt.Errorf("%w: version %d, latest known %d", ErrSchemaNewer, s.Version, m.Latest())
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version < s.Version && !s.applied(migration.Version) {
+			return fmt.Errorf("%w: %d_%s, applied version %d", ErrOutOfOrder, migration.Version, migration.Name, s.Version)
+		}
+	}
+
+	return nil
+}
+
+// Status lists known migrations in order with their state.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	s, err := state(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{Migration: migration, Applied: s.applied(migration.Version)}
+	}
+
+	return statuses, nil
+}
+
+// Up applies all pending migrations and returns them. Each migration runs in its own
+// transaction under the advisory lock, so concurrent calls apply it once.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn, s State) error {
+		if err := m.check(s); err != nil {
+			return err
+		}
+
+		for _, migration := range m.pending(s) {
+			if err := m.apply(ctx, conn, migration, true, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back given number of the last applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn, s State) error {
+		if err := m.check(s); err != nil {
+			return err
+		}
+
+		steps := m.rollback(s, steps)
+		if len(steps) == 0 {
+			return ErrNoSteps
+		}
+
+		for _, step := range steps {
+			if err := m.apply(ctx, conn, step.Migration, false, step.previous); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", step.Version, step.Name, err)
+			}
+			rolledBack = append(rolledBack, step.Migration)
+		}
+
+		return nil
+	})
+
+	return rolledBack, err
+}
+
+// pending returns migrations newer than the applied version in order.
+func (m *Migrator) pending(s State) []Migration {
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > s.Version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending
+}
+
+// rollbackStep is a migration to roll back with the version set after it.
+type rollbackStep struct {
+	Migration
+	previous int64
+}
+
+// rollback returns up to steps last applied migrations, newest first.
+func (m *Migrator) rollback(s State, steps int) []rollbackStep {
+	var rollback []rollbackStep
+	for i := len(m.migrations) - 1; i >= 0 && len(rollback) < steps; i-- {
+		if m.migrations[i].Version > s.Version {
+			continue
+		}
+
+		var previous int64
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		rollback = append(rollback, rollbackStep{Migration: m.migrations[i], previous: previous})
+	}
+
+	return rollback
+}
+
+// apply runs the up or down file of the migration, sets the version and updates the
+// history in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool, version int64) error {
+	file, history := migration.down, "DELETE FROM schema_migrations_history WHERE version = $1"
+	if up {
+		file, history = migration.up, "INSERT INTO schema_migrations_history (version) VALUES ($1)"
+	}
+
+	query, err := fs.ReadFile(m.fsys, file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(query)); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", version); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, history, migration.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// withLock runs fn on a single connection holding the advisory lock. The history of
+// a schema migrated before it was kept is filled with the known versions up to the
+// applied one.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, s State) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty   BOOLEAN NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS schema_migrations_history (
+		version    BIGINT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return err
+	}
+
+	s, err := state(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	if s.Applied == nil {
+		s.Applied = make(map[int64]bool)
+
+		var versions []int64
+		for _, migration := range m.migrations {
+			if migration.Version <= s.Version {
+				versions = append(versions, migration.Version)
+				s.Applied[migration.Version] = true
+			}
+		}
+
+		_, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations_history (version)
+			SELECT unnest($1::BIGINT[]) ON CONFLICT DO NOTHING`, pq.Array(versions))
+		if err != nil {
+			return err
+		}
+	}
+
+	return fn(conn, s)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func version(ctx context.Context, q queryRower) (version int64, dirty bool, err error) {
+	err = q.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || errors.As(err, &pqErr) && pqErr.Code == undefinedTable {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// state reads the version and the history in one query. Applied is nil if the history
+// table doesn't exist or is empty while a version is applied, i.e. the schema was migrated
+// before the history was kept.
+func state(ctx context.Context, q queryRower) (State, error) {
+	var (
+		s        State
+		versions []int64
+	)
+	err := q.QueryRowContext(ctx, `SELECT v.version, v.dirty, ARRAY(SELECT version FROM schema_migrations_history)
+		FROM schema_migrations v LIMIT 1`).Scan(&s.Version, &s.Dirty, pq.Array(&versions))
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == undefinedTable {
+		s.Version, s.Dirty, err = version(ctx, q)
+		return s, err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	if len(versions) > 0 {
+		s.Applied = make(map[int64]bool, len(versions))
+		for _, v := range versions {
+			s.Applied[v] = true
+		}
+	}
+
+	return s, nil
+}
+
+// Create writes empty up and down files of a new migration to dir and returns their paths.
+// Version is the current UTC time, as of the existing migrations.
+func Create(dir, name string, now time.Time) ([]string, error) {
+	name = strings.ReplaceAll(strings.TrimSpace(strings.ToLower(name)), " ", "_")
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: only letters, digits and _ are allowed", name)
+	}
+
+	base := now.UTC().Format("20060102150405") + "_" + name
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, base+"."+direction+".sql")
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return paths, err
+		}
+		f.Close()
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,195 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newTestMigrator(t *testing.T, versions ...string) *Migrator {
+	t.Helper()
+
+	fsys := fstest.MapFS{}
+	for _, v := range versions {
+		fsys[v+"_test.up.sql"] = &fstest.MapFile{}
+		fsys[v+"_test.down.sql"] = &fstest.MapFile{}
+	}
+
+	m, err := New(nil, fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func versionsOf(migrations []Migration) []int64 {
+	versions := make([]int64, len(migrations))
+	for i, m := range migrations {
+		versions[i] = m.Version
+	}
+
+	return versions
+}
+
+func equalVersions(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(nil, fstest.MapFS{
+		"3_accounts.up.sql":   {},
+		"3_accounts.down.sql": {},
+		"10_users.up.sql":     {},
+		"10_users.down.sql":   {},
+		"1_init.down.sql":     {},
+		"1_init.up.sql":       {},
+		"embed.go":            {},
+		"README.md":           {},
+		"2_dir.up.sql/x":      {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := versionsOf(m.migrations), []int64{1, 3, 10}; !equalVersions(got, want) {
+		t.Fatalf("versions = %v, want %v", got, want)
+	}
+
+	if m.migrations[1].Name != "accounts" || m.migrations[1].up != "3_accounts.up.sql" || m.migrations[1].down != "3_accounts.down.sql" {
+		t.Fatalf("migration 3 = %+v", m.migrations[1])
+	}
+
+	if m.Latest() != 10 {
+		t.Fatalf("Latest() = %d, want 10", m.Latest())
+	}
+}
+
+func TestNewMissingFile(t *testing.T) {
+	for _, file := range []string{"1_init.up.sql", "1_init.down.sql"} {
+		if _, err := New(nil, fstest.MapFS{file: {}}); err == nil {
+			t.Errorf("New() with only %s succeeded", file)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	m := newTestMigrator(t, "1", "2", "3")
+
+	tests := []struct {
+		name  string
+		state State
+		want  error
+	}{
+		{"empty", State{}, nil},
+		{"behind", State{Version: 2, Applied: map[int64]bool{1: true, 2: true}}, nil},
+		{"latest", State{Version: 3, Applied: map[int64]bool{1: true, 2: true, 3: true}}, nil},
+		{"no history", State{Version: 3}, nil},
+		{"dirty", State{Version: 2, Dirty: true}, ErrDirty},
+		{"newer", State{Version: 4}, ErrSchemaNewer},
+		{"out of order", State{Version: 3, Applied: map[int64]bool{1: true, 3: true}}, ErrOutOfOrder},
+	}
+
+	for _, tt := range tests {
+		if err := m.check(tt.state); !errors.Is(err, tt.want) {
+			t.Errorf("%s: check() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestPending(t *testing.T) {
+	m := newTestMigrator(t, "1", "2", "3")
+
+	tests := []struct {
+		version int64
+		want    []int64
+	}{
+		{0, []int64{1, 2, 3}},
+		{1, []int64{2, 3}},
+		{3, nil},
+	}
+
+	for _, tt := range tests {
+		if got := versionsOf(m.pending(State{Version: tt.version})); !equalVersions(got, tt.want) {
+			t.Errorf("pending(%d) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	m := newTestMigrator(t, "1", "2", "3")
+
+	tests := []struct {
+		version  int64
+		steps    int
+		want     []int64
+		previous []int64
+	}{
+		{3, 1, []int64{3}, []int64{2}},
+		{3, 5, []int64{3, 2, 1}, []int64{2, 1, 0}},
+		{2, 2, []int64{2, 1}, []int64{1, 0}},
+		{0, 1, nil, nil},
+	}
+
+	for _, tt := range tests {
+		steps := m.rollback(State{Version: tt.version}, tt.steps)
+
+		var got, previous []int64
+		for _, step := range steps {
+			got = append(got, step.Version)
+			previous = append(previous, step.previous)
+		}
+
+		if !equalVersions(got, tt.want) || !equalVersions(previous, tt.previous) {
+			t.Errorf("rollback(%d, %d) = %v to %v, want %v to %v", tt.version, tt.steps, got, previous, tt.want, tt.previous)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 12, 30, 15, 0, time.UTC)
+
+	paths, err := Create(dir, " Add Cards ", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "20261019123015_add_cards.up.sql"),
+		filepath.Join(dir, "20261019123015_add_cards.down.sql"),
+	}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("Create() = %v, want %v", paths, want)
+	}
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Create(dir, "add_cards", now); err == nil {
+		t.Fatal("Create() overwrote existing migration")
+	}
+}
+
+func TestCreateInvalidName(t *testing.T) {
+	for _, name := range []string{"", "add-cards", "../cards", "cards;"} {
+		if _, err := Create(t.TempDir(), name, time.Now()); err == nil {
+			t.Errorf("Create(%q) succeeded", name)
+		}
+	}
+}
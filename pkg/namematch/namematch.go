@@ -0,0 +1,167 @@
+package namematch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Results of name comparison. Unavailable is reported by callers when there's no holder
+// to compare with, e.g. for accounts of other banks.
+const (
+	Match       = "match"
+	CloseMatch  = "close_match"
+	NoMatch     = "no_match"
+	Unavailable = "unavailable"
+)
+
+// closeSimilarity is a minimal similarity of names considered a close match.
+const closeSimilarity = 0.8
+
+// Compare checks name given by payer against first and last name of account holder.
+// Word order, case and punctuation are ignored. Typos, initials instead of first name
+// and missing first name are reported as a close match.
+func Compare(given, firstName, lastName string) string {
+	words := normalize(given)
+	first, last := normalize(firstName), normalize(lastName)
+	holder := append(append([]string{}, first...), last...)
+
+	if len(words) == 0 || len(holder) == 0 {
+		return NoMatch
+	}
+
+	if sorted(words) == sorted(holder) {
+		return Match
+	}
+
+	for _, candidate := range [][]string{holder, append(append([]string{}, last...), first...)} {
+		if similarity(strings.Join(words, " "), strings.Join(candidate, " ")) >= closeSimilarity {
+			return CloseMatch
+		}
+	}
+
+	if initialsMatch(words, first, last) {
+		return CloseMatch
+	}
+
+	return NoMatch
+}
+
+// Mask returns the holder's name with only the first letter of every word shown,
+// e.g. "O****** F******", enough to recognize the payee but not to learn the name.
+func Mask(firstName, lastName string) string {
+	words := strings.Fields(firstName + " " + lastName)
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = string(r[0]) + strings.Repeat("*", len(r)-1)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// initialsMatch reports whether given words consist of the last name and
+// initials of the first name (or no first name at all), in any order.
+func initialsMatch(words, first, last []string) bool {
+	if len(last) == 0 {
+		return false
+	}
+
+	rest := append([]string{}, words...)
+	for _, l := range last {
+		i := index(rest, l)
+		if i < 0 {
+			return false
+		}
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+
+	if len(rest) > len(first) {
+		return false
+	}
+
+	for _, w := range rest {
+		r := []rune(w)
+		if len(r) != 1 || !hasInitial(first, r[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasInitial(words []string, initial rune) bool {
+	for _, w := range words {
+		if []rune(w)[0] == initial {
+			return true
+		}
+	}
+
+	return false
+}
+
+func index(words []string, word string) int {
+	for i, w := range words {
+		if w == word {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func normalize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
+
+func sorted(words []string) string {
+	w := append([]string{}, words...)
+	sort.Strings(w)
+
+	return strings.Join(w, " ")
+}
+
+// similarity returns 1 - normalized Levenshtein distance of a and b.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
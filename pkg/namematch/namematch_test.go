@@ -0,0 +1,41 @@
+package namematch
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		given string
+		want  string
+	}{
+		{"Oleksii Filatov", Match},
+		{"filatov, oleksii", Match},
+		{"Oleksii Filatow", CloseMatch},
+		{"O. Filatov", CloseMatch},
+		{"Filatov", CloseMatch},
+		{"Ivan Petrenko", NoMatch},
+		{"", NoMatch},
+	}
+
+	for _, tt := range tests {
+		if got := Compare(tt.given, "Oleksii", "Filatov"); got != tt.want {
+			t.Errorf("Compare(%q) = %s, want %s", tt.given, got, tt.want)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		first, last string
+		want        string
+	}{
+		{"Oleksii", "Filatov", "O****** F******"},
+		{"Анна-Марія", "Ко", "А********* К*"},
+		{"", "Li", "L*"},
+	}
+
+	for _, tt := range tests {
+		if got := Mask(tt.first, tt.last); got != tt.want {
+			t.Errorf("Mask(%q, %q) = %q, want %q", tt.first, tt.last, got, tt.want)
+		}
+	}
+}
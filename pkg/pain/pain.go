@@ -0,0 +1,199 @@
+package pain
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+// ISO 20022 text limits.
+const (
+	maxNameLength       = 70
+	maxRemittanceLength = 140
+)
+
+type Party struct {
+	Name string
+	IBAN string
+	BIC  string
+}
+
+// Transaction is a credit transfer. Amount is in minor units.
+type Transaction struct {
+	EndToEndId     string
+	Amount         int64
+	Creditor       Party
+	RemittanceInfo string
+}
+
+// Payment is a batch of transactions in one currency to execute on one date.
+type Payment struct {
+	Id            string
+	Currency      string
+	ExecutionDate time.Time
+	Transactions  []Transaction
+}
+
+// Message is a pain.001 customer credit transfer initiation.
+type Message struct {
+	Id        string
+	CreatedAt time.Time
+	Initiator string
+	Debtor    Party
+	Payments  []Payment
+}
+
+// Marshal renders message as pain.001.001.03 document.
+func (m *Message) Marshal() ([]byte, error) {
+	var (
+		count int
+		sum   int64
+	)
+
+	initiation := customerCreditTransferInitiation{}
+	for _, p := range m.Payments {
+		info := paymentInformation{
+			Id:            p.Id,
+			Method:        "TRF",
+			ExecutionDate: p.ExecutionDate.Format("2006-01-02"),
+			Debtor:        partyIdentification{Name: truncate(m.Debtor.Name, maxNameLength)},
+			DebtorAccount: cashAccount{IBAN: m.Debtor.IBAN, Currency: p.Currency},
+			DebtorAgent:   agent(m.Debtor.BIC),
+			ChargeBearer:  "SLEV",
+		}
+
+		var paymentSum int64
+		for _, t := range p.Transactions {
+			tx := creditTransfer{
+				EndToEndId:      t.EndToEndId,
+				Amount:          amount{Currency: p.Currency, Value: formatAmount(t.Amount)},
+				Creditor:        partyIdentification{Name: truncate(t.Creditor.Name, maxNameLength)},
+				CreditorAccount: cashAccount{IBAN: t.Creditor.IBAN},
+			}
+
+			if t.Creditor.BIC != "" {
+				creditorAgent := agent(t.Creditor.BIC)
+				tx.CreditorAgent = &creditorAgent
+			}
+
+			if t.RemittanceInfo != "" {
+				tx.Remittance = &remittance{Unstructured: truncate(t.RemittanceInfo, maxRemittanceLength)}
+			}
+
+			info.Transactions = append(info.Transactions, tx)
+			paymentSum += t.Amount
+		}
+
+		info.NumberOfTransactions = len(p.Transactions)
+		info.ControlSum = formatAmount(paymentSum)
+		initiation.Payments = append(initiation.Payments, info)
+
+		count += len(p.Transactions)
+		sum += paymentSum
+	}
+
+	initiation.Header = groupHeader{
+		MessageId:            m.Id,
+		CreatedAt:            m.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+		NumberOfTransactions: count,
+		ControlSum:           formatAmount(sum),
+		Initiator:            partyIdentification{Name: truncate(m.Initiator, maxNameLength)},
+	}
+
+	data, err := xml.MarshalIndent(document{Namespace: pain001Namespace, Initiation: initiation}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+func agent(bic string) financialInstitution {
+	if bic == "" {
+		return financialInstitution{Other: &other{Id: "NOTPROVIDED"}}
+	}
+
+	return financialInstitution{BIC: bic}
+}
+
+func formatAmount(value int64) string {
+	return fmt.Sprintf("%d.%02d", value/100, value%100)
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+
+	return s
+}
+
+type document struct {
+	XMLName    xml.Name                         `xml:"Document"`
+	Namespace  string                           `xml:"xmlns,attr"`
+	Initiation customerCreditTransferInitiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type customerCreditTransferInitiation struct {
+	Header   groupHeader          `xml:"GrpHdr"`
+	Payments []paymentInformation `xml:"PmtInf"`
+}
+
+type groupHeader struct {
+	MessageId            string              `xml:"MsgId"`
+	CreatedAt            string              `xml:"CreDtTm"`
+	NumberOfTransactions int                 `xml:"NbOfTxs"`
+	ControlSum           string              `xml:"CtrlSum"`
+	Initiator            partyIdentification `xml:"InitgPty"`
+}
+
+type paymentInformation struct {
+	Id                   string               `xml:"PmtInfId"`
+	Method               string               `xml:"PmtMtd"`
+	NumberOfTransactions int                  `xml:"NbOfTxs"`
+	ControlSum           string               `xml:"CtrlSum"`
+	ExecutionDate        string               `xml:"ReqdExctnDt"`
+	Debtor               partyIdentification  `xml:"Dbtr"`
+	DebtorAccount        cashAccount          `xml:"DbtrAcct"`
+	DebtorAgent          financialInstitution `xml:"DbtrAgt"`
+	ChargeBearer         string               `xml:"ChrgBr"`
+	Transactions         []creditTransfer     `xml:"CdtTrfTxInf"`
+}
+
+type creditTransfer struct {
+	EndToEndId      string                `xml:"PmtId>EndToEndId"`
+	Amount          amount                `xml:"Amt>InstdAmt"`
+	CreditorAgent   *financialInstitution `xml:"CdtrAgt,omitempty"`
+	Creditor        partyIdentification   `xml:"Cdtr"`
+	CreditorAccount cashAccount           `xml:"CdtrAcct"`
+	Remittance      *remittance           `xml:"RmtInf,omitempty"`
+}
+
+type partyIdentification struct {
+	Name string `xml:"Nm"`
+}
+
+type cashAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Currency string `xml:"Ccy,omitempty"`
+}
+
+type financialInstitution struct {
+	BIC   string `xml:"FinInstnId>BIC,omitempty"`
+	Other *other `xml:"FinInstnId>Othr,omitempty"`
+}
+
+type other struct {
+	Id string `xml:"Id"`
+}
+
+type amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type remittance struct {
+	Unstructured string `xml:"Ustrd"`
+}
@@ -0,0 +1,89 @@
+package pain
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TransactionStatus is a status of a single transaction from pain.002 report.
+type TransactionStatus struct {
+	EndToEndId string
+	Status     string
+	Reason     string
+}
+
+// StatusReport is a pain.002 customer payment status report. Group status applies
+// to every transaction of the original message which has no own status.
+type StatusReport struct {
+	OriginalMessageId string
+	GroupStatus       string
+	GroupReason       string
+	Transactions      []TransactionStatus
+}
+
+type statusReason struct {
+	Code       string   `xml:"Rsn>Cd"`
+	Additional []string `xml:"AddtlInf"`
+}
+
+func (r statusReason) String() string {
+	return strings.TrimSpace(strings.Join(append([]string{r.Code}, r.Additional...), " "))
+}
+
+type statusDocument struct {
+	Report struct {
+		Group struct {
+			MessageId string       `xml:"OrgnlMsgId"`
+			Status    string       `xml:"GrpSts"`
+			Reason    statusReason `xml:"StsRsnInf"`
+		} `xml:"OrgnlGrpInfAndSts"`
+		Payments []struct {
+			Status       string       `xml:"PmtInfSts"`
+			Reason       statusReason `xml:"StsRsnInf"`
+			Transactions []struct {
+				EndToEndId string       `xml:"OrgnlEndToEndId"`
+				Status     string       `xml:"TxSts"`
+				Reason     statusReason `xml:"StsRsnInf"`
+			} `xml:"TxInfAndSts"`
+		} `xml:"OrgnlPmtInfAndSts"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+// ParseStatusReport parses pain.002 document of any version.
+func ParseStatusReport(r io.Reader) (*StatusReport, error) {
+	var doc statusDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("pain.002: %w", err)
+	}
+
+	group := doc.Report.Group
+	if group.MessageId == "" {
+		return nil, errors.New("pain.002: original message id not found")
+	}
+
+	report := StatusReport{
+		OriginalMessageId: strings.TrimSpace(group.MessageId),
+		GroupStatus:       strings.TrimSpace(group.Status),
+		GroupReason:       group.Reason.String(),
+	}
+
+	for _, p := range doc.Report.Payments {
+		for _, t := range p.Transactions {
+			status, reason := t.Status, t.Reason.String()
+			if status == "" {
+				status, reason = p.Status, p.Reason.String()
+			}
+
+			report.Transactions = append(report.Transactions, TransactionStatus{
+				EndToEndId: strings.TrimSpace(t.EndToEndId),
+				Status:     strings.TrimSpace(status),
+				Reason:     reason,
+			})
+		}
+	}
+
+	return &report, nil
+}
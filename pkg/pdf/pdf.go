@@ -0,0 +1,130 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page in points.
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 40
+	fontSize   = 9
+	leading    = 12
+)
+
+const linesPerPage = (pageHeight - 2*margin) / leading
+
+type line struct {
+	font string
+	text string
+}
+
+// Document is a minimal text-only PDF document using standard Courier fonts,
+// so columns can be aligned with spaces. Only ASCII is supported, other runes are replaced with '?'.
+type Document struct {
+	pages [][]line
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// Line adds a line of regular text.
+func (d *Document) Line(text string) {
+	d.add(line{font: "F1", text: text})
+}
+
+// Heading adds a line of bold text.
+func (d *Document) Heading(text string) {
+	d.add(line{font: "F2", text: text})
+}
+
+func (d *Document) add(l line) {
+	if len(d.pages) == 0 || len(d.pages[len(d.pages)-1]) == linesPerPage {
+		d.pages = append(d.pages, nil)
+	}
+
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], l)
+}
+
+// WriteTo writes document in PDF 1.4 format.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+
+	if len(d.pages) == 0 {
+		d.pages = append(d.pages, nil)
+	}
+
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// objects 1-4 are catalog, page tree and fonts, then page and content objects for every page
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		content := d.content(page)
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+
+	return int64(n), err
+}
+
+func (d *Document) content(page []line) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "BT\n%d TL\n%d %d Td\n", leading, margin, pageHeight-margin)
+	for _, l := range page {
+		fmt.Fprintf(&b, "/%s %d Tf\n(%s) Tj\nT*\n", l.font, fontSize, escape(l.text))
+	}
+	b.WriteString("ET")
+
+	return b.String()
+}
+
+func escape(text string) string {
+	var b strings.Builder
+
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
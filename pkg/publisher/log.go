@@ -0,0 +1,26 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogPublisher writes ids and types of messages to the log. Payloads are not logged,
+// as they may carry personal data.
+type LogPublisher struct{}
+
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, msg Message) error {
+	logrus.WithFields(logrus.Fields{
+		"context": "LogPublisher.Publish()",
+		"id":      msg.Id,
+		"type":    msg.Type,
+		"key":     msg.Key,
+	}).Info("message published")
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package publisher
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Drivers of Config.
+const (
+	DriverLog     = "log"
+	DriverWebhook = "webhook"
+)
+
+var ErrUnknownDriver = errors.New("unknown publisher driver")
+
+// Message is an event delivered to downstream systems. Messages with the same Key
+// are published in order.
+type Message struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	Key       string          `json:"key,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+type WebhookConfig struct {
+	URL     string        `mapstructure:"url"`
+	Secret  string        `mapstructure:"secret"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Config selects publisher: webhook to push events to HTTP endpoint, log for local development.
+type Config struct {
+	Driver  string        `mapstructure:"driver"`
+	Webhook WebhookConfig `mapstructure:"webhook"`
+}
+
+func New(cfg Config) (Publisher, error) {
+	switch cfg.Driver {
+	case DriverWebhook:
+		return NewWebhookPublisher(cfg.Webhook), nil
+	case DriverLog, "":
+		return NewLogPublisher(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+// Sign returns hex HMAC-SHA256 of the timestamp and body joined with a dot.
+// Receivers recompute it with the shared secret and reject old timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
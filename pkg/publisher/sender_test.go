@@ -0,0 +1,160 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSendSignsRequest(t *testing.T) {
+	const secret = "whsec_test"
+
+	msg := Message{
+		Id:        "42",
+		Type:      "balance.changed",
+		Payload:   json.RawMessage(`{"account_id":1}`),
+		CreatedAt: time.Date(2022, 8, 25, 14, 58, 16, 0, time.UTC),
+	}
+
+	received := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- func() error {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return err
+			}
+
+			ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+			if err != nil {
+				return err
+			}
+
+			if got, want := r.Header.Get(HeaderSignature), "sha256="+Sign(secret, time.Unix(ts, 0), body); got != want {
+				return errors.New("signature " + got + " != " + want)
+			}
+			if r.Header.Get(HeaderEventId) != msg.Id || r.Header.Get(HeaderEventType) != msg.Type {
+				return errors.New("unexpected event headers")
+			}
+
+			var got Message
+			if err := json.Unmarshal(body, &got); err != nil {
+				return err
+			}
+			if got.Id != msg.Id || string(got.Payload) != string(msg.Payload) {
+				return errors.New("unexpected body " + string(body))
+			}
+
+			return nil
+		}()
+	}))
+	defer srv.Close()
+
+	status, err := NewSender(time.Second, true).Send(context.Background(), srv.URL, secret, msg)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Send() = %d, %v", status, err)
+	}
+
+	if err := <-received; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendWithoutSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderSignature) != "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	if _, err := NewSender(time.Second, true).Send(context.Background(), srv.URL, "", Message{Id: "1"}); err != nil {
+		t.Fatalf("unsigned request: %v", err)
+	}
+}
+
+func TestSendFailsOnNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	status, err := NewSender(time.Second, true).Send(context.Background(), srv.URL, "s", Message{Id: "1"})
+	if err == nil || status != http.StatusServiceUnavailable {
+		t.Fatalf("Send() = %d, %v, want 503 and error", status, err)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	var followed int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.StoreInt32(&followed, 1)
+	}))
+	defer target.Close()
+
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer srv.Close()
+
+	status, err := NewSender(time.Second, true).Send(context.Background(), srv.URL, "s", Message{Id: "1"})
+	if err == nil || status != http.StatusTemporaryRedirect {
+		t.Fatalf("Send() = %d, %v, want 307 and error", status, err)
+	}
+	if atomic.LoadInt32(&followed) != 0 {
+		t.Fatal("redirect was followed")
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	var called int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.StoreInt32(&called, 1)
+	}))
+	defer srv.Close()
+
+	_, err := NewSender(time.Second, false).Send(context.Background(), srv.URL, "s", Message{Id: "1"})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Send() to %s error = %v, want %v", srv.URL, err, ErrForbiddenAddress)
+	}
+	if atomic.LoadInt32(&called) != 0 {
+		t.Fatal("request reached loopback receiver")
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"100.63.255.255", true},
+		{"100.128.0.1", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:100.64.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublic(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package publisher
+
+import (
+	"context"
+	"time"
+)
+
+// Headers of webhook requests.
+const (
+	HeaderEventId   = "X-Event-Id"
+	HeaderEventType = "X-Event-Type"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderSignature = "X-Signature"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// WebhookPublisher posts messages as JSON to the URL. Requests are signed if secret is set.
+// Any response except 2xx is an error, so the message is published again later.
+type WebhookPublisher struct {
+	url    string
+	secret string
+	sender *Sender
+}
+
+func NewWebhookPublisher(cfg WebhookConfig) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:    cfg.URL,
+		secret: cfg.Secret,
+		// the URL is set by operator, it may point to internal service
+		sender: NewSender(cfg.Timeout, true),
+	}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, msg Message) error {
+	_, err := p.sender.Send(ctx, p.url, p.secret, msg)
+
+	return err
+}
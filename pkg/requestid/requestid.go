@@ -0,0 +1,39 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// fallback numbers ids made when the system random source fails.
+var fallback uint64
+
+// Valid reports whether id given by client is safe to log and send back: up to 64 letters,
+// digits, dashes, underscores and dots.
+func Valid(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+
+	for _, r := range id {
+		if !(r == '-' || r == '_' || r == '.' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// New returns random request id. If the random source fails, the id is made of the current
+// time and a counter: it's still unique within the process, which is all correlation needs.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(atomic.AddUint64(&fallback, 1), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
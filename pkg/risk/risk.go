@@ -0,0 +1,125 @@
+package risk
+
+import "time"
+
+// Decisions of the assessment ordered by severity.
+const (
+	Allow     = "allow"
+	Challenge = "challenge"
+	Block     = "block"
+)
+
+// Names of the rules reported in assessment.
+const (
+	RuleVelocity        = "velocity"
+	RuleNewPayee        = "new_payee"
+	RuleAverageMultiple = "average_multiple"
+	RulePasswordChange  = "password_change"
+)
+
+// AverageWindow is the history used to calculate average outgoing amount.
+const AverageWindow = 90 * 24 * time.Hour
+
+// Every rule is disabled while its Decision is empty.
+
+// VelocityRule triggers when there were already Count operations within Window.
+type VelocityRule struct {
+	Decision string        `mapstructure:"decision"`
+	Count    int           `mapstructure:"count"`
+	Window   time.Duration `mapstructure:"window"`
+}
+
+// NewPayeeRule triggers when at least Amount is sent to a destination never paid before.
+type NewPayeeRule struct {
+	Decision string `mapstructure:"decision"`
+	Amount   int64  `mapstructure:"amount"`
+}
+
+// AverageMultipleRule triggers when amount is above Multiple of average amount for
+// AverageWindow. Accounts with less than MinHistory operations are not checked.
+type AverageMultipleRule struct {
+	Decision   string  `mapstructure:"decision"`
+	Multiple   float64 `mapstructure:"multiple"`
+	MinHistory int     `mapstructure:"min_history"`
+}
+
+// PasswordChangeRule triggers on the first operation after password was changed within Window.
+type PasswordChangeRule struct {
+	Decision string        `mapstructure:"decision"`
+	Window   time.Duration `mapstructure:"window"`
+}
+
+// Rules is a set of fraud screening rules loaded from config.
+type Rules struct {
+	Velocity        VelocityRule        `mapstructure:"velocity"`
+	NewPayee        NewPayeeRule        `mapstructure:"new_payee"`
+	AverageMultiple AverageMultipleRule `mapstructure:"average_multiple"`
+	PasswordChange  PasswordChangeRule  `mapstructure:"password_change"`
+}
+
+// Facts describe the operation and the history of the account.
+type Facts struct {
+	Amount int64
+	// RecentCount is the number of operations within velocity window.
+	RecentCount int
+	// NewPayee is set when destination was never paid from the account.
+	NewPayee bool
+	// HistoryCount and HistorySum describe operations within AverageWindow.
+	HistoryCount int
+	HistorySum   int64
+	// PasswordChangedAt is zero if password was never changed.
+	PasswordChangedAt time.Time
+	// OperationsSincePasswordChange is the number of operations after the change.
+	OperationsSincePasswordChange int
+}
+
+// Assessment is the decision with the names of triggered rules.
+type Assessment struct {
+	Decision string
+	Rules    []string
+}
+
+// Evaluate applies all enabled rules to the facts. The most severe decision wins.
+func (r *Rules) Evaluate(f Facts, now time.Time) Assessment {
+	a := Assessment{Decision: Allow}
+
+	if r.Velocity.Decision != "" && f.RecentCount >= r.Velocity.Count {
+		a.add(RuleVelocity, r.Velocity.Decision)
+	}
+
+	if r.NewPayee.Decision != "" && f.NewPayee && f.Amount >= r.NewPayee.Amount {
+		a.add(RuleNewPayee, r.NewPayee.Decision)
+	}
+
+	if rule := r.AverageMultiple; rule.Decision != "" && f.HistoryCount > 0 && f.HistoryCount >= rule.MinHistory {
+		average := float64(f.HistorySum) / float64(f.HistoryCount)
+		if float64(f.Amount) > average*rule.Multiple {
+			a.add(RuleAverageMultiple, rule.Decision)
+		}
+	}
+
+	if rule := r.PasswordChange; rule.Decision != "" && !f.PasswordChangedAt.IsZero() &&
+		now.Sub(f.PasswordChangedAt) <= rule.Window && f.OperationsSincePasswordChange == 0 {
+		a.add(RulePasswordChange, rule.Decision)
+	}
+
+	return a
+}
+
+func (a *Assessment) add(rule, decision string) {
+	a.Rules = append(a.Rules, rule)
+	if severity(decision) > severity(a.Decision) {
+		a.Decision = decision
+	}
+}
+
+func severity(decision string) int {
+	switch decision {
+	case Block:
+		return 2
+	case Challenge:
+		return 1
+	default:
+		return 0
+	}
+}
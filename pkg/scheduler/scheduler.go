@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a periodic task. now is the moment of run in UTC.
+type Job func(ctx context.Context, now time.Time) error
+
+// Daily runs job immediately and then every day at given offset from UTC midnight
+// until ctx is done. Jobs must be idempotent: failed runs are just logged and retried next day.
+func Daily(ctx context.Context, name string, at time.Duration, job Job) error {
+	for {
+		run(ctx, name, job)
+
+		timer := time.NewTimer(untilNext(time.Now().UTC(), at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Every runs job immediately and then with given interval until ctx is done. It's meant
+// for frequent jobs, so only failures are logged. Interval must be positive.
+func Every(ctx context.Context, name string, interval time.Duration, job Job) error {
+	if interval <= 0 {
+		return fmt.Errorf("scheduler: interval of job %q must be positive, got %s", name, interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			logrus.WithFields(logrus.Fields{
+				"context": "scheduler.Every()",
+				"job":     name,
+				"problem": "job failed",
+			}).Error(err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func run(ctx context.Context, name string, job Job) {
+	t := time.Now()
+	if err := job(ctx, t.UTC()); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"context": "scheduler.Daily()",
+			"job":     name,
+			"problem": "job failed",
+		}).Error(err.Error())
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"job":     name,
+		"elapsed": time.Since(t).String(),
+	}).Info("Job finished")
+}
+
+func untilNext(now time.Time, at time.Duration) time.Duration {
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next.Sub(now)
+}
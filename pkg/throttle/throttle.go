@@ -0,0 +1,64 @@
+// Package throttle limits how often something happens per key, e.g. per email or client IP,
+// in memory of the process.
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows up to limit events per key within fixed windows. Zero limit allows everything.
+type Limiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	prune   time.Time
+}
+
+type bucket struct {
+	ends  time.Time
+	count int
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow counts an event of the key at now and tells whether it's within the limit.
+func (l *Limiter) Allow(key string, now time.Time) bool {
+	if l.limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// buckets of keys not seen anymore are dropped once a window, so the map doesn't grow
+	if !now.Before(l.prune) {
+		for k, b := range l.buckets {
+			if !now.Before(b.ends) {
+				delete(l.buckets, k)
+			}
+		}
+		l.prune = now.Add(l.window)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok || !now.Before(b.ends) {
+		b = &bucket{ends: now.Add(l.window)}
+		l.buckets[key] = b
+	}
+
+	if b.count >= l.limit {
+		return false
+	}
+	b.count++
+
+	return true
+}
@@ -0,0 +1,41 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+
+	tests := []struct {
+		key  string
+		at   time.Duration
+		want bool
+	}{
+		{"a", 0, true},
+		{"a", time.Second, true},
+		{"a", 2 * time.Second, false},
+		{"b", 3 * time.Second, true},
+		{"a", time.Minute, true},
+		{"a", time.Minute + time.Second, true},
+		{"a", time.Minute + 2*time.Second, false},
+	}
+
+	for _, tt := range tests {
+		if got := l.Allow(tt.key, start.Add(tt.at)); got != tt.want {
+			t.Errorf("Allow(%q) at %s = %t, want %t", tt.key, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestAllowUnlimited(t *testing.T) {
+	l := New(0, time.Minute)
+
+	for i := 0; i < 10; i++ {
+		if !l.Allow("a", time.Now()) {
+			t.Fatalf("Allow() = false on event %d without limit", i)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Hash algorithms of RFC 6238.
+const (
+	SHA1   = "SHA1"
+	SHA256 = "SHA256"
+	SHA512 = "SHA512"
+)
+
+const secretSize = 20
+
+var (
+	ErrInvalidSecret    = errors.New("invalid totp secret")
+	ErrInvalidAlgorithm = errors.New("invalid totp algorithm")
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Options of code generation. Authenticator apps support only Default well.
+type Options struct {
+	Digits    int
+	Period    time.Duration
+	Algorithm string
+}
+
+var Default = Options{Digits: 6, Period: 30 * time.Second, Algorithm: SHA1}
+
+// NewSecret returns random base32 encoded secret.
+func NewSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// Decode decodes base32 secret ignoring case, spaces and padding.
+func Decode(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	return key, nil
+}
+
+// Step returns time step number of the moment.
+func (o Options) Step(t time.Time) int64 {
+	return t.Unix() / int64(o.Period/time.Second)
+}
+
+// Code returns code for the time step (RFC 4226 HOTP with time step as counter).
+func (o Options) Code(key []byte, step int64) (string, error) {
+	var h func() hash.Hash
+	switch o.Algorithm {
+	case SHA1:
+		h = sha1.New
+	case SHA256:
+		h = sha256.New
+	case SHA512:
+		h = sha512.New
+	default:
+		return "", ErrInvalidAlgorithm
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(h, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < o.Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", o.Digits, value%mod), nil
+}
+
+// Validate checks code against time steps around the moment allowing skew steps of clock drift.
+// The matched step is returned to reject replay of the same code.
+func (o Options) Validate(key []byte, code string, t time.Time, skew int64) (int64, bool) {
+	if len(code) != o.Digits {
+		return 0, false
+	}
+
+	now := o.Step(t)
+	for step := now - skew; step <= now+skew; step++ {
+		expected, err := o.Code(key, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns otpauth key URI to be shown as QR code to authenticator apps.
+func (o Options) URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", o.Algorithm)
+	v.Set("digits", fmt.Sprint(o.Digits))
+	v.Set("period", fmt.Sprint(int64(o.Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+
+	return u.String()
+}
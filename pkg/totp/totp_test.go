@@ -0,0 +1,97 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Test vectors of RFC 6238 appendix B.
+func TestCodeRFC6238(t *testing.T) {
+	keys := map[string][]byte{
+		SHA1:   []byte("12345678901234567890"),
+		SHA256: []byte("12345678901234567890123456789012"),
+		SHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	tests := []struct {
+		unix      int64
+		algorithm string
+		code      string
+	}{
+		{59, SHA1, "94287082"},
+		{59, SHA256, "46119246"},
+		{59, SHA512, "90693936"},
+		{1111111109, SHA1, "07081804"},
+		{1111111109, SHA256, "68084774"},
+		{1111111109, SHA512, "25091201"},
+		{1111111111, SHA1, "14050471"},
+		{1111111111, SHA256, "67062674"},
+		{1111111111, SHA512, "99943326"},
+		{1234567890, SHA1, "89005924"},
+		{1234567890, SHA256, "91819424"},
+		{1234567890, SHA512, "93441116"},
+		{2000000000, SHA1, "69279037"},
+		{2000000000, SHA256, "90698825"},
+		{2000000000, SHA512, "38618901"},
+		{20000000000, SHA1, "65353130"},
+		{20000000000, SHA256, "77737706"},
+		{20000000000, SHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		o := Options{Digits: 8, Period: 30 * time.Second, Algorithm: tt.algorithm}
+
+		code, err := o.Code(keys[tt.algorithm], o.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("%s at %d: %v", tt.algorithm, tt.unix, err)
+		}
+
+		if code != tt.code {
+			t.Errorf("%s at %d: code = %s, want %s", tt.algorithm, tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	key := []byte("12345678901234567890")
+	o := Options{Digits: 8, Period: 30 * time.Second, Algorithm: SHA1}
+	now := time.Unix(59, 0)
+
+	step, ok := o.Validate(key, "94287082", now, 1)
+	if !ok || step != 1 {
+		t.Errorf("current code: step = %d, ok = %v, want 1, true", step, ok)
+	}
+
+	// code of the next step is accepted with skew 1 only
+	next := time.Unix(89, 0)
+	if _, ok := o.Validate(key, "94287082", next, 1); !ok {
+		t.Error("previous step code is rejected with skew 1")
+	}
+	if _, ok := o.Validate(key, "94287082", next, 0); ok {
+		t.Error("previous step code is accepted with skew 0")
+	}
+
+	if _, ok := o.Validate(key, "9428708", now, 1); ok {
+		t.Error("code of wrong length is accepted")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := Decode(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("Decode(NewSecret()) = %d bytes, %v", len(key), err)
+	}
+
+	if _, err := Decode("gezd gnbv gy3t qojq gezd gnbv gy3t qojq"); err != nil {
+		t.Errorf("lower case secret with spaces: %v", err)
+	}
+
+	if _, err := Decode("not base32!"); err != ErrInvalidSecret {
+		t.Errorf("invalid secret: err = %v, want %v", err, ErrInvalidSecret)
+	}
+}
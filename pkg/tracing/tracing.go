@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+// Exporters of Config.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+type OTLPConfig struct {
+	Endpoint string `mapstructure:"endpoint"`
+	Insecure bool   `mapstructure:"insecure"`
+}
+
+// Config selects exporter of spans: otlp to send them to a collector, stdout for local
+// development, none to only propagate trace context. SampleRatio is the share of traces
+// which are sampled. Sampling decisions of callers are ignored unless TrustParent is set,
+// so public clients can't make every request traced.
+type Config struct {
+	Exporter    string     `mapstructure:"exporter"`
+	ServiceName string     `mapstructure:"service_name"`
+	SampleRatio float64    `mapstructure:"sample_ratio"`
+	TrustParent bool       `mapstructure:"trust_parent"`
+	OTLP        OTLPConfig `mapstructure:"otlp"`
+}
+
+// Init sets global tracer provider and W3C trace context propagator. The returned function
+// flushes spans and must be called on shutdown.
+func Init(ctx context.Context, cfg Config) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLP.Endpoint)}
+		if cfg.OTLP.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterNone, "":
+		return func(ctx context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExporter, cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sampler(cfg)),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// sampler follows decisions of parents within the app. Remote parents are followed only if
+// they are trusted; otherwise their traces are sampled by the ratio like new ones, still
+// continuing the trace.
+func sampler(cfg Config) sdktrace.Sampler {
+	ratio := sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	if cfg.TrustParent {
+		return sdktrace.ParentBased(ratio)
+	}
+
+	return sdktrace.ParentBased(ratio,
+		sdktrace.WithRemoteParentSampled(ratio),
+		sdktrace.WithRemoteParentNotSampled(ratio),
+	)
+}
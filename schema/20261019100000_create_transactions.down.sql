@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS transactions;
+ALTER TABLE users DROP COLUMN IF EXISTS tier;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS interest_accruals;
+ALTER TABLE accounts DROP COLUMN IF EXISTS type;
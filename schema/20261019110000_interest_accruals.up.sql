@@ -0,0 +1,12 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS type VARCHAR(16) NOT NULL DEFAULT 'current';
+
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL REFERENCES accounts(id),
+    date DATE NOT NULL,
+    balance BIGINT NOT NULL,
+    rate NUMERIC(12, 8) NOT NULL,
+    amount NUMERIC(38, 18) NOT NULL,
+    reference VARCHAR(64),
+    UNIQUE (account_id, date)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS statements (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL REFERENCES accounts(id),
+    period VARCHAR(7) NOT NULL,
+    opening_balance BIGINT NOT NULL,
+    closing_balance BIGINT NOT NULL,
+    data JSONB NOT NULL,
+    generated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (account_id, period)
+);
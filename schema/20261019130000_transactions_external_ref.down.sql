@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS transactions_account_id_external_ref_idx;
+ALTER TABLE transactions DROP COLUMN IF EXISTS external_ref;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS external_ref VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_account_id_external_ref_idx ON transactions (account_id, external_ref) WHERE external_ref IS NOT NULL;
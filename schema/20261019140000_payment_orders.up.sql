@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS payment_orders (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    creditor_name VARCHAR(70) NOT NULL,
+    creditor_iban VARCHAR(34) NOT NULL,
+    creditor_bic VARCHAR(11) NOT NULL DEFAULT '',
+    remittance_info VARCHAR(140) NOT NULL DEFAULT '',
+    execution_date DATE NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    status_reason VARCHAR(255) NOT NULL DEFAULT '',
+    reference VARCHAR(35) NOT NULL UNIQUE,
+    message_id VARCHAR(35),
+    exported_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS payment_orders_status_idx ON payment_orders (status);
+CREATE INDEX IF NOT EXISTS payment_orders_message_id_idx ON payment_orders (message_id);
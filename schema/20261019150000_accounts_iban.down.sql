@@ -0,0 +1,2 @@
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_iban_key;
+ALTER TABLE accounts DROP COLUMN IF EXISTS iban;
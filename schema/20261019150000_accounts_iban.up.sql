@@ -0,0 +1,3 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS iban VARCHAR(34);
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_iban_key;
+ALTER TABLE accounts ADD CONSTRAINT accounts_iban_key UNIQUE (iban);
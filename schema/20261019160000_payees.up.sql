@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS payees (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(140) NOT NULL,
+    iban VARCHAR(34) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT payees_user_id_iban_key UNIQUE (user_id, iban)
+);
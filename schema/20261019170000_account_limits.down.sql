@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS account_limits;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+CREATE TABLE IF NOT EXISTS account_limits (
+    account_id INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    set_by VARCHAR(8) NOT NULL,
+    per_transaction BIGINT,
+    daily BIGINT,
+    monthly BIGINT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (account_id, set_by)
+);
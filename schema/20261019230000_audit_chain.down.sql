@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+DROP TRIGGER IF EXISTS audit_events_no_change ON audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
+
+DROP INDEX IF EXISTS audit_events_created_at_idx;
+DROP INDEX IF EXISTS audit_events_actor_id_idx;
+
+ALTER TABLE audit_events DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS request_id;
+ALTER TABLE audit_events ALTER COLUMN after TYPE JSONB USING after::jsonb;
+ALTER TABLE audit_events ALTER COLUMN before TYPE JSONB USING before::jsonb;
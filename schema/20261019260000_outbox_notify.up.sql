@@ -0,0 +1,13 @@
+CREATE OR REPLACE FUNCTION outbox_notify() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox', NEW.id::TEXT);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS outbox_notify ON outbox;
+CREATE TRIGGER outbox_notify AFTER INSERT ON outbox
+    FOR EACH ROW EXECUTE FUNCTION outbox_notify();
+
+CREATE INDEX IF NOT EXISTS outbox_account_user_idx ON outbox (((payload->>'user_id')::BIGINT), id)
+    WHERE aggregate_type = 'account';
@@ -0,0 +1,9 @@
+-- single row holding hash of the last audit event, locked by appends instead of the whole table
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+    id   BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    hash VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+INSERT INTO audit_chain_head (id, hash)
+    SELECT TRUE, COALESCE((SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1), '')
+    ON CONFLICT (id) DO NOTHING;
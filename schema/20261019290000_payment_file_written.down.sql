@@ -0,0 +1 @@
+ALTER TABLE payment_orders DROP COLUMN IF EXISTS file_written_at;
@@ -0,0 +1,4 @@
+ALTER TABLE payment_orders ADD COLUMN IF NOT EXISTS file_written_at TIMESTAMP;
+
+-- files of orders exported before are considered written, so they are never written twice
+UPDATE payment_orders SET file_written_at = exported_at WHERE message_id IS NOT NULL AND file_written_at IS NULL;
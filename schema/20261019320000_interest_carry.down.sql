@@ -0,0 +1,8 @@
+DELETE FROM interest_accruals WHERE kind <> 'daily';
+
+DROP INDEX IF EXISTS interest_accruals_account_id_date_kind_key;
+
+ALTER TABLE interest_accruals DROP CONSTRAINT IF EXISTS interest_accruals_account_id_date_key;
+ALTER TABLE interest_accruals ADD CONSTRAINT interest_accruals_account_id_date_key UNIQUE (account_id, date);
+
+ALTER TABLE interest_accruals DROP COLUMN IF EXISTS kind;
@@ -0,0 +1,5 @@
+ALTER TABLE interest_accruals ADD COLUMN IF NOT EXISTS kind VARCHAR(16) NOT NULL DEFAULT 'daily';
+
+ALTER TABLE interest_accruals DROP CONSTRAINT IF EXISTS interest_accruals_account_id_date_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS interest_accruals_account_id_date_kind_key ON interest_accruals (account_id, date, kind);
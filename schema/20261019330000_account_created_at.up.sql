@@ -0,0 +1,13 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+
+UPDATE accounts a SET created_at = COALESCE(LEAST(a.last_update, (SELECT MIN(t.created_at) FROM transactions t WHERE t.account_id = a.id)), NOW())
+    WHERE a.created_at IS NULL;
+
+ALTER TABLE accounts ALTER COLUMN created_at SET DEFAULT NOW(), ALTER COLUMN created_at SET NOT NULL;
+
+-- balances set directly before they were posted to the ledger become opening adjustments
+INSERT INTO transactions (account_id, amount, currency, type, reference, description, created_at)
+SELECT a.id, a.balance - COALESCE(SUM(t.amount), 0), a.currency, 'adjustment', md5('adjustment' || a.id), 'opening balance', a.created_at
+FROM accounts a LEFT JOIN transactions t ON t.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(t.amount), 0);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_limits (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency VARCHAR(10) NOT NULL,
+    per_transaction BIGINT,
+    daily BIGINT,
+    monthly BIGINT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, currency)
+);
@@ -0,0 +1,9 @@
+// Package schema embeds SQL migrations of the database, so the binary can apply them itself.
+package schema
+
+import "embed"
+
+// FS holds migration files named <version>_<name>.up.sql and <version>_<name>.down.sql.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,57 @@
+package schema_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/Viquad/crud-app/pkg/migrate"
+	"github.com/Viquad/crud-app/schema"
+
+	_ "github.com/lib/pq"
+)
+
+// TestMigrations applies all migrations to an empty Postgres database, rolls them back and
+// applies again. It runs when TEST_POSTGRES_DSN is set, e.g.
+// "host=localhost user=postgres password=postgres dbname=postgres sslmode=disable".
+func TestMigrations(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	if version, _, _ := m.Version(ctx); version != m.Latest() {
+		t.Fatalf("version after up = %d, want %d (applied %d)", version, m.Latest(), len(applied))
+	}
+
+	if _, err := m.Down(ctx, len(applied)); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+
+	if version, _, _ := m.Version(ctx); version != 0 {
+		t.Fatalf("version after down = %d, want 0", version)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}